@@ -0,0 +1,221 @@
+// Command evaluate scores the contract analysis against golden contracts.
+//
+//	evaluate run [-fixtures dir] [-config config.yaml] [-record] [-prompt-version name=vN] [-out report.json] [-min-f1 0.9]
+//	evaluate diff base.json candidate.json
+//
+// By default run replays the provider traffic recorded with each fixture, so
+// it needs no network and gives the same result every time. With -record the
+// requests go to the providers configured in -config and their traffic is
+// recorded in place of the old one; record after changing a prompt or model,
+// then diff the reports of the two runs. Both modes route requests, pick
+// prompt versions and chunk contracts as -config does, so replay with the
+// configuration the fixtures were recorded with; -prompts, -prompt-version
+// and -chunk-tokens override it in either mode.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/evaluation"
+	"contract-analysis-service/internal/pkg/external"
+	"contract-analysis-service/internal/pkg/logger"
+	"contract-analysis-service/internal/services/llm"
+	"contract-analysis-service/internal/services/llm/prompts"
+	"go.uber.org/zap"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "run":
+		err = run(os.Args[2:])
+	case "diff":
+		err = diff(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "evaluate:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: evaluate run [flags] | evaluate diff base.json candidate.json")
+	os.Exit(2)
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	fixturesDir := flags.String("fixtures", "internal/evaluation/testdata/contracts", "directory of golden contracts")
+	record := flags.Bool("record", false, "call the configured providers and record their responses")
+	configPath := flags.String("config", "config.yaml", "configuration of the providers and their routing")
+	promptsDir := flags.String("prompts", "", "directory of prompt templates overriding the embedded ones")
+	chunkTokens := flags.Int("chunk-tokens", 0, "contract length in tokens above which contracts are analyzed in chunks (default from -config)")
+	pinned := make(map[string]string)
+	flags.Func("prompt-version", "pin the version of a prompt, e.g. risk_assessment=v1; may be repeated", func(value string) error {
+		name, version, ok := strings.Cut(value, "=")
+		if !ok || name == "" || version == "" {
+			return fmt.Errorf("want name=version, got %q", value)
+		}
+		pinned[name] = version
+		return nil
+	})
+	out := flags.String("out", "", "file to write the JSON report to")
+	minF1 := flags.Float64("min-f1", 0, "fail when the overall F1 score is lower")
+	flags.Parse(args)
+
+	fixtures, err := evaluation.LoadFixtures(*fixturesDir)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := configs.LoadConfig(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if *promptsDir == "" {
+		*promptsDir = cfg.LLM.Prompts.Dir
+	}
+	if *chunkTokens == 0 {
+		*chunkTokens = cfg.LLM.ChunkTokens
+	}
+	versions := maps.Clone(cfg.LLM.Prompts.Versions)
+	if versions == nil {
+		versions = make(map[string]string)
+	}
+	maps.Copy(versions, pinned)
+	log := logger.NewLogger(cfg.Logger)
+
+	registry, err := prompts.New(*promptsDir, versions)
+	if err != nil {
+		return err
+	}
+
+	serviceFor := func(f *evaluation.Fixture) (llm.Service, error) {
+		return evaluation.NewService(cfg, f.ResponsesDir(), external.CassetteReplay, log)
+	}
+	pipeline := evaluation.AnalysisPipeline(registry, *chunkTokens)
+	if *record {
+		recorder := newRecorder(cfg, log)
+		serviceFor, pipeline = recorder.serviceFor, recorder.pipeline(pipeline)
+	}
+	report := evaluation.Evaluate(context.Background(), fixtures, serviceFor, pipeline)
+	if err := evaluation.WriteReport(os.Stdout, report); err != nil {
+		return err
+	}
+	if *out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*out, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	if f1 := report.Overall.F1(); f1 < *minF1 {
+		return fmt.Errorf("overall F1 %.3f is below %.3f", f1, *minF1)
+	}
+	return nil
+}
+
+// recorder records the traffic of each fixture into a temporary directory
+// and replaces the fixture's recordings with it once the fixture has been
+// evaluated in full, so that an interrupted or failed run leaves the old
+// recordings in place and stale ones do not pile up with every prompt change.
+type recorder struct {
+	cfg *configs.Config
+	log *zap.Logger
+	// dirs maps the service of each fixture being recorded to its directories
+	dirs map[llm.Service]recordingDirs
+}
+
+type recordingDirs struct {
+	temp, responses string
+}
+
+func newRecorder(cfg *configs.Config, log *zap.Logger) *recorder {
+	return &recorder{cfg: cfg, log: log, dirs: make(map[llm.Service]recordingDirs)}
+}
+
+func (r *recorder) serviceFor(f *evaluation.Fixture) (llm.Service, error) {
+	// Next to the recordings, so that they can be renamed into place
+	temp, err := os.MkdirTemp(f.Dir, ".responses-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	service, err := evaluation.NewService(r.cfg, temp, external.CassetteRecord, r.log)
+	if err != nil {
+		os.RemoveAll(temp)
+		return nil, err
+	}
+	r.dirs[service] = recordingDirs{temp: temp, responses: f.ResponsesDir()}
+	return service, nil
+}
+
+// pipeline runs next and keeps what it recorded only when it succeeds.
+func (r *recorder) pipeline(next evaluation.Pipeline) evaluation.Pipeline {
+	return func(ctx context.Context, service llm.Service, text string) (*evaluation.Extraction, error) {
+		dirs := r.dirs[service]
+		delete(r.dirs, service)
+		defer os.RemoveAll(dirs.temp)
+
+		extraction, err := next(ctx, service, text)
+		if err != nil {
+			return nil, err
+		}
+		// The old recordings are moved aside rather than removed, so that
+		// they can be restored if the new ones cannot be moved into place
+		old := dirs.temp + ".old"
+		if err := os.Rename(dirs.responses, old); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to move old recordings aside: %w", err)
+		}
+		if err := os.Rename(dirs.temp, dirs.responses); err != nil {
+			if restoreErr := os.Rename(old, dirs.responses); restoreErr != nil && !os.IsNotExist(restoreErr) {
+				r.log.Error("Failed to restore old recordings", zap.String("path", old), zap.Error(restoreErr))
+			}
+			return nil, fmt.Errorf("failed to replace recordings: %w", err)
+		}
+		if err := os.RemoveAll(old); err != nil {
+			return nil, fmt.Errorf("failed to remove old recordings: %w", err)
+		}
+		return extraction, nil
+	}
+}
+
+func diff(args []string) error {
+	if len(args) != 2 {
+		usage()
+	}
+	base, err := readReport(args[0])
+	if err != nil {
+		return err
+	}
+	candidate, err := readReport(args[1])
+	if err != nil {
+		return err
+	}
+	return evaluation.WriteDiff(os.Stdout, base, candidate)
+}
+
+func readReport(path string) (*evaluation.Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report evaluation.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+	return &report, nil
+}
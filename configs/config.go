@@ -17,17 +17,149 @@ type Config struct {
 	LLM         LLMConfig      `mapstructure:"llm"`
 	OCR         OCRConfig      `mapstructure:"ocr"`
 	Redis       RedisConfig    `mapstructure:"redis"`
+	Storage     StorageConfig  `mapstructure:"storage"`
+	Jobs        JobsConfig     `mapstructure:"jobs"`
 }
 
 // LLMConfig holds configuration for all LLM providers
 type LLMConfig struct {
 	OpenRouter LLMProviderConfig `mapstructure:"openrouter"`
+	// Anthropic is registered only when an API key is configured
+	Anthropic LLMProviderConfig `mapstructure:"anthropic"`
+	Routing   LLMRoutingConfig  `mapstructure:"routing"`
+	// ChunkTokens is the contract length, in estimated tokens, above which
+	// contracts are analyzed in chunks
+	ChunkTokens int            `mapstructure:"chunk_tokens"`
+	Prompts     PromptsConfig  `mapstructure:"prompts"`
+	Cassette    CassetteConfig `mapstructure:"cassette"`
+	Usage       UsageConfig    `mapstructure:"usage"`
+	Ensemble    EnsembleConfig `mapstructure:"ensemble"`
+}
+
+// EnsembleConfig holds configuration for analyzing a contract several times
+// and voting on the result
+type EnsembleConfig struct {
+	// Samples is how many times each member analyzes a contract
+	Samples int `mapstructure:"samples"`
+	// Temperature is used instead of the analysis default when sampling a
+	// member more than once, so that the samples can differ
+	Temperature float64 `mapstructure:"temperature"`
+	// Members are the providers analyzing the contract; without members the
+	// analysis route is sampled
+	Members []EnsembleMember `mapstructure:"members"`
+}
+
+// EnsembleMember is a provider, and optionally a model of it, taking part in
+// the vote
+type EnsembleMember struct {
+	Provider string `mapstructure:"provider"`
+	// Model overrides the provider's default model when set
+	Model string `mapstructure:"model"`
+}
+
+// UsageConfig holds configuration for LLM cost accounting and tenant budgets
+type UsageConfig struct {
+	// Currency that prices and budgets are in
+	Currency string `mapstructure:"currency"`
+	// Prices lists the price of each model per million tokens
+	Prices []ModelPrice `mapstructure:"prices"`
+	// MonthlyBudget is what a tenant may spend on LLM calls per calendar
+	// month (UTC) before new analyses are rejected; zero means no limit
+	MonthlyBudget float64 `mapstructure:"monthly_budget"`
+	// Budgets overrides MonthlyBudget for individual tenants
+	Budgets []TenantBudget `mapstructure:"budgets"`
+}
+
+// ModelPrice is the price of a model per million tokens. Model matches the
+// model reported by the provider exactly or as a prefix, so that a price for
+// "claude-sonnet-4" also applies to "claude-sonnet-4-20250514"; the longest
+// match wins.
+type ModelPrice struct {
+	Model      string  `mapstructure:"model"`
+	Prompt     float64 `mapstructure:"prompt"`
+	Completion float64 `mapstructure:"completion"`
+}
+
+// TenantBudget is the monthly LLM budget of a tenant
+type TenantBudget struct {
+	Tenant  string  `mapstructure:"tenant"`
+	Monthly float64 `mapstructure:"monthly"`
+}
+
+// CassetteConfig holds configuration for recording and replaying LLM traffic
+type CassetteConfig struct {
+	// Mode is "record" to record provider traffic, "replay" to serve it back
+	// without network access, or empty to call the providers normally
+	Mode string `mapstructure:"mode"`
+	// Dir holds the recordings, in a subdirectory per provider
+	Dir string `mapstructure:"dir"`
+}
+
+// PromptsConfig holds configuration for the prompt templates
+type PromptsConfig struct {
+	// Dir holds templates that replace or add to the embedded ones
+	Dir string `mapstructure:"dir"`
+	// Versions pins the version used for a prompt, e.g. risk_assessment: v1;
+	// prompts without a pin use their latest version
+	Versions map[string]string `mapstructure:"versions"`
+}
+
+// LLMRoutingConfig holds the provider chains used for each LLM task
+type LLMRoutingConfig struct {
+	// Default is the chain for tasks without a route of their own
+	Default []string `mapstructure:"default"`
+	// Routes maps a task (validation, analysis, risk...) to the providers
+	// tried for it, in order
+	Routes map[string][]string `mapstructure:"routes"`
+	// MinHealth is the health score below which a provider is tried last
+	MinHealth float64 `mapstructure:"min_health"`
+	// Cooldown is how long an unhealthy provider stays at the back of the
+	// chain before it is tried in its configured position again
+	Cooldown time.Duration `mapstructure:"cooldown"`
 }
 
 // OCRConfig holds configuration for the OCR provider
 type OCRConfig struct {
 	APIKey         string   `mapstructure:"api_key"`
 	FallbackModels []string `mapstructure:"fallback_models"`
+	RasterizerPath string   `mapstructure:"rasterizer_path"`
+}
+
+// StorageConfig holds configuration for uploaded file storage
+type StorageConfig struct {
+	Backend    string           `mapstructure:"backend"`
+	LocalPath  string           `mapstructure:"local_path"`
+	PublicURL  string           `mapstructure:"public_url"`
+	SigningKey string           `mapstructure:"signing_key"`
+	S3         S3Config         `mapstructure:"s3"`
+	Encryption EncryptionConfig `mapstructure:"encryption"`
+}
+
+// EncryptionConfig holds configuration for encryption of stored files
+type EncryptionConfig struct {
+	Enabled      bool              `mapstructure:"enabled"`
+	CurrentKeyID string            `mapstructure:"current_key_id"`
+	Keys         map[string]string `mapstructure:"keys"` // key ID -> base64-encoded 32-byte key
+}
+
+// S3Config holds configuration for an S3-compatible object store
+type S3Config struct {
+	Endpoint        string `mapstructure:"endpoint"`
+	Region          string `mapstructure:"region"`
+	Bucket          string `mapstructure:"bucket"`
+	Prefix          string `mapstructure:"prefix"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	UsePathStyle    bool   `mapstructure:"use_path_style"`
+}
+
+// JobsConfig holds configuration for the background job workers
+type JobsConfig struct {
+	Workers       int           `mapstructure:"workers"`
+	PollInterval  time.Duration `mapstructure:"poll_interval"`
+	LeaseDuration time.Duration `mapstructure:"lease_duration"`
+	MaxAttempts   int           `mapstructure:"max_attempts"`
+	RetryBackoff  time.Duration `mapstructure:"retry_backoff"`
 }
 
 // RedisConfig holds configuration for Redis
@@ -39,11 +171,11 @@ type RedisConfig struct {
 
 // LLMProviderConfig holds configuration for a single LLM provider
 type LLMProviderConfig struct {
-	BaseURL       string        `mapstructure:"base_url"`
-	APIKey        string        `mapstructure:"api_key"`
-	Timeout       time.Duration `mapstructure:"timeout"`
-	RetryCount    int           `mapstructure:"retry_count"`
-	RetryWaitTime time.Duration `mapstructure:"retry_wait_time"`
+	BaseURL          string        `mapstructure:"base_url"`
+	APIKey           string        `mapstructure:"api_key"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+	RetryCount       int           `mapstructure:"retry_count"`
+	RetryWaitTime    time.Duration `mapstructure:"retry_wait_time"`
 	RetryMaxInterval time.Duration `mapstructure:"retry_max_interval"`
 }
 
@@ -57,7 +189,7 @@ type ServerConfig struct {
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
 	Dialect string `mapstructure:"dialect"`
-	Name   string `mapstructure:"name"`
+	Name    string `mapstructure:"name"`
 	LogMode bool   `mapstructure:"log_mode"`
 }
 
@@ -93,6 +225,114 @@ func (c DatabaseConfig) GetLogMode() bool {
 	return c.LogMode
 }
 
+// GetBackend returns the storage backend, "local" or "s3"
+func (c StorageConfig) GetBackend() string {
+	if c.Backend == "" {
+		return "local" // Default to local disk if not specified
+	}
+	return c.Backend
+}
+
+// GetLocalPath returns the directory used by the local storage backend
+func (c StorageConfig) GetLocalPath() string {
+	if c.LocalPath == "" {
+		return "./uploads"
+	}
+	return c.LocalPath
+}
+
+// GetWorkers returns the number of concurrent job workers
+func (c JobsConfig) GetWorkers() int {
+	if c.Workers <= 0 {
+		return 2
+	}
+	return c.Workers
+}
+
+// GetPollInterval returns how often idle workers look for new jobs
+func (c JobsConfig) GetPollInterval() time.Duration {
+	if c.PollInterval <= 0 {
+		return time.Second
+	}
+	return c.PollInterval
+}
+
+// GetLeaseDuration returns how long a running job is reserved for its worker
+// without a heartbeat before another worker may take it over
+func (c JobsConfig) GetLeaseDuration() time.Duration {
+	if c.LeaseDuration <= 0 {
+		return time.Minute
+	}
+	return c.LeaseDuration
+}
+
+// GetMaxAttempts returns how many times a job is tried before it fails
+func (c JobsConfig) GetMaxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return 3
+	}
+	return c.MaxAttempts
+}
+
+// GetRetryBackoff returns the delay before the first retry; it doubles on
+// every further attempt
+func (c JobsConfig) GetRetryBackoff() time.Duration {
+	if c.RetryBackoff <= 0 {
+		return 30 * time.Second
+	}
+	return c.RetryBackoff
+}
+
+// GetMinHealth returns the health score below which a provider is tried last
+func (c LLMRoutingConfig) GetMinHealth() float64 {
+	if c.MinHealth <= 0 {
+		return 0.5
+	}
+	return c.MinHealth
+}
+
+// GetCooldown returns how long an unhealthy provider is tried last
+func (c LLMRoutingConfig) GetCooldown() time.Duration {
+	if c.Cooldown <= 0 {
+		return 30 * time.Second
+	}
+	return c.Cooldown
+}
+
+// GetCurrency returns the currency of prices and budgets
+func (c UsageConfig) GetCurrency() string {
+	if c.Currency == "" {
+		return "USD"
+	}
+	return c.Currency
+}
+
+// GetMonthlyBudget returns the monthly budget of a tenant, zero meaning no limit
+func (c UsageConfig) GetMonthlyBudget(tenant string) float64 {
+	for _, budget := range c.Budgets {
+		if budget.Tenant == tenant {
+			return budget.Monthly
+		}
+	}
+	return c.MonthlyBudget
+}
+
+// GetSamples returns how many times each member analyzes a contract
+func (c EnsembleConfig) GetSamples() int {
+	if c.Samples < 1 {
+		return 1
+	}
+	return c.Samples
+}
+
+// GetTemperature returns the temperature of repeated samples
+func (c EnsembleConfig) GetTemperature() float64 {
+	if c.Temperature <= 0 {
+		return 0.7
+	}
+	return c.Temperature
+}
+
 // LoadConfig loads configuration from file and environment variables
 func LoadConfig(configPath string) (*Config, error) {
 	viper.SetConfigFile(configPath)
@@ -104,11 +344,35 @@ func LoadConfig(configPath string) (*Config, error) {
 	if err := viper.Unmarshal(&cfg); err != nil {
 		return nil, err
 	}
-	
+
 	// Override config with environment variables if they exist
 	if apiKey := os.Getenv("OPENROUTER_API_KEY"); apiKey != "" {
 		cfg.OCR.APIKey = apiKey
 	}
-	
+	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
+		cfg.LLM.Anthropic.APIKey = apiKey
+	}
+	if mode := os.Getenv("LLM_CASSETTE_MODE"); mode != "" {
+		cfg.LLM.Cassette.Mode = mode
+	}
+	if dir := os.Getenv("PROMPTS_DIR"); dir != "" {
+		cfg.LLM.Prompts.Dir = dir
+	}
+	if signingKey := os.Getenv("STORAGE_SIGNING_KEY"); signingKey != "" {
+		cfg.Storage.SigningKey = signingKey
+	}
+	if key := os.Getenv("STORAGE_ENCRYPTION_KEY"); key != "" && cfg.Storage.Encryption.CurrentKeyID != "" {
+		if cfg.Storage.Encryption.Keys == nil {
+			cfg.Storage.Encryption.Keys = map[string]string{}
+		}
+		cfg.Storage.Encryption.Keys[cfg.Storage.Encryption.CurrentKeyID] = key
+	}
+	if accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID"); accessKeyID != "" {
+		cfg.Storage.S3.AccessKeyID = accessKeyID
+	}
+	if secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY"); secretAccessKey != "" {
+		cfg.Storage.S3.SecretAccessKey = secretAccessKey
+	}
+
 	return &cfg, nil
 }
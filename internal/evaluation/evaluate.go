@@ -0,0 +1,219 @@
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"contract-analysis-service/internal/services/llm"
+	"contract-analysis-service/internal/services/llm/prompts"
+)
+
+// Pipeline extracts the scored fields of a contract using service.
+type Pipeline func(ctx context.Context, service llm.Service, text string) (*Extraction, error)
+
+// AnalysisPipeline runs the analysis and risk stages of the contract analysis
+// with the prompts of registry, splitting contracts longer than chunkTokens
+// like the service does when it is positive.
+func AnalysisPipeline(registry *prompts.Registry, chunkTokens int) Pipeline {
+	engine := llm.NewPromptEngineWithRegistry(registry)
+	return func(ctx context.Context, service llm.Service, text string) (*Extraction, error) {
+		analyzer := llm.NewContractAnalyzer(service, engine)
+		if chunkTokens > 0 {
+			analyzer.SetChunkTokens(chunkTokens)
+		}
+		analysis, err := analyzer.AnalyzeContract(ctx, llm.TaskAnalysis, text)
+		if err != nil {
+			return nil, err
+		}
+		assessment, err := llm.NewRiskAssessor(service, engine).AssessRisks(ctx, llm.TaskRisk, text, "")
+		if err != nil {
+			return nil, err
+		}
+
+		extraction := &Extraction{
+			Buyer:      analysis.Buyer,
+			Seller:     analysis.Seller,
+			TotalValue: analysis.TotalValue,
+			Currency:   analysis.Currency,
+		}
+		for _, m := range analysis.Milestones {
+			extraction.Milestones = append(extraction.Milestones, Milestone{Description: m.Description, Percentage: m.Percentage})
+		}
+		for _, r := range assessment.Risks {
+			extraction.Risks = append(extraction.Risks, Risk{Type: r.Type, Severity: r.Severity})
+		}
+		return extraction, nil
+	}
+}
+
+// Report is the outcome of an evaluation run.
+type Report struct {
+	// PromptVersions lists the prompt templates the run used.
+	PromptVersions []string         `json:"prompt_versions"`
+	Fixtures       []*FixtureResult `json:"fixtures"`
+	// Fields sums the counts of every fixture by field.
+	Fields  map[string]Counts `json:"fields"`
+	Overall Counts            `json:"overall"`
+	// TotalValueAccuracy is the share of fixtures whose total value is correct.
+	TotalValueAccuracy float64 `json:"total_value_accuracy"`
+	// MeanPercentageError is the mean absolute error, in percentage points,
+	// of the milestones that matched.
+	MeanPercentageError float64 `json:"mean_percentage_error"`
+	Failed              int     `json:"failed"`
+}
+
+// FixtureResult is the outcome for one fixture. A fixture whose pipeline
+// failed has an Error and counts as extracting nothing.
+type FixtureResult struct {
+	Name       string      `json:"name"`
+	Error      string      `json:"error,omitempty"`
+	Extraction *Extraction `json:"extraction,omitempty"`
+	Score      *Score      `json:"score"`
+}
+
+// Evaluate runs pipeline over every fixture, with the LLM service serviceFor
+// returns for it, and scores the results.
+func Evaluate(ctx context.Context, fixtures []*Fixture, serviceFor func(*Fixture) (llm.Service, error), pipeline Pipeline) *Report {
+	ctx, trace := prompts.Track(ctx)
+	report := &Report{Fields: make(map[string]Counts, len(Fields))}
+	var valuesCorrect, matched int
+	var percentageError float64
+	for _, fixture := range fixtures {
+		result := &FixtureResult{Name: fixture.Name}
+		extraction, err := evaluateFixture(ctx, fixture, serviceFor, pipeline)
+		if err != nil {
+			result.Error = err.Error()
+			report.Failed++
+			extraction = &Extraction{}
+		} else {
+			result.Extraction = extraction
+		}
+		result.Score = ScoreExtraction(fixture.Expected, extraction)
+		report.Fixtures = append(report.Fixtures, result)
+
+		for _, field := range Fields {
+			counts := report.Fields[field]
+			counts.Add(result.Score.Fields[field])
+			report.Fields[field] = counts
+		}
+		report.Overall.Add(result.Score.Overall())
+		if result.Score.TotalValueCorrect {
+			valuesCorrect++
+		}
+		matched += result.Score.MatchedMilestones
+		percentageError += result.Score.PercentageError
+	}
+	if len(fixtures) > 0 {
+		report.TotalValueAccuracy = float64(valuesCorrect) / float64(len(fixtures))
+	}
+	if matched > 0 {
+		report.MeanPercentageError = percentageError / float64(matched)
+	}
+	report.PromptVersions = trace.Refs()
+	return report
+}
+
+func evaluateFixture(ctx context.Context, fixture *Fixture, serviceFor func(*Fixture) (llm.Service, error), pipeline Pipeline) (*Extraction, error) {
+	service, err := serviceFor(fixture)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline(ctx, service, fixture.Text)
+}
+
+// WriteReport writes a summary of report to w.
+func WriteReport(w io.Writer, report *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tPRECISION\tRECALL\tF1\tTP\tFP\tFN")
+	for _, field := range Fields {
+		c := report.Fields[field]
+		fmt.Fprintf(tw, "%s\t%.3f\t%.3f\t%.3f\t%d\t%d\t%d\n", field, c.Precision(), c.Recall(), c.F1(), c.TruePositives, c.FalsePositives, c.FalseNegatives)
+	}
+	c := report.Overall
+	fmt.Fprintf(tw, "overall\t%.3f\t%.3f\t%.3f\t%d\t%d\t%d\n", c.Precision(), c.Recall(), c.F1(), c.TruePositives, c.FalsePositives, c.FalseNegatives)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\ntotal value accuracy: %.3f\nmean milestone percentage error: %.2f points\nfixtures: %d, failed: %d\n",
+		report.TotalValueAccuracy, report.MeanPercentageError, len(report.Fixtures), report.Failed)
+	for _, f := range report.Fixtures {
+		if f.Error != "" && err == nil {
+			_, err = fmt.Fprintf(w, "  %s: %s\n", f.Name, f.Error)
+		}
+	}
+	return err
+}
+
+// FixtureChange is a fixture whose overall F1 changed between two runs.
+type FixtureChange struct {
+	Name      string
+	Base      float64
+	Candidate float64
+}
+
+// ChangedFixtures returns the fixtures present in both reports whose overall
+// F1 differs, the largest regressions first.
+func ChangedFixtures(base, candidate *Report) []FixtureChange {
+	baseF1 := make(map[string]float64, len(base.Fixtures))
+	for _, f := range base.Fixtures {
+		baseF1[f.Name] = f.Score.Overall().F1()
+	}
+	var changes []FixtureChange
+	for _, f := range candidate.Fixtures {
+		b, ok := baseF1[f.Name]
+		if c := f.Score.Overall().F1(); ok && c != b {
+			changes = append(changes, FixtureChange{Name: f.Name, Base: b, Candidate: c})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Candidate-changes[i].Base < changes[j].Candidate-changes[j].Base
+	})
+	return changes
+}
+
+// WriteDiff writes the differences between two reports to w: the metrics of
+// every field in both runs and the fixtures whose score changed.
+func WriteDiff(w io.Writer, base, candidate *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tPRECISION\t\tRECALL\t\tF1\t")
+	row := func(name string, b, c Counts) {
+		fmt.Fprintf(tw, "%s\t%.3f → %.3f\t%s\t%.3f → %.3f\t%s\t%.3f → %.3f\t%s\n", name,
+			b.Precision(), c.Precision(), delta(c.Precision()-b.Precision()),
+			b.Recall(), c.Recall(), delta(c.Recall()-b.Recall()),
+			b.F1(), c.F1(), delta(c.F1()-b.F1()))
+	}
+	for _, field := range Fields {
+		row(field, base.Fields[field], candidate.Fields[field])
+	}
+	row("overall", base.Overall, candidate.Overall)
+	fmt.Fprintf(tw, "total value accuracy\t%.3f → %.3f\t%s\n", base.TotalValueAccuracy, candidate.TotalValueAccuracy,
+		delta(candidate.TotalValueAccuracy-base.TotalValueAccuracy))
+	fmt.Fprintf(tw, "percentage error\t%.2f → %.2f\t%s\n", base.MeanPercentageError, candidate.MeanPercentageError,
+		delta(candidate.MeanPercentageError-base.MeanPercentageError))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	changes := ChangedFixtures(base, candidate)
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "\nno fixture changed")
+		return err
+	}
+	fmt.Fprintln(w, "\nchanged fixtures (F1):")
+	for _, c := range changes {
+		if _, err := fmt.Fprintf(w, "  %s: %.3f → %.3f (%s)\n", c.Name, c.Base, c.Candidate, delta(c.Candidate-c.Base)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func delta(d float64) string {
+	if d == 0 {
+		return "="
+	}
+	return fmt.Sprintf("%+.3f", d)
+}
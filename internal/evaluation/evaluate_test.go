@@ -0,0 +1,94 @@
+package evaluation
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/pkg/external"
+	"contract-analysis-service/internal/services/llm"
+	"contract-analysis-service/internal/services/llm/prompts"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestEvaluate_GoldenContracts replays the recorded responses of the golden
+// contracts. It fails when a prompt change alters the requests without the
+// responses being recorded again with `evaluate run -record`.
+func TestEvaluate_GoldenContracts(t *testing.T) {
+	fixtures, err := LoadFixtures("testdata/contracts")
+	require.NoError(t, err)
+	require.Len(t, fixtures, 3)
+
+	cfg, err := configs.LoadConfig("../../config.yaml")
+	require.NoError(t, err)
+
+	report := Evaluate(context.Background(), fixtures, func(f *Fixture) (llm.Service, error) {
+		return NewService(cfg, f.ResponsesDir(), external.CassetteReplay, zap.NewNop())
+	}, AnalysisPipeline(prompts.Default(), 0))
+
+	for _, f := range report.Fixtures {
+		assert.Empty(t, f.Error, f.Name)
+	}
+	assert.Equal(t, []string{"contract_analysis@v1", "risk_assessment@v2"}, report.PromptVersions)
+	assert.Equal(t, 1.0, report.TotalValueAccuracy)
+	assert.Equal(t, Counts{TruePositives: 9, FalseNegatives: 1}, report.Fields[FieldMilestones])
+	assert.Equal(t, Counts{TruePositives: 26, FalsePositives: 2, FalseNegatives: 2}, report.Overall)
+}
+
+func TestScoreExtraction(t *testing.T) {
+	expected := &Extraction{
+		Buyer: "Acme Corp", Seller: "Globex Ltd", TotalValue: decimal.NewFromInt(1000), Currency: "USD",
+		Milestones: []Milestone{{Percentage: 30}, {Percentage: 70}},
+		Risks:      []Risk{{Type: "payment"}, {Type: "liability"}},
+	}
+	actual := &Extraction{
+		Buyer: "ACME Corporation", Seller: "Initech", TotalValue: decimal.NewFromInt(900),
+		Milestones: []Milestone{{Percentage: 70.2}, {Percentage: 15}, {Percentage: 15}},
+		Risks:      []Risk{{Type: "Late payment"}},
+	}
+
+	score := ScoreExtraction(expected, actual)
+	assert.Equal(t, Counts{TruePositives: 1}, score.Fields[FieldBuyer])
+	assert.Equal(t, Counts{FalsePositives: 1, FalseNegatives: 1}, score.Fields[FieldSeller])
+	assert.Equal(t, Counts{FalseNegatives: 1}, score.Fields[FieldCurrency])
+	assert.Equal(t, Counts{FalsePositives: 1, FalseNegatives: 1}, score.Fields[FieldTotalValue])
+	assert.False(t, score.TotalValueCorrect)
+	assert.InDelta(t, 0.1, score.TotalValueError, 1e-9)
+	assert.Equal(t, Counts{TruePositives: 1, FalsePositives: 2, FalseNegatives: 1}, score.Fields[FieldMilestones])
+	assert.InDelta(t, 0.2, score.PercentageError, 1e-9)
+	assert.Equal(t, Counts{TruePositives: 1, FalseNegatives: 1}, score.Fields[FieldRisks])
+
+	overall := score.Overall()
+	assert.Equal(t, Counts{TruePositives: 3, FalsePositives: 4, FalseNegatives: 5}, overall)
+	assert.InDelta(t, 3.0/7, overall.Precision(), 1e-9)
+	assert.InDelta(t, 3.0/8, overall.Recall(), 1e-9)
+}
+
+func TestWriteDiff(t *testing.T) {
+	fixture := func(name string, c Counts) *FixtureResult {
+		return &FixtureResult{Name: name, Score: &Score{Fields: map[string]Counts{FieldRisks: c}}}
+	}
+	base := &Report{
+		Fields:   map[string]Counts{FieldRisks: {TruePositives: 2, FalseNegatives: 2}},
+		Overall:  Counts{TruePositives: 2, FalseNegatives: 2},
+		Fixtures: []*FixtureResult{fixture("a", Counts{TruePositives: 1, FalseNegatives: 1}), fixture("b", Counts{TruePositives: 1, FalseNegatives: 1})},
+	}
+	candidate := &Report{
+		Fields:   map[string]Counts{FieldRisks: {TruePositives: 3, FalseNegatives: 1}},
+		Overall:  Counts{TruePositives: 3, FalseNegatives: 1},
+		Fixtures: []*FixtureResult{fixture("a", Counts{TruePositives: 2}), fixture("b", Counts{TruePositives: 1, FalseNegatives: 1})},
+	}
+
+	changes := ChangedFixtures(base, candidate)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "a", changes[0].Name)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteDiff(&out, base, candidate))
+	assert.Contains(t, out.String(), "0.500 → 0.750")
+	assert.Contains(t, out.String(), "a: 0.667 → 1.000 (+0.333)")
+}
@@ -0,0 +1,93 @@
+// Package evaluation measures the quality of contract analysis against golden
+// contracts, so that prompt and model changes can be compared before they
+// ship.
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// Fixture file names. Each fixture is a directory holding the contract text,
+// the expected extraction and the recorded LLM responses.
+const (
+	contractFile = "contract.txt"
+	expectedFile = "expected.json"
+	responsesDir = "responses"
+)
+
+// Fixture is a golden contract.
+type Fixture struct {
+	Name     string
+	Dir      string
+	Text     string
+	Expected *Extraction
+}
+
+// ResponsesDir returns the directory holding the provider traffic recorded
+// for the fixture, in a cassette per provider.
+func (f *Fixture) ResponsesDir() string {
+	return filepath.Join(f.Dir, responsesDir)
+}
+
+// Extraction holds the fields of a contract that are scored. It is the format
+// of the expected.json file of a fixture.
+type Extraction struct {
+	Buyer      string          `json:"buyer"`
+	Seller     string          `json:"seller"`
+	TotalValue decimal.Decimal `json:"total_value"`
+	Currency   string          `json:"currency"`
+	Milestones []Milestone     `json:"milestones"`
+	Risks      []Risk          `json:"risks"`
+}
+
+// Milestone is an expected or extracted payment milestone.
+type Milestone struct {
+	Description string  `json:"description"`
+	Percentage  float64 `json:"percentage"`
+}
+
+// Risk is an expected or extracted risk.
+type Risk struct {
+	Type     string `json:"type"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// LoadFixtures reads every fixture in dir, sorted by name. Subdirectories
+// without a contract.txt are skipped.
+func LoadFixtures(dir string) ([]*Fixture, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture directory: %w", err)
+	}
+	var fixtures []*Fixture
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		fixtureDir := filepath.Join(dir, entry.Name())
+		text, err := os.ReadFile(filepath.Join(fixtureDir, contractFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", entry.Name(), err)
+		}
+		data, err := os.ReadFile(filepath.Join(fixtureDir, expectedFile))
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", entry.Name(), err)
+		}
+		var expected Extraction
+		if err := json.Unmarshal(data, &expected); err != nil {
+			return nil, fmt.Errorf("fixture %s: invalid %s: %w", entry.Name(), expectedFile, err)
+		}
+		fixtures = append(fixtures, &Fixture{Name: entry.Name(), Dir: fixtureDir, Text: string(text), Expected: &expected})
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Name < fixtures[j].Name })
+	return fixtures, nil
+}
@@ -0,0 +1,201 @@
+package evaluation
+
+import (
+	"math"
+	"strings"
+
+	"contract-analysis-service/internal/services/llm"
+	"github.com/shopspring/decimal"
+)
+
+// Scored fields.
+const (
+	FieldBuyer      = "buyer"
+	FieldSeller     = "seller"
+	FieldTotalValue = "total_value"
+	FieldCurrency   = "currency"
+	FieldMilestones = "milestones"
+	FieldRisks      = "risks"
+)
+
+// Fields lists the scored fields in report order.
+var Fields = []string{FieldBuyer, FieldSeller, FieldTotalValue, FieldCurrency, FieldMilestones, FieldRisks}
+
+const (
+	// valueTolerance is the largest difference at which a total value is
+	// still correct, i.e. one cent.
+	valueTolerance = 0.01
+	// percentageTolerance is the largest difference in percentage points at
+	// which a milestone matches an expected one.
+	percentageTolerance = 0.5
+)
+
+// Counts tallies the matches of a field: true positives are extracted values
+// that match an expected one, false positives extracted values that match
+// none, and false negatives expected values that were not extracted.
+type Counts struct {
+	TruePositives  int `json:"tp"`
+	FalsePositives int `json:"fp"`
+	FalseNegatives int `json:"fn"`
+}
+
+// Add adds the counts of o.
+func (c *Counts) Add(o Counts) {
+	c.TruePositives += o.TruePositives
+	c.FalsePositives += o.FalsePositives
+	c.FalseNegatives += o.FalseNegatives
+}
+
+// Precision is the share of extracted values that are correct; it is 1 when
+// nothing was extracted.
+func (c Counts) Precision() float64 {
+	return ratio(c.TruePositives, c.TruePositives+c.FalsePositives)
+}
+
+// Recall is the share of expected values that were extracted; it is 1 when
+// nothing was expected.
+func (c Counts) Recall() float64 {
+	return ratio(c.TruePositives, c.TruePositives+c.FalseNegatives)
+}
+
+// F1 is the harmonic mean of precision and recall.
+func (c Counts) F1() float64 {
+	p, r := c.Precision(), c.Recall()
+	if p+r == 0 {
+		return 0
+	}
+	return 2 * p * r / (p + r)
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 1
+	}
+	return float64(n) / float64(d)
+}
+
+// Score compares an extraction with the expected one.
+type Score struct {
+	Fields map[string]Counts `json:"fields"`
+	// TotalValueCorrect reports whether the total value is within a cent of
+	// the expected one.
+	TotalValueCorrect bool `json:"total_value_correct"`
+	// TotalValueError is the relative error of the total value.
+	TotalValueError float64 `json:"total_value_error"`
+	// PercentageError is the summed absolute error, in percentage points, of
+	// the milestones that matched; MatchedMilestones is their number.
+	PercentageError   float64 `json:"percentage_error"`
+	MatchedMilestones int     `json:"matched_milestones"`
+}
+
+// ScoreExtraction scores actual against expected. Parties match when their
+// names agree apart from case, punctuation and legal suffixes; milestones
+// match by percentage and risks by type.
+func ScoreExtraction(expected, actual *Extraction) *Score {
+	s := &Score{Fields: make(map[string]Counts, len(Fields))}
+	s.Fields[FieldBuyer] = scoreValue(llm.PartyKey(expected.Buyer), llm.PartyKey(actual.Buyer))
+	s.Fields[FieldSeller] = scoreValue(llm.PartyKey(expected.Seller), llm.PartyKey(actual.Seller))
+	s.Fields[FieldCurrency] = scoreValue(strings.ToUpper(strings.TrimSpace(expected.Currency)), strings.ToUpper(strings.TrimSpace(actual.Currency)))
+
+	s.TotalValueCorrect = expected.TotalValue.Sub(actual.TotalValue).Abs().LessThanOrEqual(decimal.NewFromFloat(valueTolerance))
+	if !expected.TotalValue.IsZero() {
+		s.TotalValueError, _ = expected.TotalValue.Sub(actual.TotalValue).Abs().Div(expected.TotalValue.Abs()).Float64()
+	}
+	var total Counts
+	switch {
+	case !actual.TotalValue.IsZero() && s.TotalValueCorrect:
+		total.TruePositives = 1
+	case !actual.TotalValue.IsZero():
+		total.FalsePositives = 1
+		if !expected.TotalValue.IsZero() {
+			total.FalseNegatives = 1
+		}
+	case !expected.TotalValue.IsZero():
+		total.FalseNegatives = 1
+	}
+	s.Fields[FieldTotalValue] = total
+
+	s.Fields[FieldMilestones] = s.scoreMilestones(expected.Milestones, actual.Milestones)
+	s.Fields[FieldRisks] = scoreRisks(expected.Risks, actual.Risks)
+	return s
+}
+
+// Overall returns the counts of all fields together.
+func (s *Score) Overall() Counts {
+	var c Counts
+	for _, field := range Fields {
+		c.Add(s.Fields[field])
+	}
+	return c
+}
+
+// scoreValue scores a single normalized value; an empty value is absent.
+func scoreValue(expected, actual string) Counts {
+	switch {
+	case actual != "" && actual == expected:
+		return Counts{TruePositives: 1}
+	case actual != "" && expected != "":
+		return Counts{FalsePositives: 1, FalseNegatives: 1}
+	case actual != "":
+		return Counts{FalsePositives: 1}
+	case expected != "":
+		return Counts{FalseNegatives: 1}
+	}
+	return Counts{}
+}
+
+// scoreMilestones matches each expected milestone with the closest unmatched
+// extracted milestone by percentage.
+func (s *Score) scoreMilestones(expected, actual []Milestone) Counts {
+	used := make([]bool, len(actual))
+	var c Counts
+	for _, e := range expected {
+		best, bestDiff := -1, percentageTolerance
+		for i, a := range actual {
+			if diff := math.Abs(e.Percentage - a.Percentage); !used[i] && diff <= bestDiff {
+				best, bestDiff = i, diff
+			}
+		}
+		if best < 0 {
+			c.FalseNegatives++
+			continue
+		}
+		used[best] = true
+		c.TruePositives++
+		s.MatchedMilestones++
+		s.PercentageError += bestDiff
+	}
+	c.FalsePositives = len(actual) - c.TruePositives
+	return c
+}
+
+// scoreRisks matches risks whose types are equal or contain one another, so
+// that "payment" matches "late payment".
+func scoreRisks(expected, actual []Risk) Counts {
+	used := make([]bool, len(actual))
+	var c Counts
+	for _, e := range expected {
+		want := riskKey(e.Type)
+		matched := false
+		for i, a := range actual {
+			got := riskKey(a.Type)
+			if !used[i] && got != "" && (strings.Contains(got, want) || strings.Contains(want, got)) {
+				used[i], matched = true, true
+				break
+			}
+		}
+		if matched {
+			c.TruePositives++
+		} else {
+			c.FalseNegatives++
+		}
+	}
+	c.FalsePositives = len(actual) - c.TruePositives
+	return c
+}
+
+func riskKey(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	}), " ")
+}
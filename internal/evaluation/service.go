@@ -0,0 +1,26 @@
+package evaluation
+
+import (
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/services/llm"
+	llmclient "contract-analysis-service/internal/services/llm/client"
+	"go.uber.org/zap"
+)
+
+// NewService returns the LLM service of cfg with every provider behind a
+// cassette in dir, one directory per provider, that records or replays the
+// provider's traffic according to mode (external.CassetteRecord or
+// external.CassetteReplay). Replaying needs no API keys or network.
+func NewService(cfg *configs.Config, dir, mode string, logger *zap.Logger) (llm.Service, error) {
+	withCassette := *cfg
+	withCassette.LLM.Cassette = configs.CassetteConfig{Mode: mode, Dir: dir}
+
+	service := llm.NewRouter(llm.NewLLMService(logger), withCassette.LLM.Routing, logger)
+	if err := llmclient.AddOpenRouterClientToService(service, &withCassette); err != nil {
+		return nil, err
+	}
+	if err := llmclient.AddAnthropicClientToService(service, &withCassette); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
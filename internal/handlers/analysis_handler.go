@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"contract-analysis-service/internal/services/analysis"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// streamKeepAlive is how often a comment is sent on an idle event stream so
+// that proxies do not close it.
+const streamKeepAlive = 15 * time.Second
+
+// AnalysisHandler handles HTTP requests about the progress of contract analyses.
+type AnalysisHandler struct {
+	orchestrator *analysis.Orchestrator
+	logger       *zap.Logger
+}
+
+// NewAnalysisHandler creates a new AnalysisHandler.
+func NewAnalysisHandler(orchestrator *analysis.Orchestrator, logger *zap.Logger) *AnalysisHandler {
+	return &AnalysisHandler{
+		orchestrator: orchestrator,
+		logger:       logger,
+	}
+}
+
+// GetStages returns the status of each analysis stage of a contract.
+// @Summary Get the analysis stages of a contract
+// @Description List the stages of the contract's analysis that have run, with their status, attempts, output and error. A failed analysis resumes from the failed stage when it is started again.
+// @Tags Analysis
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {array} models.AnalysisStage
+// @Failure 404 {object} map[string]string "Document not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /contracts/{id}/analysis/stages [get]
+func (h *AnalysisHandler) GetStages(c *gin.Context) {
+	id := c.Param("id")
+
+	stages, err := h.orchestrator.ListStages(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, analysis.ErrContractNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+			return
+		}
+		h.logger.Error("Failed to list analysis stages", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list analysis stages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stages)
+}
+
+// Stream streams the progress of a contract's analysis as server-sent events.
+// @Summary Stream the progress of a contract's analysis
+// @Description Stream the analysis of a contract as server-sent events. A "stage" event is sent for each stage recorded so far and whenever a stage starts, succeeds or fails; "field" events carry the fields of a stage's output as the model produces them, e.g. {"stage":"analysis","field":"milestones[0]","value":{...}}. The stream ends with a "completed" event, at once for a contract that has already been analyzed, or with a "failed" event when the run fails. Queue the analysis with POST /contracts/{id}/analyze.
+// @Tags Analysis
+// @Produce text/event-stream
+// @Param id path string true "Document ID"
+// @Success 200 {object} analysis.Event "Stream of analysis events"
+// @Failure 404 {object} map[string]string "Document not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /contracts/{id}/analysis/stream [get]
+func (h *AnalysisHandler) Stream(c *gin.Context) {
+	id := c.Param("id")
+
+	events, unsubscribe, err := h.orchestrator.Subscribe(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, analysis.ErrContractNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+			return
+		}
+		h.logger.Error("Failed to subscribe to analysis progress", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to stream analysis progress"})
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Keep reverse proxies such as nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -1,13 +1,23 @@
 package handlers
 
 import (
+	"errors"
+	"mime"
 	"net/http"
+	"time"
 
+	"contract-analysis-service/internal/pkg/storage"
+	"contract-analysis-service/internal/repositories"
 	"contract-analysis-service/internal/services/document"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+const (
+	defaultFileURLExpiry = 15 * time.Minute
+	maxFileURLExpiry     = 24 * time.Hour
+)
+
 // DocumentHandler handles HTTP requests for document operations.
 type DocumentHandler struct {
 	service document.Service
@@ -31,6 +41,9 @@ func NewDocumentHandler(service document.Service, logger *zap.Logger) *DocumentH
 // @Param file formData file true "The document to upload"
 // @Success 201 {object} map[string]string "Returns the ID of the uploaded document"
 // @Failure 400 {object} map[string]string "Bad request if the file is missing, invalid, or too large"
+// @Failure 409 {object} map[string]string "The tenant already uploaded the same document; returns the existing document ID"
+// @Failure 415 {object} map[string]string "File content does not match its extension"
+// @Failure 422 {object} map[string]string "File is encrypted or password protected"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /contracts/upload [post]
 func (h *DocumentHandler) Upload(c *gin.Context) {
@@ -50,8 +63,22 @@ func (h *DocumentHandler) Upload(c *gin.Context) {
 
 	documentID, err := h.service.Upload(c.Request.Context(), file, fileHeader)
 	if err != nil {
+		var duplicate *document.DuplicateContractError
+		if errors.As(err, &duplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "document_id": duplicate.ContractID})
+			return
+		}
+
 		h.logger.Error("Failed to upload document", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		var mismatch *document.ContentTypeMismatchError
+		switch {
+		case errors.As(err, &mismatch):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error(), "detected_type": mismatch.Detected})
+		case errors.Is(err, document.ErrEncryptedDocument):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -81,6 +108,79 @@ func (h *DocumentHandler) Get(c *gin.Context) {
 	c.JSON(http.StatusOK, document)
 }
 
+// GetFile streams the original uploaded file of a document.
+// @Summary Download the original file of a document
+// @Description Stream the file that was uploaded for the document, with its original content type and filename.
+// @Tags Documents
+// @Produce octet-stream
+// @Param id path string true "Document ID"
+// @Success 200 {file} file "The original document"
+// @Failure 404 {object} map[string]string "Document or file not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /contracts/{id}/file [get]
+func (h *DocumentHandler) GetFile(c *gin.Context) {
+	id := c.Param("id")
+
+	file, info, err := h.service.OpenFile(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) || errors.Is(err, storage.ErrFileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+			return
+		}
+		h.logger.Error("Failed to open document file", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open document file"})
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, file, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("inline", map[string]string{"filename": info.Name}),
+	})
+}
+
+// GetFileURL returns a time-limited download link for the original file.
+// @Summary Get a signed download URL for a document's file
+// @Description Return a URL that downloads the original file without further authentication until it expires.
+// @Tags Documents
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param expires_in query string false "Lifetime of the link as a duration, e.g. 15m (default 15m, max 24h)"
+// @Success 200 {object} map[string]string "Returns the signed URL and its expiry"
+// @Failure 400 {object} map[string]string "Invalid expires_in"
+// @Failure 404 {object} map[string]string "Document not found"
+// @Failure 501 {object} map[string]string "Storage backend cannot issue signed URLs"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /contracts/{id}/file/url [get]
+func (h *DocumentHandler) GetFileURL(c *gin.Context) {
+	id := c.Param("id")
+
+	expiry := defaultFileURLExpiry
+	if raw := c.Query("expires_in"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 || d > maxFileURLExpiry {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in must be a duration between 1s and 24h"})
+			return
+		}
+		expiry = d
+	}
+
+	url, err := h.service.FileURL(c.Request.Context(), id, expiry)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		case errors.Is(err, storage.ErrSignedURLsUnsupported):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to sign document file URL", zap.String("id", id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create download URL"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"url": url, "expires_at": time.Now().Add(expiry).UTC().Format(time.RFC3339)})
+}
+
 // Delete handles the document deletion endpoint.
 // @Summary Delete a document by ID
 // @Description Delete a single document by its unique ID. This will remove the file from storage and the record from the database.
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"contract-analysis-service/internal/pkg/storage"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// FileHandler serves signed download links for storage backends that cannot
+// serve files themselves, such as local disk.
+type FileHandler struct {
+	storage storage.FileStorage
+	logger  *zap.Logger
+}
+
+// NewFileHandler creates a new FileHandler.
+func NewFileHandler(storage storage.FileStorage, logger *zap.Logger) *FileHandler {
+	return &FileHandler{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// Download streams a file after checking the signature of the link.
+// @Summary Download a file through a signed URL
+// @Description Serve a file from local storage using a link issued by GET /contracts/{id}/file/url.
+// @Tags Files
+// @Produce octet-stream
+// @Param name path string true "Stored file name"
+// @Param expires query int true "Expiry of the link as a Unix timestamp"
+// @Param signature query string true "Signature of the link"
+// @Success 200 {file} file "The stored file"
+// @Failure 403 {object} map[string]string "Invalid or expired signature"
+// @Failure 404 {object} map[string]string "File not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /files/{name} [get]
+func (h *FileHandler) Download(c *gin.Context) {
+	verifier, ok := h.storage.(storage.SignedURLVerifier)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+
+	name := c.Param("name")
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": storage.ErrInvalidSignature.Error()})
+		return
+	}
+
+	filePath, err := verifier.VerifySignedURL(name, expires, c.Query("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrFileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		default:
+			c.JSON(http.StatusForbidden, gin.H{"error": storage.ErrInvalidSignature.Error()})
+		}
+		return
+	}
+
+	info, err := h.storage.Stat(filePath)
+	if err != nil {
+		h.writeError(c, filePath, err)
+		return
+	}
+	file, err := h.storage.Open(filePath)
+	if err != nil {
+		h.writeError(c, filePath, err)
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, file, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("inline", map[string]string{"filename": info.Name}),
+	})
+}
+
+func (h *FileHandler) writeError(c *gin.Context, filePath string, err error) {
+	if errors.Is(err, storage.ErrFileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+	h.logger.Error("Failed to serve file", zap.String("path", filePath), zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file"})
+}
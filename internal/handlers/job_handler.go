@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"contract-analysis-service/internal/services/jobs"
+	"contract-analysis-service/internal/services/usage"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// JobHandler handles HTTP requests for background analysis jobs.
+type JobHandler struct {
+	service jobs.Service
+	logger  *zap.Logger
+}
+
+// NewJobHandler creates a new JobHandler.
+func NewJobHandler(service jobs.Service, logger *zap.Logger) *JobHandler {
+	return &JobHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Analyze queues the analysis of a contract.
+// @Summary Start the analysis of a contract
+// @Description Queue the full LLM analysis of an uploaded contract. Poll GET /jobs/{id} for the outcome. If an analysis is already queued or running for the contract, that job is returned. New analyses are rejected once the contract's tenant has spent its monthly LLM budget.
+// @Tags Jobs
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 202 {object} map[string]string "Returns the job ID and status"
+// @Failure 404 {object} map[string]string "Document not found"
+// @Failure 429 {object} map[string]string "Monthly LLM budget exhausted"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /contracts/{id}/analyze [post]
+func (h *JobHandler) Analyze(c *gin.Context) {
+	id := c.Param("id")
+
+	job, err := h.service.Enqueue(c.Request.Context(), jobs.JobTypeAnalysis, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrContractNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+			return
+		case errors.Is(err, usage.ErrBudgetExhausted):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to queue analysis", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to queue analysis"})
+		return
+	}
+
+	c.Header("Location", "/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, gin.H{"job_id": job.ID, "status": job.Status})
+}
+
+// Get returns the state of a job.
+// @Summary Get a job by ID
+// @Description Get the status of a background job, with its result once it has succeeded or its error once it has failed.
+// @Tags Jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.Job
+// @Failure 404 {object} map[string]string "Job not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jobs/{id} [get]
+func (h *JobHandler) Get(c *gin.Context) {
+	id := c.Param("id")
+
+	job, err := h.service.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+			return
+		}
+		h.logger.Error("Failed to get job", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// Cancel cancels a job.
+// @Summary Cancel a job
+// @Description Cancel a queued job, or ask the worker running it to stop. A running job moves to cancelled once its worker notices.
+// @Tags Jobs
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 202 {object} models.Job
+// @Failure 404 {object} map[string]string "Job not found"
+// @Failure 409 {object} map[string]string "Job has already finished"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jobs/{id}/cancel [post]
+func (h *JobHandler) Cancel(c *gin.Context) {
+	id := c.Param("id")
+
+	job, err := h.service.Cancel(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		case errors.Is(err, jobs.ErrJobFinished):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to cancel job", zap.String("id", id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel job"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
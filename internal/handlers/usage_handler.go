@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"contract-analysis-service/internal/services/usage"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// UsageHandler handles HTTP requests about LLM usage and cost.
+type UsageHandler struct {
+	service usage.Service
+	logger  *zap.Logger
+}
+
+// NewUsageHandler creates a new UsageHandler.
+func NewUsageHandler(service usage.Service, logger *zap.Logger) *UsageHandler {
+	return &UsageHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetContractUsage returns the LLM usage of a contract.
+// @Summary Get the LLM usage of a contract
+// @Description Get the prompt and completion tokens and the cost of the LLM calls made for a contract, from its validation at upload through every analysis run, in total and per stage.
+// @Tags Analysis
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} models.ContractUsage
+// @Failure 404 {object} map[string]string "Document not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /contracts/{id}/usage [get]
+func (h *UsageHandler) GetContractUsage(c *gin.Context) {
+	id := c.Param("id")
+
+	result, err := h.service.GetContractUsage(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, usage.ErrContractNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+			return
+		}
+		h.logger.Error("Failed to get contract usage", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get contract usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
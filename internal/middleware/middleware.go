@@ -3,6 +3,8 @@ package middleware
 import (
 	"fmt"
 	"time"
+
+	"contract-analysis-service/internal/pkg/tenant"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/requestid"
 	"github.com/gin-contrib/zap"
@@ -78,7 +80,10 @@ func (m *Middleware) Recovery() gin.HandlerFunc {
 	return ginzap.RecoveryWithZap(m.Logger, true)
 }
 
-// JWT configures JWT authentication middleware
+// JWT configures JWT authentication middleware. The tenant named by the
+// tenant_id claim of a valid token is attached to the request context, so
+// that LLM usage and budgets are accounted to it; tokens without the claim
+// belong to the default tenant.
 func (m *Middleware) JWT(secret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
@@ -103,6 +108,12 @@ func (m *Middleware) JWT(secret string) gin.HandlerFunc {
 			c.AbortWithStatusJSON(401, gin.H{"error": "invalid token"})
 			return
 		}
+
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			if id, ok := claims[tenant.Claim].(string); ok && id != "" {
+				c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), id))
+			}
+		}
 		
 		c.Next()
 	}
@@ -112,3 +123,4 @@ func (m *Middleware) JWT(secret string) gin.HandlerFunc {
 func (m *Middleware) RequestID() gin.HandlerFunc {
 	return requestid.New()
 }
+
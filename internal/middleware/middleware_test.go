@@ -12,12 +12,14 @@ import (
 	"github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/ulule/limiter/v3"
 	"github.com/ulule/limiter/v3/drivers/store/memory"
 	"go.uber.org/zap"
 
 	"contract-analysis-service/internal/middleware"
+	"contract-analysis-service/internal/pkg/tenant"
 )
 
 func TestRecovery(t *testing.T) {
@@ -232,6 +234,36 @@ func TestRequestID(t *testing.T) {
 	assert.True(t, len(requestID) > 0)
 }
 
+func TestJWT_Tenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	mw := middleware.NewMiddleware(zap.NewNop())
+	router.Use(mw.JWT("secret"))
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, tenant.FromContext(c.Request.Context()))
+	})
+
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		assert.NoError(t, err)
+		return token
+	}
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", sign(jwt.MapClaims{tenant.Claim: "acme"}))
+	req.Header.Set("X-Tenant-ID", "globex")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "acme", w.Body.String(), "the tenant comes from the token, not a header")
+
+	// Tokens without the claim belong to the default tenant
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", sign(jwt.MapClaims{"sub": "user"}))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, tenant.Default, w.Body.String())
+}
+
 func TestValidation(t *testing.T) {
 	// Set up a test router with the validation middleware
 	gin.SetMode(gin.TestMode)
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 	"github.com/shopspring/decimal"
 )
@@ -8,12 +9,36 @@ import (
 type Contract struct {
 	ID          string                 `json:"id" gorm:"primaryKey"`
 	FilePath    string                 `json:"file_path"`
-	Hash        string                 `json:"contract_hash"`
+	FileName    string                 `json:"file_name"`
+	// Hash is the SHA-256 of the uploaded file; a tenant has at most one
+	// contract per hash.
+	Hash        string                 `json:"contract_hash" gorm:"uniqueIndex:idx_contracts_tenant_hash,priority:2"`
 	Summary     *ContractSummary       `json:"summary" gorm:"embedded"`
 	Milestones  []*Milestone           `json:"milestones" gorm:"foreignKey:ContractID"`
 	Risks       []*RiskAssessment      `json:"risks" gorm:"foreignKey:ContractID"`
 	Compliance  *ComplianceReport      `json:"compliance" gorm:"embedded"`
-	Validation  *ValidationResult      `json:"validation" gorm:"embedded"`
+	Validation  *ValidationResult      `json:"validation" gorm:"embedded;embeddedPrefix:validation_"`
+	Document    *ExtractedDocument     `json:"document,omitempty" gorm:"foreignKey:ContractID"`
+	SecurityFlags []string             `json:"security_flags,omitempty" gorm:"serializer:json"`
+	// UnverifiedFields lists the fields whose quoted source could not be
+	// found in the document, e.g. "summary.buyer_name" or "milestones[2]".
+	// They may have been hallucinated and need manual review.
+	UnverifiedFields []string          `json:"unverified_fields,omitempty" gorm:"serializer:json"`
+	// DisputedFields lists the fields the runs of an ensemble analysis did
+	// not agree on, or that contradict the values the rule-based extraction
+	// found in the text, e.g. "summary.total_value" or
+	// "milestones[1].percentage". They need manual review.
+	DisputedFields []string            `json:"disputed_fields,omitempty" gorm:"serializer:json"`
+	// Findings are the problems validating the analysis found, such as
+	// milestone percentages that do not add up to 100%. They are recorded for
+	// review rather than fixed.
+	Findings []Finding                 `json:"findings,omitempty" gorm:"serializer:json"`
+	// PromptVersions lists the prompt templates the analysis was produced
+	// with, e.g. "risk_assessment@v2.industry-healthcare".
+	PromptVersions []string            `json:"prompt_versions,omitempty" gorm:"serializer:json"`
+	// TenantID is the tenant the contract was uploaded by; its LLM usage
+	// counts against that tenant's budget.
+	TenantID    string                 `json:"tenant_id,omitempty" gorm:"uniqueIndex:idx_contracts_tenant_hash,priority:1"`
 	KnowledgeID string                 `json:"knowledge_id"`
 	Confidence  float64                `json:"confidence_score"`
 	Status        ContractStatus         `json:"status" gorm:"type:varchar(50)"`
@@ -22,6 +47,32 @@ type Contract struct {
 	UpdatedAt   time.Time              `json:"updated_at"`
 }
 
+// Finding is a problem found when validating the analysis of a contract.
+type Finding struct {
+	// Code identifies the kind of problem; see the Finding constants.
+	Code string `json:"code"`
+	// Field is the field the problem is in, e.g. "milestones[1].amount", or
+	// "milestones" for all of them.
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Finding codes.
+const (
+	// FindingAmountMismatch means a milestone's amount is not its
+	// percentage of the total value.
+	FindingAmountMismatch = "amount_mismatch"
+	// FindingPercentageSum means the milestone percentages do not add up to
+	// 100%.
+	FindingPercentageSum = "percentage_sum"
+	// FindingAmountSum means the milestone amounts do not add up to the
+	// total value.
+	FindingAmountSum = "amount_sum"
+	// FindingMissingTotal means milestone amounts could not be derived from
+	// their percentages because the total value is not known.
+	FindingMissingTotal = "missing_total"
+)
+
 type ContractSummary struct {
 	BuyerName    string `json:"buyer_name"`
 	SellerName   string `json:"seller_name"`
@@ -29,6 +80,21 @@ type ContractSummary struct {
 	TotalValue   decimal.Decimal `json:"total_value" gorm:"type:decimal(20,8)"`
 	Currency     string `json:"currency"`
 	Jurisdiction string `json:"jurisdiction"`
+	// Sources cites the passages the summary fields were taken from, keyed by
+	// field name (buyer_name, seller_name, total_value, currency).
+	Sources map[string]*SourceSpan `json:"sources,omitempty" gorm:"serializer:json"`
+}
+
+// SourceSpan cites the passage of a contract a field was extracted from.
+type SourceSpan struct {
+	// Page is the number of the page the passage starts on.
+	Page int `json:"page,omitempty"`
+	// Start and End are byte offsets into the text of the extracted document.
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Quote string `json:"quote"`
+	// Verified reports whether the quote was found in the document.
+	Verified bool `json:"verified"`
 }
 
 type Milestone struct {
@@ -39,10 +105,11 @@ type Milestone struct {
 	Percentage     float64           `json:"percentage"`
 	Trigger        string            `json:"trigger_condition"`
 	SequenceOrder  int               `json:"sequence_order"`
-	Dependencies   []string          `json:"dependencies" gorm:"type:text[]"`
+	Dependencies   []string          `json:"dependencies" gorm:"serializer:json"`
 	Category       string            `json:"category"`
 	Verification   VerificationMethod `json:"verification_method" gorm:"type:varchar(50)"`
 	OracleConfig   *OracleConfig     `json:"oracle_config,omitempty" gorm:"embedded"`
+	Source         *SourceSpan       `json:"source,omitempty" gorm:"serializer:json"`
 }
 
 type VerificationMethod string
@@ -67,6 +134,7 @@ type RiskAssessment struct {
 	Description string `json:"description"`
 	Recommendation string `json:"recommendation"`
 	IndustryRef string `json:"industry_reference"`
+	Source    *SourceSpan `json:"source,omitempty" gorm:"serializer:json"`
 }
 
 type Severity string
@@ -79,8 +147,8 @@ const (
 )
 
 type ComplianceReport struct {
-	MissingClauses []string `json:"missing_clauses" gorm:"type:text[]"`
-	Suggestions    []string `json:"suggestions" gorm:"type:text[]"`
+	MissingClauses []string `json:"missing_clauses" gorm:"serializer:json"`
+	Suggestions    []string `json:"suggestions" gorm:"serializer:json"`
 	IsCompliant    bool     `json:"is_compliant"`
 	Report         string   `json:"report" gorm:"type:text"`
 }
@@ -113,7 +181,7 @@ type DisputePath struct {
 	Method     string   `json:"method"`
 	Priority   string   `json:"priority"`
 	Category   string   `json:"category"`
-	Transitions []string `json:"state_transitions" gorm:"type:text[]"`
+	Transitions []string `json:"state_transitions" gorm:"serializer:json"`
 }
 
 type KnowledgeEntry struct {
@@ -132,6 +200,10 @@ type ContractStatus string
 const (
 	Validated ContractStatus = "validated"
 	Analyzed  ContractStatus = "analyzed"
+	// Degraded means the contract was analysed by the rule-based extraction
+	// alone, while no LLM provider was available; it has no risks or
+	// compliance report until the analysis is completed.
+	Degraded ContractStatus = "degraded"
 )
 
 // ContractAnalysis represents the structured analysis result from an LLM.
@@ -143,6 +215,17 @@ type ContractAnalysis struct {
 	Currency      string              `json:"currency"`
 	Milestones    []AnalysisMilestone `json:"milestones"`
 	RiskFactors   []AnalysisRisk      `json:"risk_factors"`
+	// SourceQuotes holds the verbatim passages the buyer, seller, total_value
+	// and currency were taken from, keyed by field name.
+	SourceQuotes map[string]string `json:"source_quotes,omitempty"`
+	// Confidence, DisputedFields and FailedRuns are not part of the LLM
+	// output. Confidence is the share of runs that agree on the most disputed
+	// field, which is 1 for an analysis by a single run. DisputedFields and
+	// FailedRuns, how many runs failed and were left out of the vote, are set
+	// when the analysis is voted on by several runs.
+	Confidence     float64  `json:"confidence,omitempty" jsonschema:"-"`
+	DisputedFields []string `json:"disputed_fields,omitempty" jsonschema:"-"`
+	FailedRuns     int      `json:"failed_runs,omitempty" jsonschema:"-"`
 }
 
 // AnalysisMilestone is a simplified milestone structure for LLM parsing.
@@ -150,6 +233,11 @@ type AnalysisMilestone struct {
 	Description string          `json:"description"`
 	Amount      decimal.Decimal `json:"amount"`
 	Percentage  float64         `json:"percentage"`
+	// SourceQuote is the verbatim passage the milestone was taken from.
+	SourceQuote string `json:"source_quote,omitempty"`
+	// SourceChunks lists the chunks of a long contract the milestone was
+	// found in; it is not part of the LLM output.
+	SourceChunks []int `json:"source_chunks,omitempty" jsonschema:"-"`
 }
 
 // AnalysisRisk is a simplified risk structure for LLM parsing.
@@ -157,6 +245,11 @@ type AnalysisRisk struct {
 	Type        string `json:"type"`
 	Description string `json:"description"`
 	Severity    string `json:"severity"`
+	// SourceQuote is the verbatim passage the risk was found in.
+	SourceQuote string `json:"source_quote,omitempty"`
+	// SourceChunks lists the chunks of a long contract the risk was found in;
+	// it is not part of the LLM output.
+	SourceChunks []int `json:"source_chunks,omitempty" jsonschema:"-"`
 }
 
 // SequencedMilestone represents a milestone with sequencing information from the LLM.
@@ -197,6 +290,8 @@ type AnalysisIndividualRisk struct {
 	Severity     string `json:"severity"`
 	Description  string `json:"description"`
 	Recommendation string `json:"recommendation"`
+	// SourceQuote is the verbatim passage the risk was found in.
+	SourceQuote string `json:"source_quote,omitempty"`
 }
 
 // ValidationResult represents the outcome of a contract validation check.
@@ -205,6 +300,163 @@ type ValidationResult struct {
 	Reason           string   `json:"reason,omitempty"`
 	Confidence       float64  `json:"confidence"`
 	ContractType     string   `json:"contract_type,omitempty"`
-	MissingElements  []string `json:"missing_elements,omitempty"`
-	DetectedElements []string `json:"detected_elements,omitempty"`
+	MissingElements  []string `json:"missing_elements,omitempty" gorm:"serializer:json"`
+	DetectedElements []string `json:"detected_elements,omitempty" gorm:"serializer:json"`
+	// PromptVersion is the prompt template the result was produced with; it
+	// is not part of the LLM output.
+	PromptVersion string `json:"prompt_version,omitempty" jsonschema:"-"`
+}
+
+// ExtractedDocument holds the text extracted from a contract's source file.
+// It is the input to validation and every later LLM call, so the file only
+// needs to be read once.
+type ExtractedDocument struct {
+	ID         string           `json:"id" gorm:"primaryKey"`
+	ContractID string           `json:"contract_id" gorm:"uniqueIndex"`
+	Text       string           `json:"text" gorm:"type:text"`
+	Pages      []*ExtractedPage `json:"pages" gorm:"foreignKey:DocumentID"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+// ExtractedPage is the text of a single page and how it was obtained.
+type ExtractedPage struct {
+	ID         string     `json:"id" gorm:"primaryKey"`
+	DocumentID string     `json:"document_id" gorm:"index"`
+	Number     int        `json:"number"`
+	Text       string     `json:"text" gorm:"type:text"`
+	Source     PageSource `json:"source" gorm:"type:varchar(20)"`
+	Confidence float64    `json:"confidence"`
+}
+
+// PageSource describes how the text of a page was obtained.
+type PageSource string
+
+const (
+	// PageSourceTextLayer means the text was read from the document's embedded text layer.
+	PageSourceTextLayer PageSource = "text_layer"
+	// PageSourceOCR means the text was recognised from an image of the page.
+	PageSourceOCR PageSource = "ocr"
+)
+
+// Security flags recorded on a Contract when its source file contains content
+// that warrants manual review.
+const (
+	// SecurityFlagJavaScript means the PDF contains JavaScript actions.
+	SecurityFlagJavaScript = "pdf_javascript"
+	// SecurityFlagEmbeddedFile means the PDF carries embedded file attachments.
+	SecurityFlagEmbeddedFile = "pdf_embedded_file"
+)
+
+// Job is a unit of background work, such as analysing a contract, persisted so
+// that it survives restarts and can be picked up by any replica.
+type Job struct {
+	ID              string          `json:"id" gorm:"primaryKey"`
+	Type            string          `json:"type" gorm:"index"`
+	ContractID      string          `json:"contract_id" gorm:"index"`
+	Status          JobStatus       `json:"status" gorm:"type:varchar(20);index"`
+	Attempts        int             `json:"attempts"`
+	MaxAttempts     int             `json:"max_attempts"`
+	Error           string          `json:"error,omitempty" gorm:"type:text"`
+	Result          json.RawMessage `json:"result,omitempty" gorm:"serializer:json"`
+	CancelRequested bool            `json:"cancel_requested"`
+	WorkerID        string          `json:"-"`
+	RunAt           time.Time       `json:"run_at" gorm:"index"`
+	LeaseExpiresAt  *time.Time      `json:"-"`
+	StartedAt       *time.Time      `json:"started_at,omitempty"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// JobStatus is the lifecycle state of a Job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// IsFinished reports whether the status is terminal.
+func (s JobStatus) IsFinished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCancelled
+}
+
+// AnalysisStage records the outcome of one step of a contract's analysis, so
+// that a failed analysis can resume from the step that failed instead of
+// repeating the LLM calls that already succeeded.
+type AnalysisStage struct {
+	ID         string          `json:"id" gorm:"primaryKey"`
+	ContractID string          `json:"contract_id" gorm:"uniqueIndex:idx_analysis_stage_contract_stage"`
+	Stage      string          `json:"stage" gorm:"type:varchar(30);uniqueIndex:idx_analysis_stage_contract_stage"`
+	Status     StageStatus     `json:"status" gorm:"type:varchar(20)"`
+	Attempts   int             `json:"attempts"`
+	Error      string          `json:"error,omitempty" gorm:"type:text"`
+	Output     json.RawMessage `json:"output,omitempty" gorm:"serializer:json"`
+	// PromptVersions lists the prompt templates the stage rendered.
+	PromptVersions []string `json:"prompt_versions,omitempty" gorm:"serializer:json"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// StageStatus is the state of an AnalysisStage.
+type StageStatus string
+
+const (
+	StageRunning   StageStatus = "running"
+	StageSucceeded StageStatus = "succeeded"
+	StageFailed    StageStatus = "failed"
+)
+
+// LLMUsage records the tokens used and the cost of one LLM call, attributed
+// to the contract, analysis stage and tenant it was made for.
+type LLMUsage struct {
+	ID               string          `json:"id" gorm:"primaryKey"`
+	ContractID       string          `json:"contract_id,omitempty" gorm:"index"`
+	TenantID         string          `json:"tenant_id" gorm:"index:idx_llm_usage_tenant_created"`
+	Stage            string          `json:"stage,omitempty" gorm:"type:varchar(30)"`
+	Provider         string          `json:"provider"`
+	Model            string          `json:"model"`
+	PromptTokens     int             `json:"prompt_tokens"`
+	CompletionTokens int             `json:"completion_tokens"`
+	TotalTokens      int             `json:"total_tokens"`
+	Cost             decimal.Decimal `json:"cost" gorm:"type:decimal(20,8)"`
+	CreatedAt        time.Time       `json:"created_at" gorm:"index:idx_llm_usage_tenant_created"`
+}
+
+// UsageTotals sums the LLM usage of a number of calls.
+type UsageTotals struct {
+	Calls            int             `json:"calls"`
+	PromptTokens     int             `json:"prompt_tokens"`
+	CompletionTokens int             `json:"completion_tokens"`
+	TotalTokens      int             `json:"total_tokens"`
+	Cost             decimal.Decimal `json:"cost"`
+}
+
+// Add adds the usage of one call to the totals.
+func (t *UsageTotals) Add(u *LLMUsage) {
+	t.Calls++
+	t.PromptTokens += u.PromptTokens
+	t.CompletionTokens += u.CompletionTokens
+	t.TotalTokens += u.TotalTokens
+	t.Cost = t.Cost.Add(u.Cost)
+}
+
+// ContractUsage is the LLM usage of a contract, in total and per stage.
+type ContractUsage struct {
+	ContractID string `json:"contract_id"`
+	TenantID   string `json:"tenant_id"`
+	Currency   string `json:"currency"`
+	UsageTotals
+	// Stages lists the usage of each stage in the order the stages first ran.
+	Stages []*StageUsage `json:"stages"`
+}
+
+// StageUsage is the LLM usage of one stage of a contract's analysis.
+type StageUsage struct {
+	Stage string `json:"stage"`
+	UsageTotals
 }
@@ -1,6 +1,8 @@
 package container
 
 import (
+	"context"
+	"fmt"
 	"contract-analysis-service/configs"
 	"contract-analysis-service/internal/handlers"
 	"contract-analysis-service/internal/pkg/cache"
@@ -12,11 +14,16 @@ import (
 	"contract-analysis-service/internal/pkg/tracing"
 	"contract-analysis-service/internal/repositories"
 	"contract-analysis-service/internal/repositories/sqlite"
+	"contract-analysis-service/internal/services/analysis"
 	"contract-analysis-service/internal/services/document"
+	"contract-analysis-service/internal/services/extraction"
+	"contract-analysis-service/internal/services/jobs"
 	"contract-analysis-service/internal/services/knowledge"
 	"contract-analysis-service/internal/services/llm"
 	llmclient "contract-analysis-service/internal/services/llm/client"
+	"contract-analysis-service/internal/services/llm/prompts"
 	"contract-analysis-service/internal/services/ocr"
+	"contract-analysis-service/internal/services/usage"
 	"contract-analysis-service/internal/services/validation"
 	"github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
@@ -32,17 +39,32 @@ type Container struct {
 	Tracer      *tracing.TracerProvider
 	RedisClient *redis.Client
 	OCRTMetrics *metrics.OCRMetrics
+	LLMMetrics  *metrics.LLMMetrics
 
 	// Repositories
 	ContractRepo  repositories.ContractRepository
+	DocumentRepo  repositories.ExtractedDocumentRepository
 	KnowledgeRepo repositories.KnowledgeEntryRepository
+	JobRepo       repositories.JobRepository
+	StageRepo     repositories.AnalysisStageRepository
+	UsageRepo     repositories.LLMUsageRepository
+
+	// Storage
+	FileStorage storage.FileStorage
 
 	// Services
 	LLMService        llm.Service
 	OCRService        ocr.Service
+	ExtractionService extraction.Service
 	DocumentService   document.Service
 	ValidationService validation.Service
 	KnowledgeService  knowledge.Service
+	JobService        jobs.Service
+	UsageService      usage.Service
+	Orchestrator      *analysis.Orchestrator
+
+	// Background workers; started by the server with WorkerPool.Start
+	WorkerPool *jobs.WorkerPool
 }
 
 // NewContainer creates and initializes a new Container
@@ -64,6 +86,7 @@ func NewContainer(cfg *configs.Config) *Container {
 
 	// Initialize metrics
 	ocrMetrics := metrics.NewOCRMetrics()
+	llmMetrics := metrics.NewLLMMetrics()
 
 	// Set log mode if enabled
 	if cfg.Database.GetLogMode() {
@@ -86,9 +109,26 @@ func NewContainer(cfg *configs.Config) *Container {
 		logger.Info("tracing disabled, no Jaeger URL provided")
 	}
 
+	// Initialize repositories
+	contractRepo := sqlite.NewContractRepository(db)
+	documentRepo := sqlite.NewExtractedDocumentRepository(db)
+	knowledgeRepo := sqlite.NewKnowledgeEntryRepository(db)
+	jobRepo := sqlite.NewJobRepository(db)
+	stageRepo := sqlite.NewAnalysisStageRepository(db)
+	usageRepo := sqlite.NewLLMUsageRepository(db)
+
 		// Initialize services
-	llmService := llm.NewLLMService(logger)
-	llmclient.AddOpenRouterClientToService(llmService, cfg)
+	// Clients are added through the router, which picks the providers for
+	// each LLM task and fails over between them. The meter below it records
+	// the tokens and cost of every call the providers answer.
+	usageService := usage.NewUsageService(usageRepo, contractRepo, cfg.LLM.Usage, llmMetrics, logger)
+	llmService := llm.NewRouter(usage.NewMeter(llm.NewLLMService(logger), usageService, logger), cfg.LLM.Routing, logger)
+	if err := llmclient.AddOpenRouterClientToService(llmService, cfg); err != nil {
+		logger.Fatal("failed to create OpenRouter client", zap.Error(err))
+	}
+	if err := llmclient.AddAnthropicClientToService(llmService, cfg); err != nil {
+		logger.Fatal("failed to create Anthropic client", zap.Error(err))
+	}
 
 	resilientClient := external.NewHTTPClient(
 		cfg.LLM.OpenRouter.BaseURL,
@@ -103,20 +143,53 @@ func NewContainer(cfg *configs.Config) *Container {
 	ocrValidator := ocr.NewValidator()
 	baseOCRService := ocr.NewOCRService(resilientClient, cfg.OCR.APIKey, cfg.OCR.FallbackModels, ocrValidator, ocrMetrics)
 	ocrService := ocr.NewCachedOCRService(baseOCRService, redisClient, logger, 1*time.Hour) // Cache for 1 hour
+	extractionService := extraction.NewExtractionService(ocrService, extraction.NewPdftoppmRasterizer(cfg.OCR.RasterizerPath), logger)
 
 	// Initialize storage
-	fileStorage, err := storage.NewLocalStorage("./uploads")
+	fileStorage, err := storage.NewFileStorage(cfg.Storage)
 	if err != nil {
 		logger.Fatal("failed to create file storage", zap.Error(err))
 	}
 
-	// Initialize repositories
-	contractRepo := sqlite.NewContractRepository(db)
-	knowledgeRepo := sqlite.NewKnowledgeEntryRepository(db)
+	promptRegistry, err := prompts.New(cfg.LLM.Prompts.Dir, cfg.LLM.Prompts.Versions)
+	if err != nil {
+		logger.Fatal("failed to load prompt templates", zap.Error(err))
+	}
+
+	validationService := validation.NewValidationService(llmService, promptRegistry, logger)
+	documentService := document.NewDocumentService(logger, fileStorage, contractRepo, documentRepo, stageRepo, extractionService, validationService)
+	knowledgeService := knowledge.NewKnowledgeService(llmService, promptRegistry, logger, knowledgeRepo, redisClient)
 
-	validationService := validation.NewValidationService(llmService, logger)
-	documentService := document.NewDocumentService(logger, fileStorage, contractRepo, validationService)
-	knowledgeService := knowledge.NewKnowledgeService(llmService, logger, knowledgeRepo, redisClient)
+	// Initialize the analysis orchestrator and the workers that run it
+	promptEngine := llm.NewPromptEngineWithRegistry(promptRegistry)
+	contractAnalyzer := llm.NewContractAnalyzer(llmService, promptEngine)
+	if cfg.LLM.ChunkTokens > 0 {
+		contractAnalyzer.SetChunkTokens(cfg.LLM.ChunkTokens)
+	}
+	contractAnalyzer.SetEnsemble(cfg.LLM.Ensemble)
+	orchestrator := analysis.NewOrchestrator(
+		contractRepo,
+		documentRepo,
+		stageRepo,
+		fileStorage,
+		extractionService,
+		validationService,
+		analysis.Stages{
+			Classifier:   knowledgeService,
+			Analyzer:     contractAnalyzer,
+			Sequencer:    llm.NewMilestoneSequencer(llmService, promptEngine),
+			RiskAssessor: llm.NewRiskAssessor(llmService, promptEngine),
+			Compliance:   llm.NewComplianceChecker(llmService, promptEngine),
+		},
+		logger,
+	)
+	orchestrator.SetPollInterval(cfg.Jobs.GetPollInterval())
+	jobService := jobs.NewJobService(jobRepo, contractRepo, usageService, map[string]jobs.PrepareFunc{
+		jobs.JobTypeAnalysis: orchestrator.Reset,
+	}, cfg.Jobs.GetMaxAttempts(), logger)
+	workerPool := jobs.NewWorkerPool(jobRepo, map[string]jobs.HandlerFunc{
+		jobs.JobTypeAnalysis: orchestrator.HandleJob,
+	}, cfg.Jobs, logger)
 
 	return &Container{
 		Config:       cfg,
@@ -125,13 +198,24 @@ func NewContainer(cfg *configs.Config) *Container {
 		Tracer:      tp,
 		RedisClient:  redisClient,
 		OCRTMetrics:  ocrMetrics,
+		LLMMetrics:   llmMetrics,
 		ContractRepo:  contractRepo,
+		DocumentRepo:  documentRepo,
 		KnowledgeRepo: knowledgeRepo,
+		JobRepo:       jobRepo,
+		StageRepo:     stageRepo,
+		UsageRepo:     usageRepo,
+		FileStorage:   fileStorage,
 		LLMService:   llmService,
 		OCRService:      ocrService,
+		ExtractionService: extractionService,
 		DocumentService:   documentService,
 		ValidationService: validationService,
 		KnowledgeService:  knowledgeService,
+		JobService:        jobService,
+		UsageService:      usageService,
+		Orchestrator:      orchestrator,
+		WorkerPool:        workerPool,
 	}
 }
 
@@ -144,3 +228,33 @@ func (c *Container) NewHealthHandler() *handlers.HealthHandler {
 func (c *Container) NewDocumentHandler() *handlers.DocumentHandler {
 	return handlers.NewDocumentHandler(c.DocumentService, c.Logger)
 }
+
+// NewFileHandler creates a new handler for signed file downloads
+func (c *Container) NewFileHandler() *handlers.FileHandler {
+	return handlers.NewFileHandler(c.FileStorage, c.Logger)
+}
+
+// NewJobHandler creates a new handler for background analysis jobs
+func (c *Container) NewJobHandler() *handlers.JobHandler {
+	return handlers.NewJobHandler(c.JobService, c.Logger)
+}
+
+// NewUsageHandler creates a new handler for the LLM usage of contracts
+func (c *Container) NewUsageHandler() *handlers.UsageHandler {
+	return handlers.NewUsageHandler(c.UsageService, c.Logger)
+}
+
+// NewAnalysisHandler creates a new handler for the progress of contract analyses
+func (c *Container) NewAnalysisHandler() *handlers.AnalysisHandler {
+	return handlers.NewAnalysisHandler(c.Orchestrator, c.Logger)
+}
+
+// RotateStorageKeys re-encrypts stored contract files under the current
+// encryption key and points contracts at the re-encrypted files.
+func (c *Container) RotateStorageKeys(ctx context.Context) (*storage.RotationReport, error) {
+	encrypted, ok := c.FileStorage.(*storage.EncryptedStorage)
+	if !ok {
+		return nil, fmt.Errorf("storage encryption is not enabled")
+	}
+	return encrypted.RotateKeys(ctx, "", c.ContractRepo.UpdateFilePath)
+}
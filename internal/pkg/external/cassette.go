@@ -0,0 +1,176 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Cassette modes
+const (
+	// CassetteRecord sends requests to the wrapped client and records them
+	// with their responses.
+	CassetteRecord = "record"
+	// CassetteReplay serves recorded responses and fails on any request that
+	// was not recorded, without touching the network.
+	CassetteReplay = "replay"
+)
+
+// ErrCassetteMiss is returned when replaying a request that was not recorded.
+var ErrCassetteMiss = errors.New("request not found in cassette")
+
+// Cassette is a Client decorator that records request/response pairs to a
+// directory, one file per request named by the hash of the normalized
+// request, and replays them. Headers are neither part of the key nor
+// recorded, so API keys never end up in a cassette.
+type Cassette struct {
+	next Client
+	dir  string
+	mode string
+	mu   sync.Mutex
+}
+
+// cassetteEntry is the file format of a recorded pair. JSON bodies are kept
+// as JSON so that cassettes can be reviewed and diffed; other bodies, such as
+// accumulated streams, are kept as text.
+type cassetteEntry struct {
+	Request struct {
+		Method    string          `json:"method"`
+		URL       string          `json:"url"`
+		Streaming bool            `json:"streaming,omitempty"`
+		Body      json.RawMessage `json:"body,omitempty"`
+		Text      string          `json:"text,omitempty"`
+	} `json:"request"`
+	Response struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body,omitempty"`
+		Text       string          `json:"text,omitempty"`
+	} `json:"response"`
+}
+
+// NewCassette wraps next in a cassette stored in dir. next may be nil in
+// replay mode.
+func NewCassette(next Client, dir, mode string) (*Cassette, error) {
+	switch mode {
+	case CassetteRecord:
+		if next == nil {
+			return nil, errors.New("cassette: recording needs a client")
+		}
+	case CassetteReplay:
+	default:
+		return nil, fmt.Errorf("cassette: unknown mode %q", mode)
+	}
+	return &Cassette{next: next, dir: dir, mode: mode}, nil
+}
+
+// ExecuteRequest replays or records req.
+func (c *Cassette) ExecuteRequest(ctx context.Context, req *Request) (*Response, error) {
+	key, err := RequestKey(req)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(c.dir, key+".json")
+
+	if c.mode == CassetteReplay {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s %s (%s)", ErrCassetteMiss, req.Method, req.URL, key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		var entry cassetteEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+		}
+		body := []byte(entry.Response.Text)
+		if entry.Response.Body != nil {
+			body = entry.Response.Body
+		}
+		if req.OnEvent != nil && entry.Response.StatusCode < 400 {
+			// Recorded events are replayed one line at a time
+			for _, event := range bytes.Split(bytes.TrimSuffix(body, []byte("\n")), []byte("\n")) {
+				if err := req.OnEvent(event); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return &Response{StatusCode: entry.Response.StatusCode, Body: body}, nil
+	}
+
+	resp, err := c.next.ExecuteRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var entry cassetteEntry
+	entry.Request.Method = req.Method
+	entry.Request.URL = req.URL
+	entry.Request.Streaming = req.IsStreaming
+	entry.Request.Body, entry.Request.Text = splitBody(req.Body)
+	entry.Response.StatusCode = resp.StatusCode
+	if req.OnEvent != nil {
+		// One event per line, which indenting a lone JSON event would break
+		entry.Response.Text = string(resp.Body)
+	} else {
+		entry.Response.Body, entry.Response.Text = splitBody(resp.Body)
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return resp, nil
+}
+
+// RequestKey returns the cassette key of a request: a hash of its method, URL,
+// streaming flags and body, with JSON bodies in canonical form so that key
+// order and formatting do not matter.
+func RequestKey(req *Request) (string, error) {
+	body := req.Body
+	if raw, _ := splitBody(req.Body); raw != nil {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return "", err
+		}
+		canonical, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		body = canonical
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s %t\n", req.Method, req.URL, req.IsStreaming)
+	if req.OnEvent != nil {
+		// The response body holds events rather than accumulated content
+		h.Write([]byte("events\n"))
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:20], nil
+}
+
+// splitBody returns body as JSON when it is a JSON object or array, and as
+// text otherwise.
+func splitBody(body []byte) (json.RawMessage, string) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return json.RawMessage(trimmed), ""
+	}
+	return nil, string(body)
+}
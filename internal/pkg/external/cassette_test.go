@@ -0,0 +1,128 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	req := &Request{
+		Method:  "POST",
+		URL:     "/v1/messages",
+		Headers: map[string]string{"x-api-key": "secret-key"},
+		Body:    []byte(`{"model":"m","messages":[{"role":"user","content":"Hi"}]}`),
+	}
+	next := new(MockClient)
+	next.On("ExecuteRequest", mock.Anything, req).Return(&Response{StatusCode: 200, Body: []byte(`{"id":"msg_1"}`)}, nil).Once()
+
+	recorder, err := NewCassette(next, dir, CassetteRecord)
+	require.NoError(t, err)
+	resp, err := recorder.ExecuteRequest(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"msg_1"}`, string(resp.Body))
+	next.AssertExpectations(t)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	recorded, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(recorded), "secret-key")
+
+	// Key order, formatting and headers do not change the key
+	replayer, err := NewCassette(nil, dir, CassetteReplay)
+	require.NoError(t, err)
+	resp, err = replayer.ExecuteRequest(context.Background(), &Request{
+		Method: "POST",
+		URL:    "/v1/messages",
+		Body:   []byte("{\n  \"messages\": [{\"content\": \"Hi\", \"role\": \"user\"}],\n  \"model\": \"m\"\n}"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.JSONEq(t, `{"id":"msg_1"}`, string(resp.Body))
+
+	_, err = replayer.ExecuteRequest(context.Background(), &Request{
+		Method: "POST",
+		URL:    "/v1/messages",
+		Body:   []byte(`{"model":"m","messages":[{"role":"user","content":"Hello"}]}`),
+	})
+	assert.ErrorIs(t, err, ErrCassetteMiss)
+}
+
+func TestCassette_TextBodies(t *testing.T) {
+	dir := t.TempDir()
+	req := &Request{Method: "POST", URL: "/chat/completions", Body: []byte(`{"stream":true}`), IsStreaming: true}
+	next := new(MockClient)
+	next.On("ExecuteRequest", mock.Anything, req).Return(&Response{StatusCode: 200, Body: []byte("accumulated text")}, nil)
+
+	recorder, err := NewCassette(next, dir, CassetteRecord)
+	require.NoError(t, err)
+	_, err = recorder.ExecuteRequest(context.Background(), req)
+	require.NoError(t, err)
+
+	replayer, err := NewCassette(nil, dir, CassetteReplay)
+	require.NoError(t, err)
+	resp, err := replayer.ExecuteRequest(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, "accumulated text", string(resp.Body))
+
+	// The streaming flag is part of the key
+	_, err = replayer.ExecuteRequest(context.Background(), &Request{Method: "POST", URL: "/chat/completions", Body: []byte(`{"stream":true}`)})
+	assert.ErrorIs(t, err, ErrCassetteMiss)
+
+	_, err = NewCassette(nil, dir, CassetteRecord)
+	assert.Error(t, err)
+	_, err = NewCassette(next, dir, "rewind")
+	assert.Error(t, err)
+}
+
+func TestCassette_StreamEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: delta\ndata: {\"text\":\"Hel\"}\n\n: keep-alive\n\ndata: {\"text\":\"lo\"}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	var events []string
+	req := &Request{
+		Method:      "POST",
+		URL:         "/stream",
+		Body:        []byte(`{"stream":true}`),
+		IsStreaming: true,
+		OnEvent: func(data []byte) error {
+			events = append(events, string(data))
+			return nil
+		},
+	}
+	dir := t.TempDir()
+	recorder, err := NewCassette(NewHTTPClient(server.URL, "stream", RetryConfig{}, 5*time.Second), dir, CassetteRecord)
+	require.NoError(t, err)
+	resp, err := recorder.ExecuteRequest(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"text":"Hel"}`, `{"text":"lo"}`}, events)
+	assert.Equal(t, "{\"text\":\"Hel\"}\n{\"text\":\"lo\"}\n", string(resp.Body))
+
+	// Replaying delivers the same events
+	events = nil
+	replayer, err := NewCassette(nil, dir, CassetteReplay)
+	require.NoError(t, err)
+	_, err = replayer.ExecuteRequest(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"text":"Hel"}`, `{"text":"lo"}`}, events)
+
+	// An accumulated stream is recorded under a different key
+	req.OnEvent = nil
+	_, err = replayer.ExecuteRequest(context.Background(), req)
+	assert.ErrorIs(t, err, ErrCassetteMiss)
+}
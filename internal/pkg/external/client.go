@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -37,6 +38,11 @@ type Request struct {
 	Headers     map[string]string
 	Body        []byte
 	IsStreaming bool
+	// OnEvent, when set on a streaming request, is called with the data of
+	// each server-sent event as it arrives, and the response body holds the
+	// data of every event, one per line, instead of the accumulated content.
+	// An error from OnEvent aborts the request.
+	OnEvent func(data []byte) error
 }
 
 // Response represents a service response
@@ -145,7 +151,12 @@ func (c *HTTPClient) doActualRequest(ctx context.Context, req *Request) (*Respon
 	defer resp.Body.Close()
 
 	var body []byte
-	if req.IsStreaming {
+	if req.IsStreaming && req.OnEvent != nil && resp.StatusCode < 400 {
+		body, err = readEvents(resp.Body, req.OnEvent)
+		if err != nil {
+			return nil, err
+		}
+	} else if req.IsStreaming {
 		// Handle streaming response
 		scanner := bufio.NewScanner(resp.Body)
 		var accumulatedContent strings.Builder
@@ -189,6 +200,42 @@ func (c *HTTPClient) doActualRequest(ctx context.Context, req *Request) (*Respon
 	}, nil
 }
 
+// readEvents passes the data of each server-sent event in r to onEvent as it
+// arrives and returns the data of all events, one per line. Events are
+// assumed to carry single-line data, as the LLM providers' JSON events do.
+func readEvents(r io.Reader, onEvent func(data []byte) error) ([]byte, error) {
+	scanner := bufio.NewScanner(r)
+	// Events can be larger than the default 64KB token limit
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var events bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(line[len("data:"):])
+		if data == "[DONE]" {
+			break
+		}
+		if data == "" {
+			continue
+		}
+		events.WriteString(data)
+		events.WriteByte('\n')
+		if err := onEvent([]byte(data)); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if events.Len() > 0 {
+			// Retrying would deliver the events received so far again
+			return nil, backoff.Permanent(fmt.Errorf("stream interrupted: %w", err))
+		}
+		return nil, fmt.Errorf("error reading streaming response: %w", err)
+	}
+	return events.Bytes(), nil
+}
+
 // isTransientError determines if an error is transient
 func isTransientError(err error) bool {
     // Treat common network/timeouts as transient
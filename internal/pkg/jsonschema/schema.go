@@ -0,0 +1,162 @@
+// Package jsonschema generates JSON Schemas from Go types and checks JSON
+// documents against them. It covers the subset of the specification needed to
+// describe the DTOs that LLM responses are decoded into.
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// JSON types
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeNull    = "null"
+)
+
+// Schema is a JSON Schema. A schema with no types accepts any value.
+type Schema struct {
+	Types                []string           `json:"-"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// MarshalJSON writes the type keyword as a string when there is one type and
+// as an array otherwise.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type plain Schema
+	out := struct {
+		Type interface{} `json:"type,omitempty"`
+		*plain
+	}{plain: (*plain)(s)}
+	switch len(s.Types) {
+	case 0:
+	case 1:
+		out.Type = s.Types[0]
+	default:
+		out.Type = s.Types
+	}
+	return json.Marshal(out)
+}
+
+// Is reports whether the schema accepts values of JSON type t.
+func (s *Schema) Is(t string) bool {
+	for _, typ := range s.Types {
+		if typ == t {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+	timeType    = reflect.TypeOf(time.Time{})
+	rawType     = reflect.TypeOf(json.RawMessage{})
+
+	cache sync.Map // reflect.Type -> *Schema
+)
+
+// For returns the schema of the JSON encoding of v's type, or of the type it
+// points to when v is a pointer. Struct fields are named by their json tags
+// and are required unless tagged omitempty. Slices and nested pointers also
+// accept null, which decodes to their zero value. Schemas are cached per type
+// and must not be modified.
+func For(v interface{}) *Schema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if cached, ok := cache.Load(t); ok {
+		return cached.(*Schema)
+	}
+	s := forType(t)
+	cache.Store(t, s)
+	return s
+}
+
+func forType(t reflect.Type) *Schema {
+	switch t {
+	case decimalType:
+		return &Schema{Types: []string{TypeNumber}}
+	case timeType:
+		return &Schema{Types: []string{TypeString}, Format: "date-time"}
+	case rawType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := *forType(t.Elem())
+		if len(s.Types) > 0 {
+			s.Types = append(append([]string{}, s.Types...), TypeNull)
+		}
+		return &s
+	case reflect.String:
+		return &Schema{Types: []string{TypeString}}
+	case reflect.Bool:
+		return &Schema{Types: []string{TypeBoolean}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Types: []string{TypeInteger}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Types: []string{TypeNumber}}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Types: []string{TypeArray, TypeNull}, Items: forType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Types: []string{TypeObject, TypeNull}, AdditionalProperties: forType(t.Elem())}
+	case reflect.Struct:
+		s := &Schema{Types: []string{TypeObject}, Properties: make(map[string]*Schema)}
+		addFields(s, t)
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+// addFields adds the fields of struct type t to s, flattening embedded
+// structs the way encoding/json does. Fields tagged jsonschema:"-" are left
+// out.
+func addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || field.Tag.Get("jsonschema") == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(s, ft)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = forType(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"contract-analysis-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFor_ContractAnalysis(t *testing.T) {
+	schema := For(&models.ContractAnalysis{})
+
+	assert.Equal(t, []string{TypeObject}, schema.Types)
+	assert.ElementsMatch(t, []string{"buyer", "seller", "total_value", "currency", "milestones", "risk_factors"}, schema.Required)
+	assert.Equal(t, []string{TypeNumber}, schema.Properties["total_value"].Types)
+
+	milestones := schema.Properties["milestones"]
+	assert.Equal(t, []string{TypeArray, TypeNull}, milestones.Types)
+	assert.Equal(t, []string{TypeNumber}, milestones.Items.Properties["amount"].Types)
+
+	// Optional fields are not required
+	assert.NotContains(t, For(models.ValidationResult{}).Required, "reason")
+
+	raw, err := json.Marshal(schema.Properties["milestones"])
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `"type":["array","null"]`)
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := For([]models.SequencedMilestone{})
+
+	assert.NoError(t, schema.Validate([]byte(`[{"id":"1","description":"Deposit","sequence_order":1,
+		"category":"payment","dependencies":null,"percentage":30}]`)))
+
+	err := schema.Validate([]byte(`[{"id":"1","description":"Deposit","sequence_order":1.5,"category":"payment","percentage":"thirty"}]`))
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, ValidationErrors{
+		{Path: "[0]", Message: `missing required property "dependencies"`},
+		{Path: "[0].percentage", Message: "expected number, got string"},
+		{Path: "[0].sequence_order", Message: "expected integer, got number"},
+	}, errs)
+
+	assert.ErrorContains(t, schema.Validate([]byte(`[{"id":`)), "invalid JSON")
+}
+
+func TestSchema_Coerce(t *testing.T) {
+	schema := For(models.ContractAnalysis{})
+
+	coerced, err := schema.Coerce([]byte(`{"buyer":"Acme","seller":null,"total_value":"$1,250,000.50","currency":"USD",
+		"milestones":[{"description":"Deposit","amount":"USD 375,000","percentage":"30%"}],"risk_factors":null}`))
+	require.NoError(t, err)
+	require.NoError(t, schema.Validate(coerced))
+
+	var analysis models.ContractAnalysis
+	require.NoError(t, json.Unmarshal(coerced, &analysis))
+	assert.Equal(t, "1250000.5", analysis.TotalValue.String())
+	assert.Equal(t, "375000", analysis.Milestones[0].Amount.String())
+	assert.Equal(t, 30.0, analysis.Milestones[0].Percentage)
+
+	// Values without a clear reading are left for Validate to report
+	coerced, err = For(models.SequencedMilestone{}).Coerce([]byte(`{"id":7,"sequence_order":"2.0","percentage":"about a third"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"7","sequence_order":2,"percentage":"about a third"}`, string(coerced))
+}
@@ -0,0 +1,207 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError is a single place where a document departs from its schema.
+type ValidationError struct {
+	// Path locates the value, e.g. "milestones[0].amount"; it is empty for
+	// the document itself.
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors lists every place a document departs from its schema.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks a JSON document against s. It returns ValidationErrors when
+// the document is valid JSON that does not match.
+func (s *Schema) Validate(data []byte) error {
+	v, err := decode(data)
+	if err != nil {
+		return err
+	}
+	var errs ValidationErrors
+	s.validate("", v, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, v interface{}, errs *ValidationErrors) {
+	if len(s.Types) == 0 {
+		return
+	}
+	typ := typeOf(v)
+	if !s.Is(typ) && !(typ == TypeInteger && s.Is(TypeNumber)) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(s.Types, " or "), typ)})
+		return
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)})
+			}
+		}
+		for _, name := range sortedKeys(value) {
+			if prop := s.property(name); prop != nil {
+				prop.validate(join(path, name), value[name], errs)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	}
+}
+
+// Coerce rewrites values of a JSON document that have the wrong JSON type but
+// an unambiguous reading as the right one: numbers written as strings (with
+// currency symbols, thousands separators or a percent sign), whole numbers
+// written as decimals, booleans written as strings, numbers where a string is
+// expected, and nulls where a scalar is expected, which become its zero
+// value. Values it cannot read are left for Validate to report.
+func (s *Schema) Coerce(data []byte) ([]byte, error) {
+	v, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s.coerce(v))
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}\s*|\s*[A-Z]{3}$`)
+
+func (s *Schema) coerce(v interface{}) interface{} {
+	if len(s.Types) == 0 {
+		return v
+	}
+	switch value := v.(type) {
+	case nil:
+		switch {
+		case s.Is(TypeNull):
+		case s.Is(TypeString):
+			return ""
+		case s.Is(TypeNumber), s.Is(TypeInteger):
+			return json.Number("0")
+		case s.Is(TypeBoolean):
+			return false
+		}
+	case string:
+		switch {
+		case s.Is(TypeString):
+		case s.Is(TypeNumber) || s.Is(TypeInteger):
+			cleaned := currencyCode.ReplaceAllString(strings.TrimSpace(value), "")
+			cleaned = strings.NewReplacer(",", "", " ", "", "$", "", "€", "", "£", "", "¥", "", "%", "").Replace(cleaned)
+			if _, err := strconv.ParseFloat(cleaned, 64); err == nil {
+				return s.coerce(json.Number(cleaned))
+			}
+		case s.Is(TypeBoolean):
+			if b, err := strconv.ParseBool(strings.ToLower(strings.TrimSpace(value))); err == nil {
+				return b
+			}
+		}
+	case json.Number:
+		switch {
+		case s.Is(TypeNumber):
+		case s.Is(TypeInteger):
+			if f, err := value.Float64(); err == nil && f == float64(int64(f)) {
+				return json.Number(strconv.FormatInt(int64(f), 10))
+			}
+		case s.Is(TypeString):
+			return value.String()
+		}
+	case map[string]interface{}:
+		for name, item := range value {
+			if prop := s.property(name); prop != nil {
+				value[name] = prop.coerce(item)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				value[i] = s.Items.coerce(item)
+			}
+		}
+	}
+	return v
+}
+
+func (s *Schema) property(name string) *Schema {
+	if prop, ok := s.Properties[name]; ok {
+		return prop
+	}
+	return s.AdditionalProperties
+}
+
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return v, nil
+}
+
+func typeOf(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case json.Number:
+		if strings.ContainsAny(value.String(), ".eE") {
+			return TypeNumber
+		}
+		return TypeInteger
+	case string:
+		return TypeString
+	case []interface{}:
+		return TypeArray
+	default:
+		return TypeObject
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
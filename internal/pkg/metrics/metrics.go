@@ -39,3 +39,30 @@ func NewOCRMetrics() *OCRMetrics {
 		),
 	}
 }
+
+// LLMMetrics holds the Prometheus metrics for LLM token usage and cost. The
+// tenant label is "other" for tenants without a budget of their own.
+type LLMMetrics struct {
+	TokensTotal *prometheus.CounterVec
+	CostTotal   *prometheus.CounterVec
+}
+
+// NewLLMMetrics creates and registers the LLM usage metrics.
+func NewLLMMetrics() *LLMMetrics {
+	return &LLMMetrics{
+		TokensTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "llm_tokens_total",
+				Help: "Total number of LLM tokens used, by kind (prompt or completion).",
+			},
+			[]string{"provider", "model", "stage", "tenant", "kind"},
+		),
+		CostTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "llm_cost_total",
+				Help: "Total cost of LLM calls in the configured currency.",
+			},
+			[]string{"provider", "model", "stage", "tenant"},
+		),
+	}
+}
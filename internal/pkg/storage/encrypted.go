@@ -0,0 +1,507 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"contract-analysis-service/configs"
+)
+
+// Encrypted files start with a header that identifies the key-encryption key
+// and carries the wrapped per-file data key:
+//
+//	magic "CAE1" | key ID length (1 byte) | key ID | wrapped key length (2 bytes) | wrapped key
+//
+// The content follows as AES-GCM sealed segments of encryptedChunkSize bytes.
+// Segment nonces are a counter plus a final-segment flag, so reordered or
+// truncated files fail to decrypt, and every segment authenticates the header.
+const (
+	encryptedMagic     = "CAE1"
+	encryptedChunkSize = 64 * 1024
+	dataKeySize        = 32
+)
+
+var (
+	// ErrUnknownKey is returned when a file was encrypted with a key the key
+	// provider does not have.
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrNotEncrypted is returned when a file does not carry an encryption header.
+	ErrNotEncrypted = errors.New("file is not encrypted")
+)
+
+// KeyProvider supplies the key-encryption keys used to wrap per-file data keys.
+type KeyProvider interface {
+	// CurrentKey returns the key new files are encrypted with.
+	CurrentKey() (keyID string, key []byte, err error)
+	// Key returns the key with the given ID, for decrypting existing files.
+	Key(keyID string) ([]byte, error)
+}
+
+// StaticKeyProvider is a KeyProvider backed by a fixed set of keys, typically
+// loaded from configuration. Old keys are kept so existing files stay readable
+// until they have been rotated.
+type StaticKeyProvider struct {
+	currentKeyID string
+	keys         map[string][]byte
+}
+
+// NewStaticKeyProvider creates a StaticKeyProvider. Every key must be 32 bytes
+// (AES-256) and currentKeyID must be one of them.
+func NewStaticKeyProvider(currentKeyID string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	if len(currentKeyID) == 0 || len(currentKeyID) > 255 {
+		return nil, fmt.Errorf("invalid current encryption key ID %q", currentKeyID)
+	}
+	if _, ok := keys[currentKeyID]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, currentKeyID)
+	}
+	for id, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %q must be 32 bytes, got %d", id, len(key))
+		}
+	}
+	return &StaticKeyProvider{currentKeyID: currentKeyID, keys: keys}, nil
+}
+
+// NewKeyProviderFromConfig creates a StaticKeyProvider from base64-encoded keys.
+func NewKeyProviderFromConfig(cfg configs.EncryptionConfig) (*StaticKeyProvider, error) {
+	keys := make(map[string][]byte, len(cfg.Keys))
+	for id, encoded := range cfg.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q is not valid base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return NewStaticKeyProvider(cfg.CurrentKeyID, keys)
+}
+
+// CurrentKey returns the key new files are encrypted with.
+func (p *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	return p.currentKeyID, p.keys[p.currentKeyID], nil
+}
+
+// Key returns the key with the given ID.
+func (p *StaticKeyProvider) Key(keyID string) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	return key, nil
+}
+
+// EncryptedStorage is a FileStorage decorator that encrypts files at rest with
+// AES-GCM envelope encryption: every file gets a random data key, which is
+// itself encrypted with a key-encryption key from the KeyProvider. Callers see
+// plaintext only.
+type EncryptedStorage struct {
+	inner FileStorage
+	keys  KeyProvider
+}
+
+// NewEncryptedStorage wraps a FileStorage with encryption at rest.
+func NewEncryptedStorage(inner FileStorage, keys KeyProvider) *EncryptedStorage {
+	return &EncryptedStorage{inner: inner, keys: keys}
+}
+
+// Save encrypts the file under the current key and stores it.
+func (s *EncryptedStorage) Save(file io.Reader, fileName string) (string, error) {
+	keyID, kek, err := s.keys.CurrentKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to get encryption key: %w", err)
+	}
+	enc, err := newEncryptingReader(file, keyID, kek)
+	if err != nil {
+		return "", err
+	}
+	return s.inner.Save(enc, fileName)
+}
+
+// Open returns a reader over the decrypted file. Authentication failures are
+// reported by Read, so callers must check its error before trusting the data.
+func (s *EncryptedStorage) Open(filePath string) (io.ReadCloser, error) {
+	rc, err := s.inner.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := newDecryptingReader(rc, s.keys)
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("failed to decrypt file: %w", err)
+	}
+	return dec, nil
+}
+
+// Stat returns information about the decrypted file. The size is the
+// plaintext size; the hash of the ciphertext is not reported.
+func (s *EncryptedStorage) Stat(filePath string) (*FileInfo, error) {
+	info, err := s.inner.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, err := s.KeyID(filePath)
+	if err != nil {
+		return nil, err
+	}
+	plain := *info
+	plain.Size = plaintextSize(info.Size, headerSize(keyID))
+	plain.Hash = ""
+	return &plain, nil
+}
+
+// List returns the stored files whose path starts with prefix. Sizes are
+// those of the encrypted objects.
+func (s *EncryptedStorage) List(prefix string) ([]*FileInfo, error) {
+	return s.inner.List(prefix)
+}
+
+// SignedURL returns a signed link only when the link is served by this
+// service, where downloads are decrypted; links served directly by the
+// backend would hand out ciphertext.
+func (s *EncryptedStorage) SignedURL(filePath string, expiry time.Duration) (string, error) {
+	if _, ok := s.inner.(SignedURLVerifier); !ok {
+		return "", ErrSignedURLsUnsupported
+	}
+	return s.inner.SignedURL(filePath, expiry)
+}
+
+// VerifySignedURL delegates to the wrapped storage.
+func (s *EncryptedStorage) VerifySignedURL(name string, expires int64, signature string) (string, error) {
+	verifier, ok := s.inner.(SignedURLVerifier)
+	if !ok {
+		return "", ErrSignedURLsUnsupported
+	}
+	return verifier.VerifySignedURL(name, expires, signature)
+}
+
+// Delete removes a file.
+func (s *EncryptedStorage) Delete(filePath string) error {
+	return s.inner.Delete(filePath)
+}
+
+// KeyID returns the ID of the key a stored file is encrypted with.
+func (s *EncryptedStorage) KeyID(filePath string) (string, error) {
+	rc, err := s.inner.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	keyID, _, _, err := readHeader(bufio.NewReader(rc))
+	return keyID, err
+}
+
+// RotationReport summarises a key rotation run.
+type RotationReport struct {
+	Rotated int      `json:"rotated"`
+	Skipped int      `json:"skipped"`
+	Failed  []string `json:"failed,omitempty"`
+}
+
+// RotateKeys re-encrypts every file under prefix that is not encrypted with
+// the current key; plaintext files written before encryption was enabled are
+// encrypted as well. Because the wrapped storage may be content-addressed, a
+// re-encrypted file can land at a new path: relocate is called with the old
+// and new path before the old file is deleted, so references can be updated.
+// Files that fail are listed in the report and left untouched.
+func (s *EncryptedStorage) RotateKeys(ctx context.Context, prefix string, relocate func(oldPath, newPath string) error) (*RotationReport, error) {
+	currentKeyID, _, err := s.keys.CurrentKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption key: %w", err)
+	}
+	files, err := s.inner.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RotationReport{}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		keyID, err := s.KeyID(f.Path)
+		encrypted := err == nil
+		if err != nil && !errors.Is(err, ErrNotEncrypted) {
+			report.Failed = append(report.Failed, f.Path)
+			continue
+		}
+		if encrypted && keyID == currentKeyID {
+			report.Skipped++
+			continue
+		}
+
+		if err := s.rotate(f, encrypted, relocate); err != nil {
+			report.Failed = append(report.Failed, f.Path)
+			continue
+		}
+		report.Rotated++
+	}
+	return report, nil
+}
+
+func (s *EncryptedStorage) rotate(f *FileInfo, encrypted bool, relocate func(oldPath, newPath string) error) error {
+	// Decrypt fully before writing so a corrupt file is never re-encrypted.
+	open := s.inner.Open
+	if encrypted {
+		open = s.Open
+	}
+	rc, err := open(f.Path)
+	if err != nil {
+		return err
+	}
+	plaintext, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+
+	newPath, err := s.Save(bytes.NewReader(plaintext), f.Name)
+	if err != nil {
+		return err
+	}
+	if newPath == f.Path {
+		return nil
+	}
+	if err := relocate(f.Path, newPath); err != nil {
+		s.inner.Delete(newPath)
+		return err
+	}
+	return s.inner.Delete(f.Path)
+}
+
+// encryptingReader produces the header followed by the sealed segments of the
+// source, one segment at a time.
+type encryptingReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint64
+	done    bool
+}
+
+func newEncryptingReader(src io.Reader, keyID string, kek []byte) (*encryptingReader, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	kekAEAD, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, kekAEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	wrapped := kekAEAD.Seal(nonce, nonce, dataKey, []byte(keyID))
+
+	header := make([]byte, 0, headerSize(keyID))
+	header = append(header, encryptedMagic...)
+	header = append(header, byte(len(keyID)))
+	header = append(header, keyID...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptingReader{
+		src:    bufio.NewReaderSize(src, encryptedChunkSize+1),
+		aead:   aead,
+		header: header,
+		buf:    header,
+	}, nil
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.sealNext(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *encryptingReader) sealNext() error {
+	chunk := make([]byte, encryptedChunkSize)
+	n, err := io.ReadFull(r.src, chunk)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	// The segment is final if nothing follows it.
+	final := err != nil
+	if !final {
+		if _, err := r.src.Peek(1); err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	r.buf = r.aead.Seal(nil, segmentNonce(r.counter, final), chunk[:n], r.header)
+	r.counter++
+	r.done = final
+	return nil
+}
+
+// decryptingReader opens sealed segments one at a time.
+type decryptingReader struct {
+	src     *bufio.Reader
+	closer  io.Closer
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint64
+	done    bool
+}
+
+func newDecryptingReader(rc io.ReadCloser, keys KeyProvider) (*decryptingReader, error) {
+	src := bufio.NewReaderSize(rc, encryptedChunkSize+32)
+	keyID, wrapped, header, err := readHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	kekAEAD, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < kekAEAD.NonceSize() {
+		return nil, fmt.Errorf("malformed encryption header")
+	}
+	dataKey, err := kekAEAD.Open(nil, wrapped[:kekAEAD.NonceSize()], wrapped[kekAEAD.NonceSize():], []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptingReader{src: src, closer: rc, aead: aead, header: header}, nil
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.openNext(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *decryptingReader) openNext() error {
+	sealed := make([]byte, encryptedChunkSize+r.aead.Overhead())
+	n, err := io.ReadFull(r.src, sealed)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	final := err != nil
+	if !final {
+		if _, err := r.src.Peek(1); err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	plain, err := r.aead.Open(nil, segmentNonce(r.counter, final), sealed[:n], r.header)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt file: %w", err)
+	}
+	r.buf = plain
+	r.counter++
+	r.done = final
+	return nil
+}
+
+func (r *decryptingReader) Close() error {
+	return r.closer.Close()
+}
+
+// readHeader parses the encryption header and returns the key ID, the wrapped
+// data key and the raw header bytes.
+func readHeader(r *bufio.Reader) (string, []byte, []byte, error) {
+	magic := make([]byte, len(encryptedMagic)+1)
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic[:len(encryptedMagic)]) != encryptedMagic {
+		return "", nil, nil, ErrNotEncrypted
+	}
+	keyID := make([]byte, int(magic[len(encryptedMagic)]))
+	if _, err := io.ReadFull(r, keyID); err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encryption header: %w", err)
+	}
+	var wrappedLen uint16
+	if err := binary.Read(r, binary.BigEndian, &wrappedLen); err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encryption header: %w", err)
+	}
+	wrapped := make([]byte, wrappedLen)
+	if _, err := io.ReadFull(r, wrapped); err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encryption header: %w", err)
+	}
+
+	header := append(magic, keyID...)
+	header = binary.BigEndian.AppendUint16(header, wrappedLen)
+	header = append(header, wrapped...)
+	return string(keyID), wrapped, header, nil
+}
+
+// headerSize is the length of the header for a key ID: magic, ID length, ID,
+// wrapped key length, and the wrapped key (nonce + data key + tag).
+func headerSize(keyID string) int {
+	return len(encryptedMagic) + 1 + len(keyID) + 2 + 12 + dataKeySize + 16
+}
+
+// plaintextSize derives the plaintext size from the size of an encrypted file.
+func plaintextSize(encryptedSize int64, headerLen int) int64 {
+	body := encryptedSize - int64(headerLen)
+	const sealedChunk = encryptedChunkSize + 16
+	segments := (body + sealedChunk - 1) / sealedChunk
+	if segments == 0 {
+		return 0
+	}
+	return body - segments*16
+}
+
+// segmentNonce builds the nonce of a segment from its index and whether it is
+// the last one.
+func segmentNonce(counter uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
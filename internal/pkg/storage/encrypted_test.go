@@ -0,0 +1,175 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	return key
+}
+
+func newTestEncryptedStorage(t *testing.T, keys map[string][]byte, current string) (*storage.EncryptedStorage, *storage.LocalStorage) {
+	t.Helper()
+	local, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	provider, err := storage.NewStaticKeyProvider(current, keys)
+	require.NoError(t, err)
+	return storage.NewEncryptedStorage(local, provider), local
+}
+
+func readAll(t *testing.T, s storage.FileStorage, path string) ([]byte, error) {
+	t.Helper()
+	rc, err := s.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func TestEncryptedStorage_RoundTrip(t *testing.T) {
+	s, local := newTestEncryptedStorage(t, map[string][]byte{"k1": newTestKey(t)}, "k1")
+
+	sizes := map[string]int{"empty": 0, "small": 19, "exact chunk": 64 * 1024, "multi chunk": 3*64*1024 + 17}
+	for name, size := range sizes {
+		t.Run(name, func(t *testing.T) {
+			plaintext := make([]byte, size)
+			_, err := rand.Read(plaintext)
+			require.NoError(t, err)
+
+			path, err := s.Save(bytes.NewReader(plaintext), "contract.pdf")
+			require.NoError(t, err)
+
+			raw, err := readAll(t, local, path)
+			require.NoError(t, err)
+			if size > 0 {
+				assert.False(t, bytes.Contains(raw, plaintext), "file must not be stored in plaintext")
+			}
+
+			decrypted, err := readAll(t, s, path)
+			require.NoError(t, err)
+			assert.Equal(t, plaintext, decrypted)
+
+			info, err := s.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, int64(size), info.Size)
+			assert.Equal(t, "application/pdf", info.ContentType)
+
+			keyID, err := s.KeyID(path)
+			require.NoError(t, err)
+			assert.Equal(t, "k1", keyID)
+		})
+	}
+}
+
+func TestEncryptedStorage_DetectsTampering(t *testing.T) {
+	s, local := newTestEncryptedStorage(t, map[string][]byte{"k1": newTestKey(t)}, "k1")
+
+	path, err := s.Save(strings.NewReader("Payment of 30 percent on delivery."), "contract.txt")
+	require.NoError(t, err)
+
+	raw, err := readAll(t, local, path)
+	require.NoError(t, err)
+	raw[len(raw)-20] ^= 0xff
+	require.NoError(t, os.WriteFile(path, raw, 0o600))
+
+	_, err = readAll(t, s, path)
+	assert.Error(t, err)
+
+	// Truncation is detected as well
+	require.NoError(t, os.WriteFile(path, raw[:len(raw)-5], 0o600))
+	_, err = readAll(t, s, path)
+	assert.Error(t, err)
+}
+
+func TestEncryptedStorage_UnknownKey(t *testing.T) {
+	s, local := newTestEncryptedStorage(t, map[string][]byte{"k1": newTestKey(t)}, "k1")
+	path, err := s.Save(strings.NewReader("this is a test file"), "contract.txt")
+	require.NoError(t, err)
+
+	provider, err := storage.NewStaticKeyProvider("k2", map[string][]byte{"k2": newTestKey(t)})
+	require.NoError(t, err)
+	other := storage.NewEncryptedStorage(local, provider)
+
+	_, err = other.Open(path)
+	assert.ErrorIs(t, err, storage.ErrUnknownKey)
+}
+
+func TestEncryptedStorage_RotateKeys(t *testing.T) {
+	k1, k2 := newTestKey(t), newTestKey(t)
+	old, local := newTestEncryptedStorage(t, map[string][]byte{"k1": k1}, "k1")
+
+	oldPath, err := old.Save(strings.NewReader("encrypted under k1"), "a.txt")
+	require.NoError(t, err)
+	legacyPath, err := local.Save(strings.NewReader("written before encryption"), "b.txt")
+	require.NoError(t, err)
+
+	provider, err := storage.NewStaticKeyProvider("k2", map[string][]byte{"k1": k1, "k2": k2})
+	require.NoError(t, err)
+	s := storage.NewEncryptedStorage(local, provider)
+	current, err := s.Save(strings.NewReader("already under k2"), "c.txt")
+	require.NoError(t, err)
+
+	relocated := map[string]string{}
+	report, err := s.RotateKeys(context.Background(), "", func(oldPath, newPath string) error {
+		relocated[oldPath] = newPath
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, report.Rotated)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Empty(t, report.Failed)
+	require.Contains(t, relocated, oldPath)
+	require.Contains(t, relocated, legacyPath)
+
+	for path, want := range map[string]string{relocated[oldPath]: "encrypted under k1", relocated[legacyPath]: "written before encryption", current: "already under k2"} {
+		keyID, err := s.KeyID(path)
+		require.NoError(t, err)
+		assert.Equal(t, "k2", keyID)
+		content, err := readAll(t, s, path)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(content))
+	}
+
+	_, err = local.Stat(oldPath)
+	assert.ErrorIs(t, err, storage.ErrFileNotFound)
+	_, err = local.Stat(legacyPath)
+	assert.ErrorIs(t, err, storage.ErrFileNotFound)
+}
+
+func TestEncryptedStorage_SignedURL(t *testing.T) {
+	s, local := newTestEncryptedStorage(t, map[string][]byte{"k1": newTestKey(t)}, "k1")
+	local.EnableSignedURLs("https://contracts.example.com/files", []byte("secret"))
+	path, err := s.Save(strings.NewReader("this is a test file"), "contract.txt")
+	require.NoError(t, err)
+
+	// Local links are served by this service, which decrypts on download
+	_, err = s.SignedURL(path, time.Minute)
+	assert.NoError(t, err)
+
+	s3, err := storage.NewS3Storage(configs.S3Config{Bucket: "contracts"}, nil)
+	require.NoError(t, err)
+	provider, err := storage.NewStaticKeyProvider("k1", map[string][]byte{"k1": newTestKey(t)})
+	require.NoError(t, err)
+
+	// Presigned S3 links would hand out ciphertext
+	_, err = storage.NewEncryptedStorage(s3, provider).SignedURL("uploads/abc.pdf", time.Minute)
+	assert.ErrorIs(t, err, storage.ErrSignedURLsUnsupported)
+}
@@ -2,7 +2,9 @@ package mocks
 
 import (
 	"io"
+	"time"
 
+	"contract-analysis-service/internal/pkg/storage"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -17,6 +19,39 @@ func (m *FileStorage) Save(file io.Reader, fileName string) (string, error) {
 	return args.String(0), args.Error(1)
 }
 
+// Open mocks the Open method.
+func (m *FileStorage) Open(filePath string) (io.ReadCloser, error) {
+	args := m.Called(filePath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
+
+// Stat mocks the Stat method.
+func (m *FileStorage) Stat(filePath string) (*storage.FileInfo, error) {
+	args := m.Called(filePath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.FileInfo), args.Error(1)
+}
+
+// List mocks the List method.
+func (m *FileStorage) List(prefix string) ([]*storage.FileInfo, error) {
+	args := m.Called(prefix)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*storage.FileInfo), args.Error(1)
+}
+
+// SignedURL mocks the SignedURL method.
+func (m *FileStorage) SignedURL(filePath string, expiry time.Duration) (string, error) {
+	args := m.Called(filePath, expiry)
+	return args.String(0), args.Error(1)
+}
+
 // Delete mocks the Delete method.
 func (m *FileStorage) Delete(filePath string) error {
 	args := m.Called(filePath)
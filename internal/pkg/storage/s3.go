@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"contract-analysis-service/configs"
+)
+
+// S3Storage implements FileStorage on top of an S3-compatible object store.
+// Objects are content-addressed like LocalStorage: the key is the SHA-256 of
+// the content plus the original extension, under the configured prefix.
+type S3Storage struct {
+	endpoint     *url.URL
+	bucket       string
+	prefix       string
+	usePathStyle bool
+	signer       *sigV4Signer
+	client       *http.Client
+	now          func() time.Time
+}
+
+// NewS3Storage creates a new S3Storage instance. If client is nil a client with
+// a one minute timeout is used.
+func NewS3Storage(cfg configs.S3Config, client *http.Client) (*S3Storage, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is required")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3.amazonaws.com"
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: time.Minute}
+	}
+
+	return &S3Storage{
+		endpoint:     endpoint,
+		bucket:       cfg.Bucket,
+		prefix:       cfg.Prefix,
+		usePathStyle: cfg.UsePathStyle,
+		signer: &sigV4Signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			region:          cfg.Region,
+			service:         "s3",
+		},
+		client: client,
+		now:    time.Now,
+	}, nil
+}
+
+// Save uploads a file and returns its object key. The content type, SHA-256
+// and original filename are stored as object metadata.
+func (s *S3Storage) Save(file io.Reader, fileName string) (string, error) {
+	// Spool to disk while hashing: the key and the signed payload hash are
+	// both derived from the content, and S3 needs the length up front.
+	tmp, err := os.CreateTemp("", "s3-upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), file)
+	if err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	ext := strings.ToLower(filepath.Ext(fileName))
+	key := s.prefix + hash + ext
+
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(key), tmp)
+	if err != nil {
+		return "", fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentTypeForExtension(ext))
+	req.Header.Set("X-Amz-Meta-Sha256", hash)
+	req.Header.Set("X-Amz-Meta-Original-Filename", url.PathEscape(filepath.Base(fileName)))
+
+	if err := s.do(req, hash); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	return key, nil
+}
+
+// Open streams an object by the key returned from Save.
+func (s *S3Storage) Open(filePath string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(filePath), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	s.signer.sign(req, emptyPayloadHash, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 GET request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to open file: %w", newS3Error(resp))
+	}
+	return resp.Body, nil
+}
+
+// Stat returns the size, content type, modification time and hash of an object.
+func (s *S3Storage) Stat(filePath string) (*FileInfo, error) {
+	req, err := http.NewRequest(http.MethodHead, s.objectURL(filePath), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	s.signer.sign(req, emptyPayloadHash, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 HEAD request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrFileNotFound
+	default:
+		// HEAD responses carry no error body
+		return nil, fmt.Errorf("failed to stat file: %w", &S3Error{StatusCode: resp.StatusCode})
+	}
+
+	info := &FileInfo{
+		Path:        filePath,
+		Name:        path.Base(filePath),
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		Hash:        resp.Header.Get("X-Amz-Meta-Sha256"),
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info, nil
+}
+
+// listBucketResult is the ListObjectsV2 response body.
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List returns the objects whose key starts with prefix, following
+// pagination until the listing is complete. Content types and hashes are not
+// part of the listing; use Stat for those.
+func (s *S3Storage) List(prefix string) ([]*FileInfo, error) {
+	// Never list objects outside the configured prefix; the bucket may be shared.
+	if !strings.HasPrefix(prefix, s.prefix) {
+		if !strings.HasPrefix(s.prefix, prefix) {
+			return nil, nil
+		}
+		prefix = s.prefix
+	}
+
+	var files []*FileInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := http.NewRequest(http.MethodGet, s.bucketURL()+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create s3 request: %w", err)
+		}
+		s.signer.sign(req, emptyPayloadHash, s.now())
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("s3 GET request failed: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := newS3Error(resp)
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode s3 listing: %w", err)
+		}
+
+		for _, obj := range result.Contents {
+			files = append(files, &FileInfo{
+				Path:    obj.Key,
+				Name:    path.Base(obj.Key),
+				Size:    obj.Size,
+				ModTime: obj.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return files, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// SignedURL returns a presigned GET URL for an object. S3 caps the expiry at
+// seven days.
+func (s *S3Storage) SignedURL(filePath string, expiry time.Duration) (string, error) {
+	if expiry <= 0 || expiry > maxPresignExpiry {
+		return "", fmt.Errorf("signed URL expiry must be between 1s and %s", maxPresignExpiry)
+	}
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(filePath), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	s.signer.presign(req, expiry, s.now())
+	return req.URL.String(), nil
+}
+
+// Delete removes an object by the key returned from Save.
+func (s *S3Storage) Delete(filePath string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.objectURL(filePath), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	if err := s.do(req, emptyPayloadHash); err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// objectURL returns the URL of an object, using path-style or virtual-hosted
+// addressing depending on configuration.
+func (s *S3Storage) objectURL(key string) string {
+	u := *s.endpoint
+	if s.usePathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	return u.String()
+}
+
+// bucketURL returns the URL of the bucket itself, used for listing.
+func (s *S3Storage) bucketURL() string {
+	u := *s.endpoint
+	if s.usePathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/"
+	}
+	u.RawPath = ""
+	return u.String()
+}
+
+// do signs and sends a request, turning non-2xx responses into errors.
+func (s *S3Storage) do(req *http.Request, payloadHash string) error {
+	s.signer.sign(req, payloadHash, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 %s request failed: %w", req.Method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return newS3Error(resp)
+}
+
+// S3Error is returned when the object store rejects a request.
+type S3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3 request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3 request failed with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func newS3Error(resp *http.Response) error {
+	s3Err := &S3Error{}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	_ = xml.Unmarshal(body, s3Err)
+	s3Err.StatusCode = resp.StatusCode
+	return s3Err
+}
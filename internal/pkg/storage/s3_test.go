@@ -0,0 +1,242 @@
+package storage_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is a minimal in-process stand-in for an S3-compatible object store
+// using path-style addressing.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	headers map[string]http.Header
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, headers: map[string]http.Header{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/") {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != r.Header.Get("X-Amz-Content-Sha256") {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "<Error><Code>XAmzContentSHA256Mismatch</Code><Message>mismatch</Message></Error>")
+			return
+		}
+		f.objects[r.URL.Path] = body
+		f.headers[r.URL.Path] = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		if r.URL.Query().Get("list-type") == "2" {
+			f.list(w, r)
+			return
+		}
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>")
+			return
+		}
+		w.Write(body)
+	case http.MethodHead:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.headers[r.URL.Path].Get("Content-Type"))
+		w.Header().Set("X-Amz-Meta-Sha256", f.headers[r.URL.Path].Get("X-Amz-Meta-Sha256"))
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("Last-Modified", "Wed, 01 Jan 2025 10:00:00 GMT")
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list serves ListObjectsV2 one key per page to exercise pagination.
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	bucket := strings.TrimPrefix(r.URL.Path, "/")
+	prefix := "/" + bucket + "/" + r.URL.Query().Get("prefix")
+	var keys []string
+	for path := range f.objects {
+		if strings.HasPrefix(path, prefix) {
+			keys = append(keys, strings.TrimPrefix(path, "/"+bucket+"/"))
+		}
+	}
+	sort.Strings(keys)
+
+	start := 0
+	if token := r.URL.Query().Get("continuation-token"); token != "" {
+		start, _ = strconv.Atoi(token)
+	}
+	io.WriteString(w, "<ListBucketResult>")
+	if start < len(keys) {
+		fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size><LastModified>2025-01-01T10:00:00.000Z</LastModified></Contents>",
+			keys[start], len(f.objects["/"+bucket+"/"+keys[start]]))
+	}
+	if start+1 < len(keys) {
+		fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", start+1)
+	}
+	io.WriteString(w, "</ListBucketResult>")
+}
+
+func newTestS3Storage(t *testing.T, server *httptest.Server, accessKeyID string) *storage.S3Storage {
+	t.Helper()
+	s, err := storage.NewS3Storage(configs.S3Config{
+		Endpoint:        server.URL,
+		Region:          "eu-west-1",
+		Bucket:          "contracts",
+		Prefix:          "uploads/",
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: "test-secret",
+		UsePathStyle:    true,
+	}, server.Client())
+	require.NoError(t, err)
+	return s
+}
+
+func TestS3Storage_SaveAndDelete(t *testing.T) {
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	s := newTestS3Storage(t, server, "test-key")
+
+	key, err := s.Save(strings.NewReader("this is a test file"), "Sale Agreement.PDF")
+	require.NoError(t, err)
+
+	assert.Equal(t, "uploads/5881707e54b0112f901bc83a1ffbacac8fab74ea46a6f706a3efc5f7d4c1c625.pdf", key)
+	objectPath := "/contracts/" + key
+	assert.Equal(t, "this is a test file", string(fake.objects[objectPath]))
+	headers := fake.headers[objectPath]
+	assert.Equal(t, "application/pdf", headers.Get("Content-Type"))
+	assert.Equal(t, "5881707e54b0112f901bc83a1ffbacac8fab74ea46a6f706a3efc5f7d4c1c625", headers.Get("X-Amz-Meta-Sha256"))
+	assert.Equal(t, "Sale%20Agreement.PDF", headers.Get("X-Amz-Meta-Original-Filename"))
+
+	rc, err := s.Open(key)
+	require.NoError(t, err)
+	content, err := io.ReadAll(rc)
+	rc.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "this is a test file", string(content))
+
+	info, err := s.Stat(key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("this is a test file")), info.Size)
+	assert.Equal(t, "application/pdf", info.ContentType)
+	assert.Equal(t, "5881707e54b0112f901bc83a1ffbacac8fab74ea46a6f706a3efc5f7d4c1c625", info.Hash)
+
+	require.NoError(t, s.Delete(key))
+	assert.NotContains(t, fake.objects, objectPath)
+
+	_, err = s.Open(key)
+	assert.ErrorIs(t, err, storage.ErrFileNotFound)
+	_, err = s.Stat(key)
+	assert.ErrorIs(t, err, storage.ErrFileNotFound)
+}
+
+func TestS3Storage_List(t *testing.T) {
+	server := httptest.NewServer(newFakeS3())
+	defer server.Close()
+	s := newTestS3Storage(t, server, "test-key")
+
+	first, err := s.Save(strings.NewReader("first"), "a.txt")
+	require.NoError(t, err)
+	second, err := s.Save(strings.NewReader("second"), "b.txt")
+	require.NoError(t, err)
+
+	files, err := s.List("uploads/")
+	require.NoError(t, err)
+
+	var keys []string
+	for _, f := range files {
+		keys = append(keys, f.Path)
+	}
+	assert.ElementsMatch(t, []string{first, second}, keys)
+}
+
+func TestS3Storage_SignedURL(t *testing.T) {
+	server := httptest.NewServer(newFakeS3())
+	defer server.Close()
+	s := newTestS3Storage(t, server, "test-key")
+
+	signed, err := s.SignedURL("uploads/abc.pdf", 15*time.Minute)
+	require.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "/contracts/uploads/abc.pdf", u.Path)
+	assert.Equal(t, "900", u.Query().Get("X-Amz-Expires"))
+	assert.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+
+	_, err = s.SignedURL("uploads/abc.pdf", 8*24*time.Hour)
+	assert.Error(t, err)
+}
+
+func TestS3Storage_Save_ReturnsS3Error(t *testing.T) {
+	server := httptest.NewServer(newFakeS3())
+	defer server.Close()
+	s := newTestS3Storage(t, server, "wrong-key")
+
+	_, err := s.Save(strings.NewReader("this is a test file"), "contract.pdf")
+
+	var s3Err *storage.S3Error
+	require.ErrorAs(t, err, &s3Err)
+	assert.Equal(t, http.StatusForbidden, s3Err.StatusCode)
+	assert.Equal(t, "AccessDenied", s3Err.Code)
+}
+
+func TestNewFileStorage_SelectsBackend(t *testing.T) {
+	local, err := storage.NewFileStorage(configs.StorageConfig{LocalPath: t.TempDir()})
+	require.NoError(t, err)
+	assert.IsType(t, &storage.LocalStorage{}, local)
+
+	s3, err := storage.NewFileStorage(configs.StorageConfig{Backend: "s3", S3: configs.S3Config{Bucket: "contracts"}})
+	require.NoError(t, err)
+	assert.IsType(t, &storage.S3Storage{}, s3)
+
+	_, err = storage.NewFileStorage(configs.StorageConfig{Backend: "ftp"})
+	assert.Error(t, err)
+
+	encrypted, err := storage.NewFileStorage(configs.StorageConfig{LocalPath: t.TempDir(), Encryption: configs.EncryptionConfig{
+		Enabled:      true,
+		CurrentKeyID: "k1",
+		Keys:         map[string]string{"k1": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="},
+	}})
+	require.NoError(t, err)
+	assert.IsType(t, &storage.EncryptedStorage{}, encrypted)
+
+	_, err = storage.NewFileStorage(configs.StorageConfig{LocalPath: t.TempDir(), Encryption: configs.EncryptionConfig{Enabled: true, CurrentKeyID: "k1"}})
+	assert.ErrorIs(t, err, storage.ErrUnknownKey)
+}
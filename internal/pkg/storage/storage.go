@@ -1,23 +1,102 @@
 package storage
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"mime"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/google/uuid"
+	"contract-analysis-service/configs"
+)
+
+var (
+	// ErrFileNotFound is returned when a stored file does not exist.
+	ErrFileNotFound = errors.New("file not found")
+	// ErrSignedURLsUnsupported is returned when the storage backend has not been
+	// configured to issue signed URLs.
+	ErrSignedURLsUnsupported = errors.New("signed URLs are not configured for this storage backend")
+	// ErrInvalidSignature is returned when a signed URL is forged or has expired.
+	ErrInvalidSignature = errors.New("invalid or expired signature")
 )
 
 // FileStorage defines the interface for file storage operations.
+// Paths are the opaque values returned by Save.
 type FileStorage interface {
 	Save(file io.Reader, fileName string) (string, error)
+	Open(filePath string) (io.ReadCloser, error)
+	Stat(filePath string) (*FileInfo, error)
+	List(prefix string) ([]*FileInfo, error)
+	SignedURL(filePath string, expiry time.Duration) (string, error)
 	Delete(filePath string) error
 }
 
+// SignedURLVerifier is implemented by storage backends whose signed URLs are
+// served by this service rather than by the backend itself.
+type SignedURLVerifier interface {
+	// VerifySignedURL checks the expiry and signature of a signed URL for the
+	// named file and returns the path to Open.
+	VerifySignedURL(name string, expires int64, signature string) (string, error)
+}
+
+// FileInfo describes a stored file.
+type FileInfo struct {
+	Path        string    `json:"path"`
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	Hash        string    `json:"hash,omitempty"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// NewFileStorage creates the FileStorage selected by the storage backend setting,
+// wrapped with encryption at rest when it is enabled.
+func NewFileStorage(cfg configs.StorageConfig) (FileStorage, error) {
+	backend, err := newBackend(cfg)
+	if err != nil || !cfg.Encryption.Enabled {
+		return backend, err
+	}
+	keys, err := NewKeyProviderFromConfig(cfg.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load storage encryption keys: %w", err)
+	}
+	return NewEncryptedStorage(backend, keys), nil
+}
+
+func newBackend(cfg configs.StorageConfig) (FileStorage, error) {
+	switch cfg.GetBackend() {
+	case "local":
+		s, err := NewLocalStorage(cfg.GetLocalPath())
+		if err != nil {
+			return nil, err
+		}
+		if cfg.SigningKey != "" {
+			s.EnableSignedURLs(cfg.PublicURL, []byte(cfg.SigningKey))
+		}
+		return s, nil
+	case "s3":
+		return NewS3Storage(cfg.S3, nil)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend %q", cfg.Backend)
+	}
+}
+
 // LocalStorage implements FileStorage for the local filesystem.
 type LocalStorage struct {
-	basePath string
+	basePath   string
+	publicURL  string
+	signingKey []byte
+	now        func() time.Time
 }
 
 // NewLocalStorage creates a new LocalStorage instance.
@@ -25,36 +104,194 @@ func NewLocalStorage(basePath string) (*LocalStorage, error) {
 	if err := os.MkdirAll(basePath, os.ModePerm); err != nil {
 		return nil, fmt.Errorf("failed to create storage directory: %w", err)
 	}
-	return &LocalStorage{basePath: basePath},
+	return &LocalStorage{basePath: basePath, now: time.Now},
 		nil
 }
 
+// EnableSignedURLs lets SignedURL issue HMAC-signed links of the form
+// <publicURL>/<name>?expires=...&signature=..., to be checked with
+// VerifySignedURL by whichever handler serves publicURL.
+func (s *LocalStorage) EnableSignedURLs(publicURL string, key []byte) {
+	s.publicURL = strings.TrimSuffix(publicURL, "/")
+	s.signingKey = key
+}
+
 // Save saves a file to the local filesystem and returns the path.
+// Files are content-addressed: the name is the SHA-256 of the content plus
+// the original extension, so identical uploads share a single file.
 func (s *LocalStorage) Save(file io.Reader, fileName string) (string, error) {
-	// Generate a unique filename to prevent collisions
-	ext := filepath.Ext(fileName)
-	newFileName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	dstPath := filepath.Join(s.basePath, newFileName)
-
-	// Create the destination file
-	dst, err := os.Create(dstPath)
+	// Stream into a temporary file first; the final name is only known once
+	// the whole content has been hashed.
+	tmp, err := os.CreateTemp(s.basePath, ".upload-*")
 	if err != nil {
 		return "", fmt.Errorf("failed to create destination file: %w", err)
 	}
-	defer dst.Close()
+	defer os.Remove(tmp.Name())
 
-	// Copy the file content
-	if _, err := io.Copy(dst, file); err != nil {
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hasher), file); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	dstPath := filepath.Join(s.basePath, hex.EncodeToString(hasher.Sum(nil))+ext)
+
+	if err := os.Rename(tmp.Name(), dstPath); err != nil {
 		return "", fmt.Errorf("failed to save file: %w", err)
 	}
 
 	return dstPath, nil
 }
 
+// Open opens a stored file for reading.
+func (s *LocalStorage) Open(filePath string) (io.ReadCloser, error) {
+	if err := s.checkPath(filePath); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return f, nil
+}
+
+// Stat returns information about a stored file.
+func (s *LocalStorage) Stat(filePath string) (*FileInfo, error) {
+	if err := s.checkPath(filePath); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return s.fileInfo(filePath, info), nil
+}
+
+// List returns the stored files whose path starts with prefix, sorted by path.
+func (s *LocalStorage) List(prefix string) ([]*FileInfo, error) {
+	entries, err := os.ReadDir(s.basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var files []*FileInfo
+	for _, entry := range entries {
+		// Skip directories and in-flight uploads
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		filePath := filepath.Join(s.basePath, entry.Name())
+		if !strings.HasPrefix(filePath, prefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed while listing
+		}
+		files = append(files, s.fileInfo(filePath, info))
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// SignedURL returns a link to the file that is valid until expiry has elapsed.
+func (s *LocalStorage) SignedURL(filePath string, expiry time.Duration) (string, error) {
+	if s.signingKey == nil {
+		return "", ErrSignedURLsUnsupported
+	}
+	if err := s.checkPath(filePath); err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(filePath)
+	expires := s.now().Add(expiry).Unix()
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expires, 10)},
+		"signature": {s.sign(name, expires)},
+	}
+	return s.publicURL + "/" + url.PathEscape(name) + "?" + query.Encode(), nil
+}
+
+// VerifySignedURL checks a link issued by SignedURL and returns the path of
+// the file it grants access to.
+func (s *LocalStorage) VerifySignedURL(name string, expires int64, signature string) (string, error) {
+	if s.signingKey == nil {
+		return "", ErrSignedURLsUnsupported
+	}
+	if s.now().Unix() > expires || !hmac.Equal([]byte(signature), []byte(s.sign(name, expires))) {
+		return "", ErrInvalidSignature
+	}
+	filePath := filepath.Join(s.basePath, name)
+	if err := s.checkPath(filePath); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
 // Delete removes a file from the local filesystem.
 func (s *LocalStorage) Delete(filePath string) error {
+	if err := s.checkPath(filePath); err != nil {
+		return err
+	}
 	if err := os.Remove(filePath); err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
 	return nil
 }
+
+func (s *LocalStorage) sign(name string, expires int64) string {
+	return hex.EncodeToString(hmacSHA256(s.signingKey, name+"\n"+strconv.FormatInt(expires, 10)))
+}
+
+// checkPath rejects paths outside the storage directory.
+func (s *LocalStorage) checkPath(filePath string) error {
+	rel, err := filepath.Rel(filepath.Clean(s.basePath), filepath.Clean(filePath))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || strings.Contains(rel, string(filepath.Separator)) {
+		return ErrFileNotFound
+	}
+	return nil
+}
+
+func (s *LocalStorage) fileInfo(filePath string, info fs.FileInfo) *FileInfo {
+	name := info.Name()
+	ext := filepath.Ext(name)
+	fi := &FileInfo{
+		Path:        filePath,
+		Name:        name,
+		Size:        info.Size(),
+		ContentType: contentTypeForExtension(ext),
+		ModTime:     info.ModTime(),
+	}
+	// Content-addressed names carry the hash of the file
+	if hash := strings.TrimSuffix(name, ext); len(hash) == sha256.Size*2 {
+		fi.Hash = hash
+	}
+	return fi
+}
+
+// contentTypeForExtension returns the mime type for a file extension, covering
+// the document types accepted on upload.
+func contentTypeForExtension(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".docx":
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	case ".txt":
+		return "text/plain; charset=utf-8"
+	case ".tif", ".tiff":
+		return "image/tiff"
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
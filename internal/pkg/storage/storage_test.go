@@ -0,0 +1,106 @@
+package storage_test
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"contract-analysis-service/internal/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage_Save_IsContentAddressed(t *testing.T) {
+	dir := t.TempDir()
+	s, err := storage.NewLocalStorage(dir)
+	require.NoError(t, err)
+
+	first, err := s.Save(strings.NewReader("this is a test file"), "contract.TXT")
+	require.NoError(t, err)
+	second, err := s.Save(strings.NewReader("this is a test file"), "copy.txt")
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(dir, "5881707e54b0112f901bc83a1ffbacac8fab74ea46a6f706a3efc5f7d4c1c625.txt"), first)
+	assert.Equal(t, first, second)
+
+	content, err := os.ReadFile(first)
+	require.NoError(t, err)
+	assert.Equal(t, "this is a test file", string(content))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestLocalStorage_OpenStatList(t *testing.T) {
+	dir := t.TempDir()
+	s, err := storage.NewLocalStorage(dir)
+	require.NoError(t, err)
+
+	path, err := s.Save(strings.NewReader("this is a test file"), "contract.pdf")
+	require.NoError(t, err)
+
+	rc, err := s.Open(path)
+	require.NoError(t, err)
+	content, err := io.ReadAll(rc)
+	rc.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "this is a test file", string(content))
+
+	info, err := s.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(19), info.Size)
+	assert.Equal(t, "application/pdf", info.ContentType)
+	assert.Equal(t, "5881707e54b0112f901bc83a1ffbacac8fab74ea46a6f706a3efc5f7d4c1c625", info.Hash)
+
+	files, err := s.List(filepath.Join(dir, "5881"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, path, files[0].Path)
+
+	files, err = s.List(filepath.Join(dir, "ffff"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	_, err = s.Open(filepath.Join(dir, "missing.pdf"))
+	assert.ErrorIs(t, err, storage.ErrFileNotFound)
+	_, err = s.Open(filepath.Join(dir, "..", "outside.pdf"))
+	assert.ErrorIs(t, err, storage.ErrFileNotFound)
+}
+
+func TestLocalStorage_SignedURL(t *testing.T) {
+	dir := t.TempDir()
+	s, err := storage.NewLocalStorage(dir)
+	require.NoError(t, err)
+
+	path, err := s.Save(strings.NewReader("this is a test file"), "contract.pdf")
+	require.NoError(t, err)
+
+	_, err = s.SignedURL(path, time.Minute)
+	assert.ErrorIs(t, err, storage.ErrSignedURLsUnsupported)
+
+	s.EnableSignedURLs("https://contracts.example.com/files/", []byte("secret"))
+	signed, err := s.SignedURL(path, time.Minute)
+	require.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "/files/"+filepath.Base(path), u.Path)
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	require.NoError(t, err)
+
+	verified, err := s.VerifySignedURL(filepath.Base(path), expires, u.Query().Get("signature"))
+	require.NoError(t, err)
+	assert.Equal(t, path, verified)
+
+	_, err = s.VerifySignedURL(filepath.Base(path), expires+3600, u.Query().Get("signature"))
+	assert.ErrorIs(t, err, storage.ErrInvalidSignature)
+	_, err = s.VerifySignedURL(filepath.Base(path), time.Now().Add(-time.Minute).Unix(), u.Query().Get("signature"))
+	assert.ErrorIs(t, err, storage.ErrInvalidSignature)
+}
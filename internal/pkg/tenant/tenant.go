@@ -0,0 +1,25 @@
+// Package tenant carries the tenant a request is made on behalf of.
+package tenant
+
+import "context"
+
+// Claim is the JWT claim that names the tenant of an authenticated request.
+const Claim = "tenant_id"
+
+// Default is the tenant of requests that do not name one.
+const Default = "default"
+
+type contextKey struct{}
+
+// WithID returns a copy of ctx that carries the tenant id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant carried by ctx, or Default.
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return Default
+}
@@ -3,24 +3,82 @@ package repositories
 import (
 	"contract-analysis-service/internal/models"
 	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // Common repository errors
 var (
 	// ErrNotFound is returned when a record is not found
 	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a record is not in a state that allows the operation
+	ErrConflict = errors.New("record is in a conflicting state")
 	// ErrStaleData is returned when trying to update a stale record
 	ErrStaleData = errors.New("stale data: the record has been updated by another process")
+	// ErrDuplicate is returned when creating a record that a unique index
+	// says already exists
+	ErrDuplicate = errors.New("record already exists")
 )
 
 type ContractRepository interface {
 	Create(c *models.Contract) error
 	GetByID(id string) (*models.Contract, error)
+	// GetByHash returns the tenant's contract with the given hash.
+	GetByHash(tenantID, hash string) (*models.Contract, error)
 	Update(c *models.Contract) error
+	UpdateFilePath(oldPath, newPath string) error
+	// CountByFilePath returns how many contracts are stored at filePath.
+	CountByFilePath(filePath string) (int64, error)
+	// SaveAnalysis updates a contract and replaces its milestones and risks
+	// with the ones set on it.
+	SaveAnalysis(c *models.Contract) error
+	// Delete removes a contract together with its milestones, risks and jobs.
 	Delete(id string) error
 	List() ([]*models.Contract, error)
 }
 
+type ExtractedDocumentRepository interface {
+	Create(d *models.ExtractedDocument) error
+	GetByContractID(contractID string) (*models.ExtractedDocument, error)
+	DeleteByContractID(contractID string) error
+}
+
+type AnalysisStageRepository interface {
+	// Save creates or updates a stage record.
+	Save(s *models.AnalysisStage) error
+	ListByContractID(contractID string) ([]*models.AnalysisStage, error)
+	DeleteByContractID(contractID string) error
+}
+
+type LLMUsageRepository interface {
+	Create(u *models.LLMUsage) error
+	// ListByContractID returns the usage records of a contract, oldest first.
+	ListByContractID(contractID string) ([]*models.LLMUsage, error)
+	// TenantCost returns the cost of a tenant's LLM calls made since the given time.
+	TenantCost(tenantID string, since time.Time) (decimal.Decimal, error)
+}
+
+type JobRepository interface {
+	Create(j *models.Job) error
+	GetByID(id string) (*models.Job, error)
+	Update(j *models.Job) error
+	// GetActive returns the queued or running job of the given type for a contract.
+	GetActive(jobType, contractID string) (*models.Job, error)
+	// ClaimNext atomically assigns the oldest runnable job to workerID: a queued
+	// job that is due, or a running job whose lease has expired.
+	ClaimNext(workerID string, now, leaseUntil time.Time) (*models.Job, error)
+	// ExtendLease renews the lease of a job held by workerID and returns the
+	// job as stored, so the worker can observe cancellation requests.
+	ExtendLease(id, workerID string, leaseUntil time.Time) (*models.Job, error)
+	// Release stores the outcome of a job held by workerID. It returns
+	// ErrConflict if the worker no longer holds the job.
+	Release(j *models.Job, workerID string) error
+	// Cancel cancels a queued job immediately, or flags a running job for
+	// cancellation by its worker.
+	Cancel(id string, now time.Time) (*models.Job, error)
+}
+
 type MilestoneRepository interface {
 	Create(m *models.Milestone) error
 	GetByID(id string) (*models.Milestone, error)
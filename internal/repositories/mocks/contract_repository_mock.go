@@ -25,12 +25,39 @@ func (m *ContractRepository) GetByID(id string) (*models.Contract, error) {
 	return args.Get(0).(*models.Contract), args.Error(1)
 }
 
+// GetByHash mocks the GetByHash method.
+func (m *ContractRepository) GetByHash(tenantID, hash string) (*models.Contract, error) {
+	args := m.Called(tenantID, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Contract), args.Error(1)
+}
+
+// SaveAnalysis mocks the SaveAnalysis method.
+func (m *ContractRepository) SaveAnalysis(c *models.Contract) error {
+	args := m.Called(c)
+	return args.Error(0)
+}
+
 // Update mocks the Update method.
 func (m *ContractRepository) Update(c *models.Contract) error {
 	args := m.Called(c)
 	return args.Error(0)
 }
 
+// UpdateFilePath mocks the UpdateFilePath method.
+func (m *ContractRepository) UpdateFilePath(oldPath, newPath string) error {
+	args := m.Called(oldPath, newPath)
+	return args.Error(0)
+}
+
+// CountByFilePath mocks the CountByFilePath method.
+func (m *ContractRepository) CountByFilePath(filePath string) (int64, error) {
+	args := m.Called(filePath)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // Delete mocks the Delete method.
 func (m *ContractRepository) Delete(id string) error {
 	args := m.Called(id)
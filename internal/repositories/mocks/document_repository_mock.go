@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"contract-analysis-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// ExtractedDocumentRepository is a mock implementation of the ExtractedDocumentRepository interface.
+type ExtractedDocumentRepository struct {
+	mock.Mock
+}
+
+// Create mocks the Create method.
+func (m *ExtractedDocumentRepository) Create(d *models.ExtractedDocument) error {
+	args := m.Called(d)
+	return args.Error(0)
+}
+
+// GetByContractID mocks the GetByContractID method.
+func (m *ExtractedDocumentRepository) GetByContractID(contractID string) (*models.ExtractedDocument, error) {
+	args := m.Called(contractID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExtractedDocument), args.Error(1)
+}
+
+// DeleteByContractID mocks the DeleteByContractID method.
+func (m *ExtractedDocumentRepository) DeleteByContractID(contractID string) error {
+	args := m.Called(contractID)
+	return args.Error(0)
+}
@@ -0,0 +1,32 @@
+package mocks
+
+import (
+	"contract-analysis-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// AnalysisStageRepository is a mock implementation of the AnalysisStageRepository interface.
+type AnalysisStageRepository struct {
+	mock.Mock
+}
+
+// Save mocks the Save method.
+func (m *AnalysisStageRepository) Save(s *models.AnalysisStage) error {
+	args := m.Called(s)
+	return args.Error(0)
+}
+
+// ListByContractID mocks the ListByContractID method.
+func (m *AnalysisStageRepository) ListByContractID(contractID string) ([]*models.AnalysisStage, error) {
+	args := m.Called(contractID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AnalysisStage), args.Error(1)
+}
+
+// DeleteByContractID mocks the DeleteByContractID method.
+func (m *AnalysisStageRepository) DeleteByContractID(contractID string) error {
+	args := m.Called(contractID)
+	return args.Error(0)
+}
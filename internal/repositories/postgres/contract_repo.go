@@ -1,6 +1,8 @@
 package postgres
 
 import (
+	"errors"
+
 	"contract-analysis-service/internal/models"
 	"contract-analysis-service/internal/repositories"
 	"gorm.io/gorm"
@@ -15,9 +17,13 @@ func NewContractRepository(db *gorm.DB) repositories.ContractRepository {
 }
 
 func (r *contractRepo) Create(c *models.Contract) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
 		return tx.Create(c).Error
 	})
+	if translator, ok := r.db.Dialector.(gorm.ErrorTranslator); ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return repositories.ErrDuplicate
+	}
+	return err
 }
 
 func (r *contractRepo) GetByID(id string) (*models.Contract, error) {
@@ -26,12 +32,51 @@ func (r *contractRepo) GetByID(id string) (*models.Contract, error) {
 	return &c, err
 }
 
+func (r *contractRepo) GetByHash(tenantID, hash string) (*models.Contract, error) {
+	var c models.Contract
+	err := r.db.First(&c, "tenant_id = ? AND hash = ?", tenantID, hash).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, repositories.ErrNotFound
+	}
+	return &c, err
+}
+
 func (r *contractRepo) Update(c *models.Contract) error {
 	return r.db.Save(c).Error
 }
 
+func (r *contractRepo) SaveAnalysis(c *models.Contract) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("contract_id = ?", c.ID).Delete(&models.Milestone{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("contract_id = ?", c.ID).Delete(&models.RiskAssessment{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Document").Save(c).Error
+	})
+}
+
+func (r *contractRepo) UpdateFilePath(oldPath, newPath string) error {
+	return r.db.Model(&models.Contract{}).Where("file_path = ?", oldPath).Update("file_path", newPath).Error
+}
+
+func (r *contractRepo) CountByFilePath(filePath string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Contract{}).Where("file_path = ?", filePath).Count(&count).Error
+	return count, err
+}
+
+// Delete removes a contract together with its milestones, risks and jobs.
 func (r *contractRepo) Delete(id string) error {
-	return r.db.Delete(&models.Contract{}, "id = ?", id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, owned := range []interface{}{&models.Milestone{}, &models.RiskAssessment{}, &models.Job{}} {
+			if err := tx.Where("contract_id = ?", id).Delete(owned).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Contract{}, "id = ?", id).Error
+	})
 }
 
 func (r *contractRepo) List() ([]*models.Contract, error) {
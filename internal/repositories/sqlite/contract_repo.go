@@ -1,6 +1,8 @@
 package sqlite
 
 import (
+	"errors"
+
 	"contract-analysis-service/internal/models"
 	"contract-analysis-service/internal/repositories"
 	"gorm.io/gorm"
@@ -12,8 +14,8 @@ type contractRepository struct {
 
 // NewContractRepository creates a new SQLite contract repository
 func NewContractRepository(db *gorm.DB) repositories.ContractRepository {
-	// Auto-migrate the schema
-	err := db.AutoMigrate(&models.Contract{})
+	// Auto-migrate the schema. Jobs are deleted together with their contract.
+	err := db.AutoMigrate(&models.Contract{}, &models.Milestone{}, &models.RiskAssessment{}, &models.Job{})
 	if err != nil {
 		panic("failed to migrate contract model: " + err.Error())
 	}
@@ -23,13 +25,33 @@ func NewContractRepository(db *gorm.DB) repositories.ContractRepository {
 	}
 }
 
+// Create stores a new contract. It returns repositories.ErrDuplicate when the
+// tenant already has a contract with the same hash.
 func (r *contractRepository) Create(c *models.Contract) error {
-	return r.db.Create(c).Error
+	err := r.db.Create(c).Error
+	if translator, ok := r.db.Dialector.(gorm.ErrorTranslator); ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return repositories.ErrDuplicate
+	}
+	return err
 }
 
 func (r *contractRepository) GetByID(id string) (*models.Contract, error) {
 	var contract models.Contract
-	err := r.db.First(&contract, "id = ?", id).Error
+	err := r.db.Preload("Milestones", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sequence_order")
+	}).Preload("Risks").First(&contract, "id = ?", id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, repositories.ErrNotFound
+		}
+		return nil, err
+	}
+	return &contract, nil
+}
+
+func (r *contractRepository) GetByHash(tenantID, hash string) (*models.Contract, error) {
+	var contract models.Contract
+	err := r.db.First(&contract, "tenant_id = ? AND hash = ?", tenantID, hash).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, repositories.ErrNotFound
@@ -43,15 +65,47 @@ func (r *contractRepository) Update(c *models.Contract) error {
 	return r.db.Save(c).Error
 }
 
+// SaveAnalysis updates a contract and replaces its milestones and risks.
+func (r *contractRepository) SaveAnalysis(c *models.Contract) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("contract_id = ?", c.ID).Delete(&models.Milestone{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("contract_id = ?", c.ID).Delete(&models.RiskAssessment{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Document").Save(c).Error
+	})
+}
+
+// UpdateFilePath points every contract stored at oldPath to newPath.
+func (r *contractRepository) UpdateFilePath(oldPath, newPath string) error {
+	return r.db.Model(&models.Contract{}).Where("file_path = ?", oldPath).Update("file_path", newPath).Error
+}
+
+func (r *contractRepository) CountByFilePath(filePath string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Contract{}).Where("file_path = ?", filePath).Count(&count).Error
+	return count, err
+}
+
+// Delete removes a contract together with its milestones, risks and jobs.
 func (r *contractRepository) Delete(id string) error {
-	result := r.db.Delete(&models.Contract{}, "id = ?", id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return repositories.ErrNotFound
-	}
-	return nil
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, owned := range []interface{}{&models.Milestone{}, &models.RiskAssessment{}, &models.Job{}} {
+			if err := tx.Where("contract_id = ?", id).Delete(owned).Error; err != nil {
+				return err
+			}
+		}
+		result := tx.Delete(&models.Contract{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repositories.ErrNotFound
+		}
+		return nil
+	})
 }
 
 func (r *contractRepository) List() ([]*models.Contract, error) {
@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// extractedDocumentRepository implements the repositories.ExtractedDocumentRepository interface for SQLite.
+type extractedDocumentRepository struct {
+	db *gorm.DB
+}
+
+// NewExtractedDocumentRepository creates a new extracted document repository.
+func NewExtractedDocumentRepository(db *gorm.DB) repositories.ExtractedDocumentRepository {
+	// Auto-migrate the schema
+	err := db.AutoMigrate(&models.ExtractedDocument{}, &models.ExtractedPage{})
+	if err != nil {
+		panic("failed to migrate extracted document model: " + err.Error())
+	}
+
+	return &extractedDocumentRepository{db: db}
+}
+
+// Create stores the document together with its pages.
+func (r *extractedDocumentRepository) Create(d *models.ExtractedDocument) error {
+	return r.db.Create(d).Error
+}
+
+// GetByContractID returns the extracted document of a contract with its pages in order.
+func (r *extractedDocumentRepository) GetByContractID(contractID string) (*models.ExtractedDocument, error) {
+	var d models.ExtractedDocument
+	err := r.db.Preload("Pages", func(db *gorm.DB) *gorm.DB {
+		return db.Order("number")
+	}).First(&d, "contract_id = ?", contractID).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, repositories.ErrNotFound
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+// DeleteByContractID removes the extracted document of a contract and its pages.
+func (r *extractedDocumentRepository) DeleteByContractID(contractID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		if err := tx.Model(&models.ExtractedDocument{}).Where("contract_id = ?", contractID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Where("document_id IN ?", ids).Delete(&models.ExtractedPage{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&models.ExtractedDocument{}).Error
+	})
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"errors"
+	"time"
+
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// jobRepository implements the repositories.JobRepository interface for SQLite.
+type jobRepository struct {
+	db *gorm.DB
+}
+
+// NewJobRepository creates a new SQLite job repository.
+func NewJobRepository(db *gorm.DB) repositories.JobRepository {
+	// Auto-migrate the schema
+	err := db.AutoMigrate(&models.Job{})
+	if err != nil {
+		panic("failed to migrate job model: " + err.Error())
+	}
+
+	return &jobRepository{db: db}
+}
+
+func (r *jobRepository) Create(j *models.Job) error {
+	return r.db.Create(j).Error
+}
+
+func (r *jobRepository) GetByID(id string) (*models.Job, error) {
+	var job models.Job
+	err := r.db.First(&job, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repositories.ErrNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *jobRepository) Update(j *models.Job) error {
+	return r.db.Save(j).Error
+}
+
+func (r *jobRepository) GetActive(jobType, contractID string) (*models.Job, error) {
+	var job models.Job
+	err := r.db.Where("type = ? AND contract_id = ? AND status IN ?", jobType, contractID, []models.JobStatus{models.JobQueued, models.JobRunning}).
+		Order("created_at").First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, repositories.ErrNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *jobRepository) ClaimNext(workerID string, now, leaseUntil time.Time) (*models.Job, error) {
+	// Several workers may race for the same candidate; the conditional update
+	// lets exactly one of them win, and the losers move on to the next one.
+	for {
+		var candidate models.Job
+		err := r.db.Where("(status = ? AND run_at <= ?) OR (status = ? AND lease_expires_at < ?)",
+			models.JobQueued, now, models.JobRunning, now).
+			Order("run_at").First(&candidate).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, repositories.ErrNotFound
+			}
+			return nil, err
+		}
+
+		result := r.db.Model(&models.Job{}).
+			Where("id = ? AND ((status = ? AND run_at <= ?) OR (status = ? AND lease_expires_at < ?))",
+				candidate.ID, models.JobQueued, now, models.JobRunning, now).
+			Updates(map[string]interface{}{
+				"status":           models.JobRunning,
+				"worker_id":        workerID,
+				"attempts":         gorm.Expr("attempts + 1"),
+				"lease_expires_at": leaseUntil,
+				"started_at":       now,
+				"updated_at":       now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return r.GetByID(candidate.ID)
+		}
+	}
+}
+
+func (r *jobRepository) ExtendLease(id, workerID string, leaseUntil time.Time) (*models.Job, error) {
+	result := r.db.Model(&models.Job{}).
+		Where("id = ? AND worker_id = ? AND status = ?", id, workerID, models.JobRunning).
+		Update("lease_expires_at", leaseUntil)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, repositories.ErrConflict
+	}
+	return r.GetByID(id)
+}
+
+func (r *jobRepository) Release(j *models.Job, workerID string) error {
+	// Only the columns the worker owns are written, so that a cancellation
+	// requested while the job ran is not overwritten with the worker's copy.
+	result := r.db.Model(&models.Job{}).
+		Where("id = ? AND worker_id = ? AND status = ?", j.ID, workerID, models.JobRunning).
+		Select("status", "error", "result", "run_at", "attempts", "worker_id", "lease_expires_at", "finished_at", "updated_at").
+		Updates(j)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return repositories.ErrConflict
+	}
+	return nil
+}
+
+func (r *jobRepository) Cancel(id string, now time.Time) (*models.Job, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var job models.Job
+		if err := tx.First(&job, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return repositories.ErrNotFound
+			}
+			return err
+		}
+
+		switch job.Status {
+		case models.JobQueued:
+			return tx.Model(&job).Updates(map[string]interface{}{
+				"status":           models.JobCancelled,
+				"cancel_requested": true,
+				"finished_at":      now,
+			}).Error
+		case models.JobRunning:
+			return tx.Model(&job).Update("cancel_requested", true).Error
+		default:
+			return repositories.ErrConflict
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
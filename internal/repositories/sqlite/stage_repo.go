@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// analysisStageRepository implements the repositories.AnalysisStageRepository interface for SQLite.
+type analysisStageRepository struct {
+	db *gorm.DB
+}
+
+// NewAnalysisStageRepository creates a new analysis stage repository.
+func NewAnalysisStageRepository(db *gorm.DB) repositories.AnalysisStageRepository {
+	// Auto-migrate the schema
+	err := db.AutoMigrate(&models.AnalysisStage{})
+	if err != nil {
+		panic("failed to migrate analysis stage model: " + err.Error())
+	}
+
+	return &analysisStageRepository{db: db}
+}
+
+// Save creates or updates a stage record.
+func (r *analysisStageRepository) Save(s *models.AnalysisStage) error {
+	return r.db.Save(s).Error
+}
+
+// ListByContractID returns the stage records of a contract.
+func (r *analysisStageRepository) ListByContractID(contractID string) ([]*models.AnalysisStage, error) {
+	var stages []*models.AnalysisStage
+	err := r.db.Where("contract_id = ?", contractID).Find(&stages).Error
+	if err != nil {
+		return nil, err
+	}
+	return stages, nil
+}
+
+// DeleteByContractID removes the stage records of a contract.
+func (r *analysisStageRepository) DeleteByContractID(contractID string) error {
+	return r.db.Where("contract_id = ?", contractID).Delete(&models.AnalysisStage{}).Error
+}
@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"time"
+
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/repositories"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// llmUsageRepository implements the repositories.LLMUsageRepository interface for SQLite.
+type llmUsageRepository struct {
+	db *gorm.DB
+}
+
+// NewLLMUsageRepository creates a new LLM usage repository.
+func NewLLMUsageRepository(db *gorm.DB) repositories.LLMUsageRepository {
+	// Auto-migrate the schema
+	err := db.AutoMigrate(&models.LLMUsage{})
+	if err != nil {
+		panic("failed to migrate LLM usage model: " + err.Error())
+	}
+
+	return &llmUsageRepository{db: db}
+}
+
+// Create records the usage of an LLM call.
+func (r *llmUsageRepository) Create(u *models.LLMUsage) error {
+	return r.db.Create(u).Error
+}
+
+// ListByContractID returns the usage records of a contract, oldest first.
+func (r *llmUsageRepository) ListByContractID(contractID string) ([]*models.LLMUsage, error) {
+	var usage []*models.LLMUsage
+	err := r.db.Where("contract_id = ?", contractID).Order("created_at").Find(&usage).Error
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// TenantCost returns the cost of a tenant's LLM calls made since the given
+// time. The costs are added up here rather than with SUM, which would lose
+// precision on decimal columns stored as text.
+func (r *llmUsageRepository) TenantCost(tenantID string, since time.Time) (decimal.Decimal, error) {
+	var costs []decimal.Decimal
+	err := r.db.Model(&models.LLMUsage{}).
+		Where("tenant_id = ? AND created_at >= ?", tenantID, since).
+		Pluck("cost", &costs).Error
+	if err != nil {
+		return decimal.Zero, err
+	}
+	total := decimal.Zero
+	for _, cost := range costs {
+		total = total.Add(cost)
+	}
+	return total, nil
+}
@@ -0,0 +1,101 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/services/extraction/rules"
+	"contract-analysis-service/internal/services/llm"
+	"github.com/shopspring/decimal"
+)
+
+// crossCheck lists the fields of an analysis that contradict the values the
+// rules found in the text, named like the disputed fields of an ensemble
+// analysis: the buyer or seller when the text defines a party in that role
+// by another name, the total value or currency when no amount in the text
+// has it, and the percentage or amount of a milestone when its quote states
+// others. Values the rules did not find anything for are not checked.
+func crossCheck(analysis *models.ContractAnalysis, extracted *rules.Extraction) []string {
+	var fields []string
+	if !definesParty(extracted, rules.RoleBuyer, analysis.Buyer) {
+		fields = append(fields, "buyer")
+	}
+	if !definesParty(extracted, rules.RoleSeller, analysis.Seller) {
+		fields = append(fields, "seller")
+	}
+	if !analysis.TotalValue.IsZero() && len(extracted.Amounts) > 0 && !statesAmount(extracted.Amounts, analysis.TotalValue) {
+		fields = append(fields, "total_value")
+	}
+	if currency := strings.TrimSpace(analysis.Currency); currency != "" && !statesCurrency(extracted.Amounts, currency) {
+		fields = append(fields, "currency")
+	}
+
+	for i, m := range analysis.Milestones {
+		if m.SourceQuote == "" {
+			continue
+		}
+		quoted := rules.Extract(m.SourceQuote)
+		if m.Percentage != 0 && len(quoted.Percentages) > 0 && !statesPercentage(quoted.Percentages, m.Percentage) {
+			fields = append(fields, fmt.Sprintf("milestones[%d].percentage", i))
+		}
+		if !m.Amount.IsZero() && len(quoted.Amounts) > 0 && !statesAmount(quoted.Amounts, m.Amount) {
+			fields = append(fields, fmt.Sprintf("milestones[%d].amount", i))
+		}
+	}
+	return fields
+}
+
+// definesParty reports whether name is the name of a party the text defines
+// in role, or the text defines none. Names match when the words of one
+// appear in the other, so that "Acme" matches "Acme Widgets Inc.".
+func definesParty(extracted *rules.Extraction, role, name string) bool {
+	defined := false
+	key := " " + llm.PartyKey(name) + " "
+	for _, p := range extracted.Parties {
+		if p.Role != role {
+			continue
+		}
+		defined = true
+		other := " " + llm.PartyKey(p.Name) + " "
+		if strings.TrimSpace(key) != "" && (strings.Contains(other, key) || strings.Contains(key, other)) {
+			return true
+		}
+	}
+	return !defined
+}
+
+func statesAmount(amounts []rules.Amount, value decimal.Decimal) bool {
+	for _, a := range amounts {
+		if a.Value.Equal(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// statesCurrency reports whether one of the amounts is in currency, or none
+// of them is stated with a currency.
+func statesCurrency(amounts []rules.Amount, currency string) bool {
+	found := false
+	for _, a := range amounts {
+		if a.Currency == "" {
+			continue
+		}
+		if strings.EqualFold(a.Currency, currency) {
+			return true
+		}
+		found = true
+	}
+	return !found
+}
+
+func statesPercentage(percentages []rules.Percentage, value float64) bool {
+	for _, p := range percentages {
+		if math.Abs(p.Value-value) < 0.005 {
+			return true
+		}
+	}
+	return false
+}
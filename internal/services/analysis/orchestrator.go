@@ -0,0 +1,920 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/pkg/storage"
+	"contract-analysis-service/internal/repositories"
+	"contract-analysis-service/internal/services/extraction"
+	"contract-analysis-service/internal/services/extraction/rules"
+	"contract-analysis-service/internal/services/jobs"
+	"contract-analysis-service/internal/services/llm"
+	"contract-analysis-service/internal/services/llm/prompts"
+	"contract-analysis-service/internal/services/usage"
+	"contract-analysis-service/internal/services/validation"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// Stage names, in the order the orchestrator runs them.
+const (
+	StageExtraction     = "extraction"
+	StageValidation     = "validation"
+	StageClassification = "classification"
+	StageAnalysis       = "analysis"
+	StageSequencing     = "sequencing"
+	StageRisk           = "risk"
+	StageCompliance     = "compliance"
+)
+
+// defaultJurisdiction is used for the compliance check when no choice of law
+// clause is found in the contract.
+const defaultJurisdiction = "international"
+
+var (
+	// ErrContractNotFound is returned when analysing an unknown contract.
+	ErrContractNotFound = errors.New("contract not found")
+	// ErrNotAContract is returned when validation found that the document is not a contract.
+	ErrNotAContract = errors.New("document is not a valid contract")
+)
+
+// Analyzer extracts the parties, value, milestones and risk factors of a contract.
+type Analyzer interface {
+	AnalyzeContract(ctx context.Context, provider, contractText string) (*models.ContractAnalysis, error)
+}
+
+// Sequencer orders milestones and works out their dependencies.
+type Sequencer interface {
+	SequenceMilestones(ctx context.Context, provider string, milestones []models.AnalysisMilestone) ([]models.SequencedMilestone, error)
+}
+
+// RiskAssessor assesses contract risks against industry standards.
+type RiskAssessor interface {
+	AssessRisks(ctx context.Context, provider, contractText, industryStandards string) (*models.AnalysisRiskAssessment, error)
+}
+
+// ComplianceChecker checks a contract against the requirements of a jurisdiction.
+type ComplianceChecker interface {
+	CheckCompliance(ctx context.Context, provider, contractText, jurisdiction string) (*models.AnalysisComplianceReport, error)
+}
+
+// IndustryClassifier classifies contracts and looks up industry standards.
+type IndustryClassifier interface {
+	ClassifyIndustry(ctx context.Context, contractText string) (string, error)
+	QueryByIndustry(ctx context.Context, industry string) ([]*models.KnowledgeEntry, error)
+}
+
+// Stages holds the LLM-backed steps of the analysis.
+type Stages struct {
+	Classifier   IndustryClassifier
+	Analyzer     Analyzer
+	Sequencer    Sequencer
+	RiskAssessor RiskAssessor
+	Compliance   ComplianceChecker
+}
+
+// Classification is the output of the classification stage.
+type Classification struct {
+	Industry  string `json:"industry"`
+	Standards string `json:"standards,omitempty"`
+}
+
+// Orchestrator runs every analysis stage for a contract and maps the results
+// onto the contract. The outcome of each stage is persisted as it completes,
+// so a failed run resumes at the stage that failed when its job is retried.
+// A new analysis starts over; see Reset.
+type Orchestrator struct {
+	contractRepo      repositories.ContractRepository
+	documentRepo      repositories.ExtractedDocumentRepository
+	stageRepo         repositories.AnalysisStageRepository
+	storage           storage.FileStorage
+	extractionService extraction.Service
+	validationService validation.Service
+	stages            Stages
+	progress          *progress
+	pollInterval      time.Duration
+	logger            *zap.Logger
+	now               func() time.Time
+}
+
+// NewOrchestrator creates a new analysis orchestrator. Each stage names its
+// own LLM task, so the providers it uses follow the routing configuration.
+func NewOrchestrator(contractRepo repositories.ContractRepository, documentRepo repositories.ExtractedDocumentRepository, stageRepo repositories.AnalysisStageRepository, storage storage.FileStorage, extractionService extraction.Service, validationService validation.Service, stages Stages, logger *zap.Logger) *Orchestrator {
+	return &Orchestrator{
+		contractRepo:      contractRepo,
+		documentRepo:      documentRepo,
+		stageRepo:         stageRepo,
+		storage:           storage,
+		extractionService: extractionService,
+		validationService: validationService,
+		stages:            stages,
+		progress:          newProgress(),
+		pollInterval:      time.Second,
+		logger:            logger,
+		now:               time.Now,
+	}
+}
+
+// SetPollInterval sets how often subscribers to an analysis running in
+// another process are updated from the store.
+func (o *Orchestrator) SetPollInterval(interval time.Duration) {
+	if interval > 0 {
+		o.pollInterval = interval
+	}
+}
+
+// Run analyses a contract, skipping stages that succeeded in an earlier
+// attempt of the same analysis, and returns the contract with its summary,
+// milestones, risks and compliance report filled in. Its progress is
+// published to the subscribers of the contract.
+func (o *Orchestrator) Run(ctx context.Context, contractID string) (*models.Contract, error) {
+	o.progress.start(contractID)
+	contract, err := o.run(ctx, contractID)
+	if err != nil {
+		o.progress.finish(contractID, Event{Type: EventFailed, Error: err.Error()})
+	} else {
+		o.progress.finish(contractID, Event{Type: EventCompleted})
+	}
+	return contract, err
+}
+
+func (o *Orchestrator) run(ctx context.Context, contractID string) (*models.Contract, error) {
+	contract, err := o.contractRepo.GetByID(contractID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrContractNotFound
+		}
+		return nil, fmt.Errorf("failed to load contract: %w", err)
+	}
+	ctx = usage.WithContract(ctx, contractID, contract.TenantID)
+
+	records, err := o.stageRepo.ListByContractID(contractID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load analysis stages: %w", err)
+	}
+	run := &stageRun{contractID: contractID, records: make(map[string]*models.AnalysisStage, len(records))}
+	for _, record := range records {
+		run.records[record.Stage] = record
+	}
+
+	logger := o.logger.With(zap.String("contract_id", contractID))
+
+	// Extraction: the text is normally stored at upload; only re-extract when it is missing
+	var pages int
+	err = o.runStage(ctx, run, StageExtraction, &pages, func(ctx context.Context) (interface{}, error) {
+		doc, err := o.ensureDocument(ctx, contract)
+		if err != nil {
+			return nil, err
+		}
+		return len(doc.Pages), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	doc, err := o.documentRepo.GetByContractID(contractID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load extracted document: %w", err)
+	}
+	extracted := rules.Extract(doc.Text)
+
+	var validationResult *models.ValidationResult
+	err = o.runStage(ctx, run, StageValidation, &validationResult, func(ctx context.Context) (interface{}, error) {
+		if hasValidation(contract.Validation) {
+			return contract.Validation, nil
+		}
+		return o.validationService.ValidateContract(ctx, doc.Text)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !validationResult.IsValidContract {
+		return nil, jobs.Permanent(fmt.Errorf("%w: %s", ErrNotAContract, validationResult.Reason))
+	}
+	contract.Validation = validationResult
+	if validationResult.ContractType != "" {
+		contract.ContractType = validationResult.ContractType
+	}
+
+	var classification Classification
+	err = o.runStage(ctx, run, StageClassification, &classification, func(ctx context.Context) (interface{}, error) {
+		industry, err := o.stages.Classifier.ClassifyIndustry(ctx, doc.Text)
+		if err != nil {
+			return nil, err
+		}
+		standards, err := o.stages.Classifier.QueryByIndustry(ctx, industry)
+		if err != nil {
+			// Risks can still be assessed without reference material
+			logger.Warn("Failed to load industry standards", zap.String("industry", industry), zap.Error(err))
+		}
+		return &Classification{Industry: industry, Standards: formatStandards(standards)}, nil
+	})
+	if err != nil {
+		return o.degrade(contract, doc, extracted, err)
+	}
+	// Later prompts may have overrides for the industry
+	ctx = prompts.WithSelector(ctx, prompts.Industry, classification.Industry)
+
+	var contractAnalysis *models.ContractAnalysis
+	err = o.runStage(ctx, run, StageAnalysis, &contractAnalysis, func(ctx context.Context) (interface{}, error) {
+		return o.stages.Analyzer.AnalyzeContract(ctx, llm.TaskAnalysis, doc.Text)
+	})
+	if err != nil {
+		return o.degrade(contract, doc, extracted, err)
+	}
+
+	var sequenced []models.SequencedMilestone
+	err = o.runStage(ctx, run, StageSequencing, &sequenced, func(ctx context.Context) (interface{}, error) {
+		if len(contractAnalysis.Milestones) == 0 {
+			return []models.SequencedMilestone{}, nil
+		}
+		return o.stages.Sequencer.SequenceMilestones(ctx, llm.TaskSequencing, contractAnalysis.Milestones)
+	})
+	if err != nil {
+		return o.degrade(contract, doc, extracted, err)
+	}
+
+	var risks *models.AnalysisRiskAssessment
+	err = o.runStage(ctx, run, StageRisk, &risks, func(ctx context.Context) (interface{}, error) {
+		return o.stages.RiskAssessor.AssessRisks(ctx, llm.TaskRisk, doc.Text, classification.Standards)
+	})
+	if err != nil {
+		return o.degrade(contract, doc, extracted, err)
+	}
+
+	var compliance *models.AnalysisComplianceReport
+	err = o.runStage(ctx, run, StageCompliance, &compliance, func(ctx context.Context) (interface{}, error) {
+		return o.stages.Compliance.CheckCompliance(ctx, llm.TaskCompliance, doc.Text, jurisdiction(extracted))
+	})
+	if err != nil {
+		return o.degrade(contract, doc, extracted, err)
+	}
+
+	applyAnalysis(contract, doc, extracted, classification.Industry, contractAnalysis, sequenced, risks, compliance)
+	contract.PromptVersions = run.promptVersions(contract.Validation)
+	if len(contract.UnverifiedFields) > 0 {
+		logger.Warn("Analysis cites passages that are not in the document", zap.Strings("fields", contract.UnverifiedFields))
+	}
+	if len(contract.DisputedFields) > 0 {
+		logger.Warn("Analysis is disputed", zap.Strings("fields", contract.DisputedFields), zap.Float64("confidence", contract.Confidence))
+	}
+	if contractAnalysis.FailedRuns > 0 {
+		logger.Warn("Ensemble runs failed", zap.Int("failed_runs", contractAnalysis.FailedRuns))
+	}
+	if len(contract.Findings) > 0 {
+		logger.Warn("Milestones do not reconcile with the total value", zap.Any("findings", contract.Findings))
+	}
+	if err := o.contractRepo.SaveAnalysis(contract); err != nil {
+		return nil, fmt.Errorf("failed to save contract analysis: %w", err)
+	}
+
+	logger.Info("Contract analysis completed", zap.String("industry", classification.Industry))
+	return contract, nil
+}
+
+// degrade handles the failure of an LLM stage. When every provider is
+// unavailable, the analysis the rules extract from the text is saved in the
+// meantime, unless the contract already has a full one. err is returned
+// either way, so that the job is retried and the analysis completed once a
+// provider is back.
+func (o *Orchestrator) degrade(contract *models.Contract, doc *models.ExtractedDocument, extracted *rules.Extraction, err error) (*models.Contract, error) {
+	if !errors.Is(err, llm.ErrProvidersUnavailable) || contract.Status == models.Analyzed {
+		return nil, err
+	}
+	applyExtraction(contract, doc, extracted)
+	if saveErr := o.contractRepo.SaveAnalysis(contract); saveErr != nil {
+		o.logger.Error("Failed to save rule-based analysis", zap.String("contract_id", contract.ID), zap.Error(saveErr))
+		return nil, err
+	}
+	o.logger.Warn("LLM providers are unavailable, saved a rule-based analysis", zap.String("contract_id", contract.ID), zap.Error(err))
+	return nil, err
+}
+
+// ListStages returns the recorded stages of a contract's analysis in run order.
+func (o *Orchestrator) ListStages(ctx context.Context, contractID string) ([]*models.AnalysisStage, error) {
+	if _, err := o.contractRepo.GetByID(contractID); err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrContractNotFound
+		}
+		return nil, fmt.Errorf("failed to load contract: %w", err)
+	}
+	records, err := o.stageRepo.ListByContractID(contractID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load analysis stages: %w", err)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return stageOrder[records[i].Stage] < stageOrder[records[j].Stage]
+	})
+	return records, nil
+}
+
+// Subscribe returns the progress events of a contract's analysis: first one
+// EventStage per stage recorded so far, then the events of the analysis as it
+// runs, including the fields of stage outputs as the LLM streams them, up to
+// EventCompleted or EventFailed. The channel is closed after those, at once
+// for a contract that has been analyzed and is not being analyzed again, or
+// when the subscriber falls behind. Call the returned function to
+// unsubscribe. An analysis running in another process is followed through
+// the stage records and the contract status in the store, without its
+// fields.
+func (o *Orchestrator) Subscribe(ctx context.Context, contractID string) (<-chan Event, func(), error) {
+	var seen map[string]stageState
+	sub, err := o.progress.subscribe(contractID, func(running bool) ([]Event, error) {
+		contract, err := o.contractRepo.GetByID(contractID)
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return nil, ErrContractNotFound
+			}
+			return nil, fmt.Errorf("failed to load contract: %w", err)
+		}
+		records, err := o.ListStages(ctx, contractID)
+		if err != nil {
+			return nil, err
+		}
+		seen = make(map[string]stageState, len(records))
+		events := make([]Event, 0, len(records)+1)
+		for _, record := range records {
+			seen[record.Stage] = stageStateOf(record)
+			events = append(events, Event{Type: EventStage, Stage: record.Stage, Status: record.Status, Error: record.Error})
+		}
+		if !running && contract.Status == models.Analyzed {
+			events = append(events, Event{Type: EventCompleted})
+		}
+		return events, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	go o.follow(ctx, contractID, sub, seen)
+	return sub.ch, func() { o.progress.unsubscribe(contractID, sub) }, nil
+}
+
+// stageState is what a subscriber has been told of a stage.
+type stageState struct {
+	status   models.StageStatus
+	attempts int
+}
+
+func stageStateOf(record *models.AnalysisStage) stageState {
+	return stageState{status: record.Status, attempts: record.Attempts}
+}
+
+// follow polls the store for the progress of an analysis of the contract
+// running in another process, and delivers it to sub until the analysis
+// completes or fails, or sub unsubscribes. seen holds the stage states sub
+// has been told of.
+func (o *Orchestrator) follow(ctx context.Context, contractID string, sub *subscriber, seen map[string]stageState) {
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		subscribed, running := o.progress.following(contractID, sub)
+		if !subscribed {
+			return
+		}
+		if running {
+			continue
+		}
+
+		// The contract is read first, so that the stages it was analyzed
+		// with are reported before the end of the stream
+		contract, err := o.contractRepo.GetByID(contractID)
+		if err != nil {
+			o.logger.Warn("Failed to poll analysis progress", zap.String("contract_id", contractID), zap.Error(err))
+			continue
+		}
+		records, err := o.ListStages(ctx, contractID)
+		if err != nil {
+			o.logger.Warn("Failed to poll analysis progress", zap.String("contract_id", contractID), zap.Error(err))
+			continue
+		}
+
+		var events []Event
+		var failed *models.AnalysisStage
+		current := make(map[string]stageState, len(records))
+		for _, record := range records {
+			state := stageStateOf(record)
+			current[record.Stage] = state
+			if seen[record.Stage] == state {
+				continue
+			}
+			events = append(events, Event{Type: EventStage, Stage: record.Stage, Status: record.Status, Error: record.Error})
+			if record.Status == models.StageFailed {
+				failed = record
+			}
+		}
+		seen = current
+
+		end := true
+		switch {
+		case contract.Status == models.Analyzed:
+			events = append(events, Event{Type: EventCompleted})
+		case failed != nil:
+			events = append(events, Event{Type: EventFailed, Error: fmt.Sprintf("%s stage failed: %s", failed.Stage, failed.Error)})
+		default:
+			end = false
+		}
+		if !o.progress.deliver(contractID, sub, events, end) {
+			return
+		}
+	}
+}
+
+// stageOrder is the position of each stage in a run.
+var stageOrder = map[string]int{
+	StageExtraction:     0,
+	StageValidation:     1,
+	StageClassification: 2,
+	StageAnalysis:       3,
+	StageSequencing:     4,
+	StageRisk:           5,
+	StageCompliance:     6,
+}
+
+// Reset discards the stage records of a contract's earlier analysis, so that
+// the next run calls every stage again with the current prompts, models and
+// settings instead of reusing their outputs. It has the signature of
+// jobs.PrepareFunc, to be called when a new analysis job is queued.
+func (o *Orchestrator) Reset(ctx context.Context, contractID string) error {
+	if err := o.stageRepo.DeleteByContractID(contractID); err != nil {
+		return fmt.Errorf("failed to discard analysis stages: %w", err)
+	}
+	return nil
+}
+
+// HandleJob analyses the contract of an analysis job. It has the signature of
+// jobs.HandlerFunc.
+func (o *Orchestrator) HandleJob(ctx context.Context, job *models.Job) (interface{}, error) {
+	contract, err := o.Run(ctx, job.ContractID)
+	if errors.Is(err, ErrContractNotFound) {
+		return nil, jobs.Permanent(err)
+	}
+	return contract, err
+}
+
+// stageRun holds the stage records of one contract during a run.
+type stageRun struct {
+	contractID string
+	records    map[string]*models.AnalysisStage
+}
+
+// promptVersions lists the prompt templates used by the stages of the run, or
+// when validation was reused from the upload, by that validation.
+func (r *stageRun) promptVersions(validation *models.ValidationResult) []string {
+	seen := make(map[string]bool)
+	if validation != nil && validation.PromptVersion != "" {
+		seen[validation.PromptVersion] = true
+	}
+	for _, record := range r.records {
+		for _, ref := range record.PromptVersions {
+			seen[ref] = true
+		}
+	}
+	versions := make([]string, 0, len(seen))
+	for ref := range seen {
+		versions = append(versions, ref)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// runStage runs fn unless the stage already succeeded, and decodes the stage
+// output into out either way. The outcome of fn is recorded before returning,
+// together with the prompt templates fn rendered with its context. The LLM
+// calls fn makes with its context are accounted to the stage, and streamed to
+// the subscribers of the contract when there are any.
+func (o *Orchestrator) runStage(ctx context.Context, run *stageRun, name string, out interface{}, fn func(ctx context.Context) (interface{}, error)) error {
+	record := run.records[name]
+	if record != nil && record.Status == models.StageSucceeded {
+		if err := json.Unmarshal(record.Output, out); err == nil {
+			o.progress.publish(run.contractID, Event{Type: EventStage, Stage: name, Status: models.StageSucceeded})
+			return nil
+		}
+		// An unreadable output is treated like a missing one and recomputed
+	}
+	if record == nil {
+		record = &models.AnalysisStage{ID: uuid.New().String(), ContractID: run.contractID, Stage: name}
+		run.records[name] = record
+	}
+
+	startedAt := o.now()
+	record.Status = models.StageRunning
+	record.Attempts++
+	record.Error = ""
+	record.StartedAt = &startedAt
+	record.FinishedAt = nil
+	if err := o.stageRepo.Save(record); err != nil {
+		return fmt.Errorf("failed to record %s stage: %w", name, err)
+	}
+	o.progress.publish(run.contractID, Event{Type: EventStage, Stage: name, Status: models.StageRunning})
+
+	ctx, trace := prompts.Track(usage.WithStage(ctx, name))
+	if o.progress.watched(run.contractID) {
+		ctx = llm.WithStream(ctx, o.streamFields(run.contractID, name))
+	}
+	result, err := fn(ctx)
+	record.PromptVersions = trace.Refs()
+	if err == nil {
+		record.Output, err = json.Marshal(result)
+		if err == nil {
+			err = json.Unmarshal(record.Output, out)
+		}
+	}
+
+	finishedAt := o.now()
+	record.FinishedAt = &finishedAt
+	if err != nil {
+		record.Status = models.StageFailed
+		record.Error = err.Error()
+		record.Output = nil
+	} else {
+		record.Status = models.StageSucceeded
+	}
+	o.progress.publish(run.contractID, Event{Type: EventStage, Stage: name, Status: record.Status, Error: record.Error})
+	if saveErr := o.stageRepo.Save(record); saveErr != nil {
+		o.logger.Error("Failed to record analysis stage", zap.String("contract_id", run.contractID), zap.String("stage", name), zap.Error(saveErr))
+		if err == nil {
+			return fmt.Errorf("failed to record %s stage: %w", name, saveErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s stage failed: %w", name, err)
+	}
+	return nil
+}
+
+// streamFields returns a stream handler that publishes the fields of a
+// stage's output as each of them has been streamed in full. Each value of a
+// field is published once; a field gets another value when a response is
+// repaired or retried, or from another chunk of a long contract.
+func (o *Orchestrator) streamFields(contractID, stage string) func(llm.ChatDelta) {
+	var mu sync.Mutex
+	published := make(map[string]bool)
+	return func(delta llm.ChatDelta) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, field := range llm.PartialFields(delta.Text) {
+			key := field.Path + "=" + string(field.Value)
+			if published[key] {
+				continue
+			}
+			published[key] = true
+			o.progress.publish(contractID, Event{Type: EventField, Stage: stage, Field: field.Path, Value: field.Value})
+		}
+	}
+}
+
+// ensureDocument returns the extracted document of a contract, extracting it
+// from the stored file if it has not been stored yet.
+func (o *Orchestrator) ensureDocument(ctx context.Context, contract *models.Contract) (*models.ExtractedDocument, error) {
+	doc, err := o.documentRepo.GetByContractID(contract.ID)
+	if err == nil {
+		return doc, nil
+	}
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, fmt.Errorf("failed to load extracted document: %w", err)
+	}
+
+	info, err := o.storage.Stat(contract.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat contract file: %w", err)
+	}
+	mimeType, _, err := mime.ParseMediaType(info.ContentType)
+	if err != nil {
+		return nil, fmt.Errorf("unknown content type %q for contract file: %w", info.ContentType, err)
+	}
+	file, err := o.storage.Open(contract.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open contract file: %w", err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contract file: %w", err)
+	}
+
+	doc, err = o.extractionService.Extract(ctx, content, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract document text: %w", err)
+	}
+	doc.ID = uuid.New().String()
+	doc.ContractID = contract.ID
+	for _, page := range doc.Pages {
+		page.ID = uuid.New().String()
+		page.DocumentID = doc.ID
+	}
+	if err := o.documentRepo.Create(doc); err != nil {
+		return nil, fmt.Errorf("failed to store extracted document: %w", err)
+	}
+	return doc, nil
+}
+
+// summaryQuoteFields maps the keys of ContractAnalysis.SourceQuotes to the
+// ContractSummary fields they cite.
+var summaryQuoteFields = map[string]string{
+	"buyer":       "buyer_name",
+	"seller":      "seller_name",
+	"total_value": "total_value",
+	"currency":    "currency",
+}
+
+// applyAnalysis maps the stage outputs onto the contract and marks it
+// analyzed. The passages quoted by the model are located in doc; fields whose
+// quote is not there are listed in UnverifiedFields. The milestones are
+// reconciled with the total value, with the discrepancies in Findings. The
+// disputed fields of an ensemble analysis, and those that contradict the
+// rule-based extraction of the text, are listed in DisputedFields.
+func applyAnalysis(contract *models.Contract, doc *models.ExtractedDocument, extracted *rules.Extraction, industry string, analysis *models.ContractAnalysis, sequenced []models.SequencedMilestone, risks *models.AnalysisRiskAssessment, compliance *models.AnalysisComplianceReport) {
+	citer := extraction.NewCiter(doc)
+	contract.Summary = mapSummary(citer, analysis, compliance.Jurisdiction)
+	contract.Milestones = mapMilestones(contract.ID, citer, extracted, analysis, sequenced)
+	contract.Findings = reconcileMilestones(contract.Summary, contract.Milestones)
+	contract.Risks = mapRisks(contract.ID, industry, citer, analysis, risks)
+	contract.UnverifiedFields = unverifiedFields(contract)
+	contract.Confidence = analysis.Confidence
+	contract.DisputedFields = disputedFields(contract, analysis, mergeUnique(analysis.DisputedFields, crossCheck(analysis, extracted)))
+
+	missing := mergeUnique(compliance.MissingClauses, risks.MissingClauses)
+	contract.Compliance = &models.ComplianceReport{
+		MissingClauses: missing,
+		Suggestions:    mergeUnique(compliance.Recommendations, risks.Suggestions),
+		IsCompliant:    strings.EqualFold(compliance.ComplianceLevel, "full") && len(missing) == 0,
+		Report: fmt.Sprintf("Compliance level: %s. Risk level: %s. Compliance score: %.2f.",
+			compliance.ComplianceLevel, compliance.RiskLevel, risks.ComplianceScore),
+	}
+	contract.Status = models.Analyzed
+}
+
+// applyExtraction maps the analysis the rules extract from the text onto the
+// contract and marks it degraded. It has no risks or compliance report, and
+// its milestones are in the order they appear in the text.
+func applyExtraction(contract *models.Contract, doc *models.ExtractedDocument, extracted *rules.Extraction) {
+	citer := extraction.NewCiter(doc)
+	analysis := extracted.Analysis()
+	contract.Summary = mapSummary(citer, analysis, "")
+	contract.Milestones = mapMilestones(contract.ID, citer, extracted, analysis, nil)
+	contract.Findings = reconcileMilestones(contract.Summary, contract.Milestones)
+	contract.Risks = nil
+	contract.Compliance = &models.ComplianceReport{}
+	contract.UnverifiedFields = unverifiedFields(contract)
+	contract.Confidence = 0
+	contract.DisputedFields = nil
+	contract.Status = models.Degraded
+}
+
+// mapSummary builds the contract summary from the analysis, citing the
+// passages the model quoted for its fields.
+func mapSummary(citer *extraction.Citer, analysis *models.ContractAnalysis, jurisdiction string) *models.ContractSummary {
+	summary := &models.ContractSummary{
+		BuyerName:    analysis.Buyer,
+		SellerName:   analysis.Seller,
+		TotalValue:   analysis.TotalValue,
+		Currency:     analysis.Currency,
+		Jurisdiction: jurisdiction,
+	}
+	for key, quote := range analysis.SourceQuotes {
+		field, ok := summaryQuoteFields[key]
+		if !ok {
+			continue
+		}
+		if span := citer.Cite(quote); span != nil {
+			if summary.Sources == nil {
+				summary.Sources = make(map[string]*models.SourceSpan)
+			}
+			summary.Sources[field] = span
+		}
+	}
+	return summary
+}
+
+// mapMilestones builds the contract milestones from the sequenced milestones,
+// taking amounts from the analysis; missing ones are derived when they are
+// reconciled. Without a sequence the analysed milestones are used in the
+// order they were found. The trigger of a milestone is the first date stated
+// in its cited passage.
+func mapMilestones(contractID string, citer *extraction.Citer, extracted *rules.Extraction, analysis *models.ContractAnalysis, sequenced []models.SequencedMilestone) []*models.Milestone {
+	amounts := make(map[string]decimal.Decimal, len(analysis.Milestones))
+	quotes := make(map[string]string, len(analysis.Milestones))
+	for _, m := range analysis.Milestones {
+		if !m.Amount.IsZero() {
+			amounts[normalizeDescription(m.Description)] = m.Amount
+		}
+		if m.SourceQuote != "" {
+			quotes[normalizeDescription(m.Description)] = m.SourceQuote
+		}
+	}
+
+	if len(sequenced) == 0 {
+		for i, m := range analysis.Milestones {
+			sequenced = append(sequenced, models.SequencedMilestone{
+				Description:   m.Description,
+				Percentage:    m.Percentage,
+				SequenceOrder: i + 1,
+			})
+		}
+	}
+
+	// The sequencer refers to milestones by its own IDs; translate them to ours
+	ids := make(map[string]string, len(sequenced))
+	for _, m := range sequenced {
+		if m.ID != "" {
+			ids[m.ID] = uuid.New().String()
+		}
+	}
+
+	milestones := make([]*models.Milestone, 0, len(sequenced))
+	for _, m := range sequenced {
+		id, ok := ids[m.ID]
+		if !ok {
+			id = uuid.New().String()
+		}
+		amount := amounts[normalizeDescription(m.Description)]
+		var dependencies []string
+		for _, dep := range m.Dependencies {
+			if mapped, ok := ids[dep]; ok {
+				dependencies = append(dependencies, mapped)
+			}
+		}
+		source := citer.Cite(quotes[normalizeDescription(m.Description)])
+		var trigger string
+		if source != nil && source.Verified {
+			if date, ok := extracted.DateIn(source.Start, source.End); ok {
+				trigger = date.Text
+			}
+		}
+		milestones = append(milestones, &models.Milestone{
+			ID:            id,
+			ContractID:    contractID,
+			Description:   m.Description,
+			Amount:        amount,
+			Percentage:    m.Percentage,
+			Trigger:       trigger,
+			SequenceOrder: m.SequenceOrder,
+			Dependencies:  dependencies,
+			Category:      m.Category,
+			Verification:  models.Manual,
+			Source:        source,
+		})
+	}
+	return milestones
+}
+
+// mapRisks builds the contract risks from the risk assessment, falling back
+// to the risk factors found during analysis.
+func mapRisks(contractID, industry string, citer *extraction.Citer, analysis *models.ContractAnalysis, assessment *models.AnalysisRiskAssessment) []*models.RiskAssessment {
+	var risks []*models.RiskAssessment
+	for _, r := range assessment.Risks {
+		risks = append(risks, &models.RiskAssessment{
+			ID:             uuid.New().String(),
+			ContractID:     contractID,
+			Type:           r.Type,
+			Severity:       parseSeverity(r.Severity),
+			Description:    r.Description,
+			Recommendation: r.Recommendation,
+			IndustryRef:    industry,
+			Source:         citer.Cite(r.SourceQuote),
+		})
+	}
+	if len(risks) > 0 {
+		return risks
+	}
+	for _, r := range analysis.RiskFactors {
+		risks = append(risks, &models.RiskAssessment{
+			ID:          uuid.New().String(),
+			ContractID:  contractID,
+			Type:        r.Type,
+			Severity:    parseSeverity(r.Severity),
+			Description: r.Description,
+			IndustryRef: industry,
+			Source:      citer.Cite(r.SourceQuote),
+		})
+	}
+	return risks
+}
+
+// unverifiedFields lists the fields of an analyzed contract whose cited
+// passage was not found in the document.
+func unverifiedFields(contract *models.Contract) []string {
+	var fields []string
+	for field, span := range contract.Summary.Sources {
+		if !span.Verified {
+			fields = append(fields, "summary."+field)
+		}
+	}
+	sort.Strings(fields)
+	for i, m := range contract.Milestones {
+		if m.Source != nil && !m.Source.Verified {
+			fields = append(fields, fmt.Sprintf("milestones[%d]", i))
+		}
+	}
+	for i, r := range contract.Risks {
+		if r.Source != nil && !r.Source.Verified {
+			fields = append(fields, fmt.Sprintf("risks[%d]", i))
+		}
+	}
+	return fields
+}
+
+// disputedFields translates disputed fields of an analysis to the fields of
+// the contract, e.g. "total_value" to "summary.total_value". Milestones are
+// matched by description, as they may have been sequenced in another order.
+func disputedFields(contract *models.Contract, analysis *models.ContractAnalysis, disputed []string) []string {
+	positions := make(map[string]int, len(contract.Milestones))
+	for i, m := range contract.Milestones {
+		positions[normalizeDescription(m.Description)] = i
+	}
+	var fields []string
+	for _, field := range disputed {
+		if summaryField, ok := summaryQuoteFields[field]; ok {
+			fields = append(fields, "summary."+summaryField)
+			continue
+		}
+		var index int
+		var property string
+		if _, err := fmt.Sscanf(field, "milestones[%d].%s", &index, &property); err == nil {
+			if index < len(analysis.Milestones) {
+				if i, ok := positions[normalizeDescription(analysis.Milestones[index].Description)]; ok {
+					fields = append(fields, fmt.Sprintf("milestones[%d].%s", i, property))
+					continue
+				}
+			}
+			field = "milestones"
+		}
+		fields = append(fields, field)
+	}
+	return mergeUnique(fields)
+}
+
+// hasValidation reports whether a contract has been validated. Embedded
+// structs are loaded as zero values rather than nil when the columns are empty.
+func hasValidation(v *models.ValidationResult) bool {
+	return v != nil && (v.IsValidContract || v.Reason != "" || v.ContractType != "" || v.Confidence != 0)
+}
+
+func parseSeverity(s string) models.Severity {
+	switch severity := models.Severity(strings.ToLower(strings.TrimSpace(s))); severity {
+	case models.Low, models.Medium, models.High, models.Critical:
+		return severity
+	default:
+		return models.Medium
+	}
+}
+
+// jurisdiction returns the governing law the contract states, so that its
+// prompt overrides apply, or defaultJurisdiction.
+func jurisdiction(extracted *rules.Extraction) string {
+	if law, ok := extracted.GoverningLaw(); ok {
+		return law.Name
+	}
+	return defaultJurisdiction
+}
+
+func normalizeDescription(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// mergeUnique concatenates lists, dropping empty and repeated entries.
+func mergeUnique(lists ...[]string) []string {
+	seen := map[string]bool{}
+	var merged []string
+	for _, list := range lists {
+		for _, s := range list {
+			key := normalizeDescription(s)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, s)
+		}
+	}
+	return merged
+}
+
+// formatStandards renders knowledge entries as reference text for the risk prompt.
+func formatStandards(entries []*models.KnowledgeEntry) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		if entry.Type != "" {
+			fmt.Fprintf(&b, "[%s] ", entry.Type)
+		}
+		b.WriteString(entry.Content)
+	}
+	return b.String()
+}
@@ -0,0 +1,590 @@
+package analysis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/pkg/external"
+	"contract-analysis-service/internal/pkg/storage"
+	"contract-analysis-service/internal/repositories"
+	sqliterepo "contract-analysis-service/internal/repositories/sqlite"
+	"contract-analysis-service/internal/services/analysis"
+	extraction_mocks "contract-analysis-service/internal/services/extraction/mocks"
+	"contract-analysis-service/internal/services/jobs"
+	"contract-analysis-service/internal/services/llm"
+	"contract-analysis-service/internal/services/llm/prompts"
+	validation_mocks "contract-analysis-service/internal/services/validation/mocks"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeStages implements every LLM stage and counts the calls it receives.
+type fakeStages struct {
+	calls     map[string]int
+	standards string
+	failRisk  bool
+	// unavailable fails the classification as if every provider was down
+	unavailable bool
+	// prompts, if set, renders the risk prompt like the real assessor
+	prompts *prompts.Registry
+	// llm, if set, is called by the analysis like the real analyzer
+	llm llm.Service
+	// disputed, if set, are the fields the runs of the analysis disagree on
+	disputed []string
+}
+
+// eventsClient streams its events to every request.
+type eventsClient []string
+
+func (c eventsClient) ExecuteRequest(ctx context.Context, req *external.Request) (*external.Response, error) {
+	for _, event := range c {
+		if err := req.OnEvent([]byte(event)); err != nil {
+			return nil, err
+		}
+	}
+	return &external.Response{StatusCode: 200, Body: []byte(strings.Join(c, "\n"))}, nil
+}
+
+func (f *fakeStages) ClassifyIndustry(ctx context.Context, text string) (string, error) {
+	f.calls["classify"]++
+	if f.unavailable {
+		return "", fmt.Errorf("%w for classification: overloaded", llm.ErrProvidersUnavailable)
+	}
+	return "Technology", nil
+}
+
+func (f *fakeStages) QueryByIndustry(ctx context.Context, industry string) ([]*models.KnowledgeEntry, error) {
+	return []*models.KnowledgeEntry{
+		{Type: "sla", Content: "Uptime of 99.9%"},
+		{Content: "Liability capped at fees paid"},
+	}, nil
+}
+
+func (f *fakeStages) AnalyzeContract(ctx context.Context, provider, text string) (*models.ContractAnalysis, error) {
+	f.calls["analyze"]++
+	if f.llm != nil {
+		if _, err := f.llm.Chat(ctx, provider, llm.NewChatRequest("", text)); err != nil {
+			return nil, err
+		}
+	}
+	analysis := &models.ContractAnalysis{
+		Buyer:      "Acme",
+		Seller:     "Globex",
+		TotalValue: decimal.NewFromInt(1000),
+		Currency:   "USD",
+		Milestones: []models.AnalysisMilestone{
+			{Description: "Delivery", Amount: decimal.NewFromInt(400), Percentage: 40, SourceQuote: "40% is due on delivery"},
+			{Description: "Acceptance", Percentage: 60},
+		},
+		SourceQuotes: map[string]string{"buyer": "Acme (the “Buyer”)", "total_value": "a total of USD 1,000"},
+		Confidence:   1,
+	}
+	if f.disputed != nil {
+		analysis.Confidence = 0.5
+		analysis.DisputedFields = f.disputed
+	}
+	return analysis, nil
+}
+
+func (f *fakeStages) SequenceMilestones(ctx context.Context, provider string, milestones []models.AnalysisMilestone) ([]models.SequencedMilestone, error) {
+	f.calls["sequence"]++
+	return []models.SequencedMilestone{
+		{ID: "m1", Description: "Delivery", SequenceOrder: 1, Percentage: 40, Category: "delivery"},
+		{ID: "m2", Description: "Acceptance", SequenceOrder: 2, Percentage: 60, Dependencies: []string{"m1", "unknown"}},
+	}, nil
+}
+
+func (f *fakeStages) AssessRisks(ctx context.Context, provider, text, standards string) (*models.AnalysisRiskAssessment, error) {
+	f.calls["risk"]++
+	f.standards = standards
+	if f.prompts != nil {
+		if _, err := f.prompts.Render(ctx, prompts.RiskAssessment, nil); err != nil {
+			return nil, err
+		}
+	}
+	if f.failRisk {
+		return nil, errors.New("rate limited")
+	}
+	return &models.AnalysisRiskAssessment{
+		MissingClauses:  []string{"Force majeure"},
+		Risks:           []models.AnalysisIndividualRisk{{Type: "payment", Severity: "HIGH", Description: "No late fees", SourceQuote: "no late fees apply"}, {Type: "ip", Severity: "unclear"}},
+		ComplianceScore: 0.8,
+		Suggestions:     []string{"Add late fees"},
+	}, nil
+}
+
+func (f *fakeStages) CheckCompliance(ctx context.Context, provider, text, jurisdiction string) (*models.AnalysisComplianceReport, error) {
+	f.calls["compliance"]++
+	return &models.AnalysisComplianceReport{
+		Jurisdiction:    jurisdiction,
+		MissingClauses:  []string{"force majeure", "Governing law"},
+		ComplianceLevel: "partial",
+		Recommendations: []string{"Add late fees", "State the governing law"},
+		RiskLevel:       "medium",
+	}, nil
+}
+
+type fixture struct {
+	contractRepo repositories.ContractRepository
+	documentRepo repositories.ExtractedDocumentRepository
+	stageRepo    repositories.AnalysisStageRepository
+	storage      storage.FileStorage
+	extraction   *extraction_mocks.Service
+	validation   *validation_mocks.Service
+	stages       *fakeStages
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "analysis.db")), &gorm.Config{})
+	require.NoError(t, err)
+	fileStorage, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	return &fixture{
+		contractRepo: sqliterepo.NewContractRepository(db),
+		documentRepo: sqliterepo.NewExtractedDocumentRepository(db),
+		stageRepo:    sqliterepo.NewAnalysisStageRepository(db),
+		storage:      fileStorage,
+		extraction:   new(extraction_mocks.Service),
+		validation:   new(validation_mocks.Service),
+		stages:       &fakeStages{calls: map[string]int{}},
+	}
+}
+
+func (f *fixture) orchestrator() *analysis.Orchestrator {
+	return analysis.NewOrchestrator(f.contractRepo, f.documentRepo, f.stageRepo, f.storage, f.extraction, f.validation,
+		analysis.Stages{Classifier: f.stages, Analyzer: f.stages, Sequencer: f.stages, RiskAssessor: f.stages, Compliance: f.stages},
+		zap.NewNop())
+}
+
+// addContract stores a validated contract and, if text is not empty, its extracted document.
+func (f *fixture) addContract(t *testing.T, validation *models.ValidationResult, text string) *models.Contract {
+	t.Helper()
+	filePath, err := f.storage.Save(strings.NewReader("contract file"), "contract.txt")
+	require.NoError(t, err)
+	contract := &models.Contract{ID: "contract-1", FilePath: filePath, Status: models.Validated, Validation: validation}
+	require.NoError(t, f.contractRepo.Create(contract))
+	if text != "" {
+		require.NoError(t, f.documentRepo.Create(&models.ExtractedDocument{ID: "doc-1", ContractID: contract.ID, Text: text}))
+	}
+	return contract
+}
+
+func TestOrchestrator_Run_PopulatesContract(t *testing.T) {
+	f := newFixture(t)
+	f.addContract(t, &models.ValidationResult{IsValidContract: true, ContractType: "Services"}, "contract text")
+
+	contract, err := f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.Analyzed, contract.Status)
+	assert.Equal(t, "[sla] Uptime of 99.9%\n\nLiability capped at fees paid", f.stages.standards)
+
+	stored, err := f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.Analyzed, stored.Status)
+	assert.Equal(t, "Services", stored.ContractType)
+
+	require.NotNil(t, stored.Summary)
+	assert.Equal(t, "Acme", stored.Summary.BuyerName)
+	assert.Equal(t, "Globex", stored.Summary.SellerName)
+	assert.True(t, decimal.NewFromInt(1000).Equal(stored.Summary.TotalValue))
+	assert.Equal(t, "international", stored.Summary.Jurisdiction)
+
+	require.Len(t, stored.Milestones, 2)
+	delivery, acceptance := stored.Milestones[0], stored.Milestones[1]
+	assert.Equal(t, "Delivery", delivery.Description)
+	assert.True(t, decimal.NewFromInt(400).Equal(delivery.Amount), "amount should come from the analysis")
+	assert.True(t, decimal.NewFromInt(600).Equal(acceptance.Amount), "amount should be derived from the percentage")
+	assert.Equal(t, []string{delivery.ID}, acceptance.Dependencies)
+	assert.Empty(t, stored.Findings)
+
+	require.Len(t, stored.Risks, 2)
+	severities := []models.Severity{stored.Risks[0].Severity, stored.Risks[1].Severity}
+	assert.ElementsMatch(t, []models.Severity{models.High, models.Medium}, severities)
+	assert.Equal(t, "Technology", stored.Risks[0].IndustryRef)
+
+	require.NotNil(t, stored.Compliance)
+	assert.Equal(t, []string{"force majeure", "Governing law"}, stored.Compliance.MissingClauses)
+	assert.Equal(t, []string{"Add late fees", "State the governing law"}, stored.Compliance.Suggestions)
+	assert.False(t, stored.Compliance.IsCompliant)
+
+	stages, err := f.orchestrator().ListStages(context.Background(), "contract-1")
+	require.NoError(t, err)
+	require.Len(t, stages, 7)
+	assert.Equal(t, analysis.StageExtraction, stages[0].Stage)
+	assert.Equal(t, analysis.StageCompliance, stages[6].Stage)
+	for _, stage := range stages {
+		assert.Equal(t, models.StageSucceeded, stage.Status, stage.Stage)
+	}
+	f.validation.AssertNotCalled(t, "ValidateContract", mock.Anything, mock.Anything)
+}
+
+func TestOrchestrator_Run_ChecksComplianceWithStatedGoverningLaw(t *testing.T) {
+	f := newFixture(t)
+	f.addContract(t, &models.ValidationResult{IsValidContract: true}, "This Agreement is governed by the laws of the State of New York.")
+
+	_, err := f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+
+	stored, err := f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, "New York", stored.Summary.Jurisdiction)
+}
+
+func TestOrchestrator_Run_CitesSources(t *testing.T) {
+	f := newFixture(t)
+	text := "This agreement is made between Acme (the \"Buyer\") and Globex.\f2. Payment\nThe Buyer pays a total of\nUSD 1,000. 40% is due on  delivery."
+	f.addContract(t, &models.ValidationResult{IsValidContract: true}, text)
+
+	_, err := f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+
+	stored, err := f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+
+	buyer := stored.Summary.Sources["buyer_name"]
+	require.NotNil(t, buyer)
+	assert.True(t, buyer.Verified)
+	assert.Equal(t, 1, buyer.Page)
+	assert.Equal(t, `Acme (the "Buyer")`, text[buyer.Start:buyer.End])
+
+	total := stored.Summary.Sources["total_value"]
+	require.NotNil(t, total)
+	assert.Equal(t, 2, total.Page)
+	assert.Equal(t, "a total of\nUSD 1,000", text[total.Start:total.End])
+
+	require.NotNil(t, stored.Milestones[0].Source)
+	assert.Equal(t, "40% is due on  delivery", text[stored.Milestones[0].Source.Start:stored.Milestones[0].Source.End])
+	assert.Nil(t, stored.Milestones[1].Source)
+
+	// The payment risk quotes a passage the contract does not contain
+	assert.Equal(t, []string{"risks[0]"}, stored.UnverifiedFields)
+	for _, risk := range stored.Risks {
+		if risk.Type == "payment" {
+			require.NotNil(t, risk.Source)
+			assert.False(t, risk.Source.Verified)
+			assert.Equal(t, "no late fees apply", risk.Source.Quote)
+		} else {
+			assert.Nil(t, risk.Source)
+		}
+	}
+}
+
+func TestOrchestrator_Run_RecordsPromptVersions(t *testing.T) {
+	f := newFixture(t)
+	f.addContract(t, &models.ValidationResult{IsValidContract: true, PromptVersion: "contract_validation@v1"}, "contract text")
+	registry, err := prompts.Load(fstest.MapFS{
+		"risk_assessment/v1.tmpl":                     {Data: []byte("Assess the risks")},
+		"risk_assessment/v2.tmpl":                     {Data: []byte("Assess the risks")},
+		"risk_assessment/v2.industry-technology.tmpl": {Data: []byte("Assess the risks of a technology contract")},
+	})
+	require.NoError(t, err)
+	f.stages.prompts = registry
+
+	contract, err := f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+	// The classified industry selects the override
+	assert.Equal(t, []string{"contract_validation@v1", "risk_assessment@v2.industry-technology"}, contract.PromptVersions)
+
+	stored, err := f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, contract.PromptVersions, stored.PromptVersions)
+
+	stages, err := f.orchestrator().ListStages(context.Background(), "contract-1")
+	require.NoError(t, err)
+	require.Len(t, stages, 7)
+	assert.Equal(t, analysis.StageRisk, stages[5].Stage)
+	assert.Equal(t, []string{"risk_assessment@v2.industry-technology"}, stages[5].PromptVersions)
+	assert.Empty(t, stages[4].PromptVersions)
+}
+
+func TestOrchestrator_Run_RecordsDisputedFields(t *testing.T) {
+	f := newFixture(t)
+	f.addContract(t, &models.ValidationResult{IsValidContract: true}, "contract text")
+	f.stages.disputed = []string{"total_value", "milestones", "milestones[1].percentage", "milestones[1].amount", "milestones[2].amount"}
+
+	_, err := f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+
+	stored, err := f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, stored.Confidence)
+	// Fields are named as on the contract; a milestone the analysis does not
+	// have is reported as the milestones
+	assert.Equal(t, []string{"summary.total_value", "milestones", "milestones[1].percentage", "milestones[1].amount"}, stored.DisputedFields)
+}
+
+func TestOrchestrator_Run_CrossChecksRules(t *testing.T) {
+	f := newFixture(t)
+	text := "Acme (the \"Buyer\") and Initech (the \"Seller\") agree.\nThe Buyer pays a total of USD 2,000. 40% is due on delivery."
+	f.addContract(t, &models.ValidationResult{IsValidContract: true}, text)
+
+	_, err := f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+
+	stored, err := f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+	// The analysis names another seller and total than the text defines
+	assert.Equal(t, []string{"summary.seller_name", "summary.total_value"}, stored.DisputedFields)
+	assert.Equal(t, 1.0, stored.Confidence)
+}
+
+func TestOrchestrator_Run_SingleAnalysisRunIsFullyConfident(t *testing.T) {
+	f := newFixture(t)
+	f.addContract(t, &models.ValidationResult{IsValidContract: true}, "contract text")
+	service := new(llm.MockLLMService)
+	service.On("Chat", mock.Anything, llm.TaskAnalysis, mock.Anything).Return(&llm.ChatResponse{
+		Content: `{"buyer":"Acme","seller":"Globex","total_value":1000,"currency":"USD","milestones":[],"risk_factors":[]}`,
+	}, nil)
+
+	orchestrator := analysis.NewOrchestrator(f.contractRepo, f.documentRepo, f.stageRepo, f.storage, f.extraction, f.validation,
+		analysis.Stages{Classifier: f.stages, Analyzer: llm.NewContractAnalyzer(service, llm.NewPromptEngine()), Sequencer: f.stages, RiskAssessor: f.stages, Compliance: f.stages},
+		zap.NewNop())
+	_, err := orchestrator.Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+
+	stored, err := f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.Analyzed, stored.Status)
+	assert.Equal(t, 1.0, stored.Confidence)
+	assert.Empty(t, stored.DisputedFields)
+	service.AssertNumberOfCalls(t, "Chat", 1)
+}
+
+func TestOrchestrator_Run_DegradesWhenProvidersAreDown(t *testing.T) {
+	f := newFixture(t)
+	text := "Acme Corp (the \"Buyer\") and Globex Ltd (the \"Supplier\") agree.\n\n" +
+		"The Buyer pays a total of EUR 5.000. 20% is payable on signing; 70% is payable within 30 days of delivery."
+	f.addContract(t, &models.ValidationResult{IsValidContract: true}, text)
+	f.stages.unavailable = true
+
+	_, err := f.orchestrator().Run(context.Background(), "contract-1")
+	require.ErrorIs(t, err, llm.ErrProvidersUnavailable)
+
+	stored, err := f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.Degraded, stored.Status)
+	assert.Equal(t, "Acme Corp", stored.Summary.BuyerName)
+	assert.Equal(t, "Globex Ltd", stored.Summary.SellerName)
+	assert.True(t, decimal.NewFromInt(5000).Equal(stored.Summary.TotalValue))
+	assert.Equal(t, "EUR", stored.Summary.Currency)
+	assert.True(t, stored.Summary.Sources["total_value"].Verified)
+	require.Len(t, stored.Milestones, 2)
+	assert.Equal(t, 70.0, stored.Milestones[1].Percentage)
+	assert.True(t, decimal.NewFromInt(3500).Equal(stored.Milestones[1].Amount))
+	assert.Equal(t, "within 30 days of delivery", stored.Milestones[1].Trigger)
+	// Discrepancies in the text are recorded, not fixed
+	require.Len(t, stored.Findings, 1)
+	assert.Equal(t, models.FindingPercentageSum, stored.Findings[0].Code)
+	assert.Empty(t, stored.Risks)
+	assert.Equal(t, 0, f.stages.calls["analyze"])
+
+	// Once a provider is back the analysis is completed
+	f.stages.unavailable = false
+	_, err = f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+	stored, err = f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.Analyzed, stored.Status)
+	assert.Equal(t, "Acme", stored.Summary.BuyerName)
+	assert.Len(t, stored.Risks, 2)
+
+	// A full analysis is not replaced by a degraded one
+	f.stages.unavailable = true
+	require.NoError(t, f.stageRepo.DeleteByContractID("contract-1"))
+	_, err = f.orchestrator().Run(context.Background(), "contract-1")
+	require.Error(t, err)
+	stored, err = f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.Analyzed, stored.Status)
+}
+
+func TestOrchestrator_Subscribe(t *testing.T) {
+	f := newFixture(t)
+	f.addContract(t, &models.ValidationResult{IsValidContract: true}, "contract text")
+	f.stages.llm = llm.NewLLMService(zap.NewNop())
+	f.stages.llm.AddClient(analysis.StageAnalysis, eventsClient{
+		`{"choices":[{"delta":{"content":"{\"buyer\": \"Acme\", \"sel"}}]}`,
+		`{"choices":[{"delta":{"content":"ler\": \"Globex\"}"},"finish_reason":"stop"}]}`,
+	})
+	orchestrator := f.orchestrator()
+
+	_, _, err := orchestrator.Subscribe(context.Background(), "missing")
+	assert.ErrorIs(t, err, analysis.ErrContractNotFound)
+
+	events, unsubscribe, err := orchestrator.Subscribe(context.Background(), "contract-1")
+	require.NoError(t, err)
+	defer unsubscribe()
+	_, err = orchestrator.Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+
+	var received []analysis.Event
+	for event := range events {
+		received = append(received, event)
+	}
+	require.NotEmpty(t, received)
+	assert.Equal(t, analysis.Event{Type: analysis.EventCompleted}, received[len(received)-1])
+	assert.Contains(t, received, analysis.Event{Type: analysis.EventStage, Stage: analysis.StageAnalysis, Status: models.StageRunning})
+	assert.Contains(t, received, analysis.Event{Type: analysis.EventStage, Stage: analysis.StageAnalysis, Status: models.StageSucceeded})
+	var fields []string
+	for _, event := range received {
+		if event.Type == analysis.EventField {
+			assert.Equal(t, analysis.StageAnalysis, event.Stage)
+			fields = append(fields, event.Field+"="+string(event.Value))
+		}
+	}
+	assert.Equal(t, []string{`buyer="Acme"`, `seller="Globex"`}, fields)
+
+	// An analyzed contract gets its stages and the end of the stream at once
+	events, unsubscribe, err = orchestrator.Subscribe(context.Background(), "contract-1")
+	require.NoError(t, err)
+	defer unsubscribe()
+	received = nil
+	for event := range events {
+		received = append(received, event)
+	}
+	require.Len(t, received, 8)
+	assert.Equal(t, analysis.Event{Type: analysis.EventStage, Stage: analysis.StageExtraction, Status: models.StageSucceeded}, received[0])
+	assert.Equal(t, analysis.EventCompleted, received[7].Type)
+}
+
+func TestOrchestrator_Subscribe_FollowsOtherProcesses(t *testing.T) {
+	f := newFixture(t)
+	f.addContract(t, &models.ValidationResult{IsValidContract: true}, "contract text")
+	f.stages.failRisk = true
+	// The subscriber and the analysis are on different replicas
+	watcher := f.orchestrator()
+	watcher.SetPollInterval(5 * time.Millisecond)
+
+	receive := func(events <-chan analysis.Event) []analysis.Event {
+		t.Helper()
+		var received []analysis.Event
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return received
+				}
+				received = append(received, event)
+			case <-timeout:
+				t.Fatal("the stream did not end")
+			}
+		}
+	}
+
+	events, unsubscribe, err := watcher.Subscribe(context.Background(), "contract-1")
+	require.NoError(t, err)
+	defer unsubscribe()
+	_, err = f.orchestrator().Run(context.Background(), "contract-1")
+	require.Error(t, err)
+	received := receive(events)
+	require.NotEmpty(t, received)
+	assert.Contains(t, received, analysis.Event{Type: analysis.EventStage, Stage: analysis.StageRisk, Status: models.StageFailed, Error: "rate limited"})
+	assert.Equal(t, analysis.Event{Type: analysis.EventFailed, Error: "risk stage failed: rate limited"}, received[len(received)-1])
+
+	f.stages.failRisk = false
+	events, unsubscribe, err = watcher.Subscribe(context.Background(), "contract-1")
+	require.NoError(t, err)
+	defer unsubscribe()
+	_, err = f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+	received = receive(events)
+	assert.Contains(t, received, analysis.Event{Type: analysis.EventStage, Stage: analysis.StageCompliance, Status: models.StageSucceeded})
+	assert.Equal(t, analysis.Event{Type: analysis.EventCompleted}, received[len(received)-1])
+}
+
+func TestOrchestrator_Run_ResumesFromFailedStage(t *testing.T) {
+	f := newFixture(t)
+	f.addContract(t, &models.ValidationResult{IsValidContract: true}, "contract text")
+	f.stages.failRisk = true
+
+	_, err := f.orchestrator().Run(context.Background(), "contract-1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "risk stage failed")
+
+	stages, err := f.orchestrator().ListStages(context.Background(), "contract-1")
+	require.NoError(t, err)
+	require.Len(t, stages, 6)
+	assert.Equal(t, models.StageFailed, stages[5].Status)
+	assert.Equal(t, "rate limited", stages[5].Error)
+
+	stored, err := f.contractRepo.GetByID("contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.Validated, stored.Status)
+
+	f.stages.failRisk = false
+	contract, err := f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.Analyzed, contract.Status)
+
+	// Earlier paid calls are not repeated
+	assert.Equal(t, 1, f.stages.calls["classify"])
+	assert.Equal(t, 1, f.stages.calls["analyze"])
+	assert.Equal(t, 1, f.stages.calls["sequence"])
+	assert.Equal(t, 2, f.stages.calls["risk"])
+	assert.Equal(t, 1, f.stages.calls["compliance"])
+	assert.Equal(t, "[sla] Uptime of 99.9%\n\nLiability capped at fees paid", f.stages.standards, "standards should be restored from the classification stage")
+
+	stages, err = f.orchestrator().ListStages(context.Background(), "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, stages[5].Attempts)
+	assert.Equal(t, models.StageSucceeded, stages[5].Status)
+
+	// A new analysis calls every stage again
+	require.NoError(t, f.orchestrator().Reset(context.Background(), "contract-1"))
+	_, err = f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, f.stages.calls["analyze"])
+	assert.Equal(t, 3, f.stages.calls["risk"])
+}
+
+func TestOrchestrator_Run_ExtractsAndValidatesWhenMissing(t *testing.T) {
+	f := newFixture(t)
+	f.addContract(t, nil, "")
+	f.extraction.On("Extract", mock.Anything, []byte("contract file"), "text/plain").
+		Return(&models.ExtractedDocument{Text: "extracted text", Pages: []*models.ExtractedPage{{Number: 1, Text: "extracted text"}}}, nil)
+	f.validation.On("ValidateContract", mock.Anything, "extracted text").
+		Return(&models.ValidationResult{IsValidContract: true, ContractType: "Lease"}, nil)
+
+	contract, err := f.orchestrator().Run(context.Background(), "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Lease", contract.ContractType)
+
+	doc, err := f.documentRepo.GetByContractID("contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, "extracted text", doc.Text)
+	f.extraction.AssertExpectations(t)
+	f.validation.AssertExpectations(t)
+}
+
+func TestOrchestrator_Run_RejectsInvalidContract(t *testing.T) {
+	f := newFixture(t)
+	f.addContract(t, &models.ValidationResult{IsValidContract: false, Reason: "this is a recipe"}, "text")
+
+	_, err := f.orchestrator().Run(context.Background(), "contract-1")
+	assert.ErrorIs(t, err, analysis.ErrNotAContract)
+	assert.True(t, jobs.IsPermanent(err))
+	assert.Zero(t, f.stages.calls["analyze"])
+}
+
+func TestOrchestrator_HandleJob_UnknownContractIsPermanent(t *testing.T) {
+	f := newFixture(t)
+
+	_, err := f.orchestrator().HandleJob(context.Background(), &models.Job{ContractID: "missing"})
+	assert.ErrorIs(t, err, analysis.ErrContractNotFound)
+	assert.True(t, jobs.IsPermanent(err))
+}
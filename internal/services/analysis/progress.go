@@ -0,0 +1,230 @@
+package analysis
+
+import (
+	"encoding/json"
+	"sync"
+
+	"contract-analysis-service/internal/models"
+)
+
+// Event types of an analysis progress stream
+const (
+	// EventStage reports that a stage started, succeeded or failed.
+	EventStage = "stage"
+	// EventField reports a field of a stage's output that has been streamed
+	// in full while the stage is still running.
+	EventField = "field"
+	// EventCompleted ends the stream of an analysis that succeeded.
+	EventCompleted = "completed"
+	// EventFailed ends the stream of an analysis run that failed. The job
+	// may run it again.
+	EventFailed = "failed"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind by before
+// it is disconnected.
+const subscriberBuffer = 256
+
+// Event reports the progress of a contract's analysis.
+type Event struct {
+	Type   string             `json:"type"`
+	Stage  string             `json:"stage,omitempty"`
+	Status models.StageStatus `json:"status,omitempty"`
+	// Field is the path of a streamed field, e.g. "buyer" or "milestones[0]".
+	Field string          `json:"field,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// progress fans the events of the analyses running in this process out to
+// their subscribers.
+type progress struct {
+	mu          sync.Mutex
+	running     map[string]int
+	subscribers map[string]map[*subscriber]bool
+}
+
+// subscriber is a subscription to the events of a contract. Until its
+// snapshot has been delivered, the events published for it are held in
+// pending and its channel is nil.
+type subscriber struct {
+	ch      chan Event
+	ready   bool
+	pending []Event
+	// ended is set when the subscription ends before it is ready
+	ended bool
+}
+
+func newProgress() *progress {
+	return &progress{
+		running:     make(map[string]int),
+		subscribers: make(map[string]map[*subscriber]bool),
+	}
+}
+
+// subscribe registers a subscriber to the events of a contract. The events
+// returned by snapshot, which is told whether an analysis of the contract is
+// running in this process, are delivered first, followed by the events
+// published while it ran, so that none is missed or delivered out of order.
+// snapshot is called without holding the lock, as it reads the store. A
+// snapshot ending in EventCompleted closes the channel after it.
+func (p *progress) subscribe(contractID string, snapshot func(running bool) ([]Event, error)) (*subscriber, error) {
+	sub := &subscriber{}
+	p.mu.Lock()
+	if p.subscribers[contractID] == nil {
+		p.subscribers[contractID] = make(map[*subscriber]bool)
+	}
+	p.subscribers[contractID][sub] = true
+	running := p.running[contractID] > 0
+	p.mu.Unlock()
+
+	events, err := snapshot(running)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.drop(contractID, sub)
+		return nil, err
+	}
+	completed := len(events) > 0 && events[len(events)-1].Type == EventCompleted
+	if !completed {
+		events = append(events, sub.pending...)
+	}
+	sub.pending = nil
+	sub.ch = make(chan Event, subscriberBuffer+len(events))
+	for _, event := range events {
+		sub.ch <- event
+	}
+	if completed || sub.ended {
+		p.drop(contractID, sub)
+		close(sub.ch)
+		return sub, nil
+	}
+	sub.ready = true
+	return sub, nil
+}
+
+// unsubscribe removes a subscriber and closes its channel, unless it has
+// already been closed.
+func (p *progress) unsubscribe(contractID string, sub *subscriber) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.subscribers[contractID][sub] {
+		p.remove(contractID, sub)
+	}
+}
+
+// watched reports whether anyone is subscribed to the events of a contract.
+func (p *progress) watched(contractID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.subscribers[contractID]) > 0
+}
+
+// following reports whether sub is still subscribed, and whether an analysis
+// of its contract is running in this process, in which case that analysis
+// publishes its events.
+func (p *progress) following(contractID string, sub *subscriber) (subscribed, running bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.subscribers[contractID][sub], p.running[contractID] > 0
+}
+
+// start marks an analysis of the contract as running.
+func (p *progress) start(contractID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running[contractID]++
+}
+
+// finish marks an analysis of the contract as done, publishes the final
+// event and ends every subscription.
+func (p *progress) finish(contractID string, event Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running[contractID]--; p.running[contractID] <= 0 {
+		delete(p.running, contractID)
+	}
+	p.send(contractID, event)
+	for sub := range p.subscribers[contractID] {
+		p.remove(contractID, sub)
+	}
+}
+
+// publish sends an event to the subscribers of a contract.
+func (p *progress) publish(contractID string, event Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.send(contractID, event)
+}
+
+// deliver sends events to a single subscriber on behalf of an analysis that
+// runs elsewhere, ending the subscription after them if end is set. Nothing
+// is sent while an analysis of the contract runs in this process. It reports
+// whether the subscription is still open.
+func (p *progress) deliver(contractID string, sub *subscriber, events []Event, end bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.subscribers[contractID][sub] {
+		return false
+	}
+	if p.running[contractID] > 0 {
+		return true
+	}
+	for _, event := range events {
+		if !p.sendTo(contractID, sub, event) {
+			return false
+		}
+	}
+	if end {
+		p.remove(contractID, sub)
+		return false
+	}
+	return true
+}
+
+// send delivers an event to every subscriber of a contract without blocking.
+func (p *progress) send(contractID string, event Event) {
+	for sub := range p.subscribers[contractID] {
+		p.sendTo(contractID, sub, event)
+	}
+}
+
+// sendTo delivers an event without blocking and reports whether it was.
+// Subscribers that have fallen too far behind are disconnected; they can
+// subscribe again to catch up.
+func (p *progress) sendTo(contractID string, sub *subscriber, event Event) bool {
+	if !sub.ready {
+		if len(sub.pending) < subscriberBuffer {
+			sub.pending = append(sub.pending, event)
+			return true
+		}
+		p.remove(contractID, sub)
+		return false
+	}
+	select {
+	case sub.ch <- event:
+		return true
+	default:
+		p.remove(contractID, sub)
+		return false
+	}
+}
+
+// remove ends a subscription. The channel of a subscriber that is not ready
+// yet is closed by subscribe once the snapshot has been delivered.
+func (p *progress) remove(contractID string, sub *subscriber) {
+	p.drop(contractID, sub)
+	if sub.ready {
+		close(sub.ch)
+	} else {
+		sub.ended = true
+	}
+}
+
+func (p *progress) drop(contractID string, sub *subscriber) {
+	delete(p.subscribers[contractID], sub)
+	if len(p.subscribers[contractID]) == 0 {
+		delete(p.subscribers, contractID)
+	}
+}
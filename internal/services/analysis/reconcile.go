@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"contract-analysis-service/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// minorUnits is the number of decimal places of the currencies whose minor
+// unit is not a hundredth.
+var minorUnits = map[string]int32{
+	"JPY": 0, "KRW": 0, "VND": 0, "CLP": 0, "ISK": 0, "UGX": 0, "PYG": 0, "RWF": 0, "XAF": 0, "XOF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// percentPrecision is the precision percentages are stated with; a stated
+// percentage may be off by half of it, as "33.33%" is for a third.
+var percentPrecision = decimal.New(1, -2)
+
+var hundred = decimal.NewFromInt(100)
+
+// reconcileMilestones makes the amounts and percentages of the milestones
+// of a contract consistent with its total value where they are missing, and
+// returns the discrepancies among those that are stated. A missing amount
+// is its percentage of the total and a missing percentage its amount's
+// share of the total. Derived amounts are rounded to the minor unit of the
+// currency, and when the percentages add up to 100% the rounding difference
+// is added to the last derived amount, so that the amounts add up to the
+// total. Stated values are never changed: an amount that is not its
+// percentage of the total, percentages that do not add up to 100% and
+// stated amounts that do not add up to the total are returned as findings.
+func reconcileMilestones(summary *models.ContractSummary, milestones []*models.Milestone) []models.Finding {
+	if len(milestones) == 0 {
+		return nil
+	}
+	total := summary.TotalValue
+	places := int32(2)
+	if p, ok := minorUnits[strings.ToUpper(strings.TrimSpace(summary.Currency))]; ok {
+		places = p
+	}
+	unit := decimal.New(1, -places)
+	n := decimal.NewFromInt(int64(len(milestones)))
+	// The most a percentage rounded to percentPrecision can be off from the
+	// share of the total
+	shareTolerance := decimal.Max(unit, total.Mul(percentPrecision).Div(hundred).Div(decimal.NewFromInt(2)))
+
+	var findings []models.Finding
+	var statedPercentages, statedAmounts bool
+	percentSum, amountSum := decimal.Zero, decimal.Zero
+	last := -1
+	for i, m := range milestones {
+		percentage := decimal.NewFromFloat(m.Percentage)
+		switch {
+		case m.Amount.IsZero() && m.Percentage == 0:
+			// Not a payment milestone
+		case m.Amount.IsZero():
+			statedPercentages = true
+			if total.IsZero() {
+				findings = append(findings, models.Finding{
+					Code:    models.FindingMissingTotal,
+					Field:   fmt.Sprintf("milestones[%d].amount", i),
+					Message: fmt.Sprintf("The amount of %s%% cannot be derived without the total value", percentage),
+				})
+				break
+			}
+			m.Amount = total.Mul(percentage).Div(hundred).Round(places)
+			last = i
+		case m.Percentage == 0:
+			statedAmounts = true
+			if !total.IsZero() {
+				m.Percentage = m.Amount.Div(total).Mul(hundred).Round(2).InexactFloat64()
+			}
+		default:
+			statedPercentages, statedAmounts = true, true
+			if total.IsZero() {
+				break
+			}
+			share := total.Mul(percentage).Div(hundred)
+			if m.Amount.Sub(share).Abs().GreaterThan(shareTolerance) {
+				findings = append(findings, models.Finding{
+					Code:  models.FindingAmountMismatch,
+					Field: fmt.Sprintf("milestones[%d].amount", i),
+					Message: fmt.Sprintf("The amount %s is not %s%% of the total value %s, which is %s",
+						m.Amount.StringFixed(places), percentage, total.StringFixed(places), share.Round(places).StringFixed(places)),
+				})
+			}
+		}
+		percentSum = percentSum.Add(decimal.NewFromFloat(m.Percentage))
+		amountSum = amountSum.Add(m.Amount)
+	}
+	if !statedPercentages && !statedAmounts {
+		return findings
+	}
+
+	percentsAddUp := percentSum.Sub(hundred).Abs().LessThanOrEqual(percentPrecision.Div(decimal.NewFromInt(2)).Mul(n))
+	if statedPercentages && !percentsAddUp {
+		findings = append(findings, models.Finding{
+			Code:    models.FindingPercentageSum,
+			Field:   "milestones",
+			Message: fmt.Sprintf("The milestone percentages add up to %s%%, not 100%%", percentSum.Round(2)),
+		})
+	}
+	if total.IsZero() {
+		return findings
+	}
+	if last >= 0 && percentsAddUp && len(findings) == 0 {
+		// The rounding difference of percentages that add up to 100%
+		difference := total.Sub(amountSum)
+		if difference.Abs().LessThanOrEqual(shareTolerance.Mul(n)) {
+			milestones[last].Amount = milestones[last].Amount.Add(difference)
+			amountSum = total
+		}
+	}
+	if statedAmounts && amountSum.Sub(total).Abs().GreaterThan(unit.Mul(n)) {
+		findings = append(findings, models.Finding{
+			Code:    models.FindingAmountSum,
+			Field:   "milestones",
+			Message: fmt.Sprintf("The milestone amounts add up to %s, not the total value %s", amountSum.StringFixed(places), total.StringFixed(places)),
+		})
+	}
+	return findings
+}
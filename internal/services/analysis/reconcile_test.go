@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"testing"
+
+	"contract-analysis-service/internal/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileMilestones(t *testing.T) {
+	type milestone struct {
+		amount     string
+		percentage float64
+	}
+	tests := []struct {
+		name       string
+		total      string
+		currency   string
+		milestones []milestone
+		want       []milestone
+		findings   []string
+	}{
+		{name: "missing amount", total: "1000", currency: "USD", milestones: []milestone{{"400", 40}, {"0", 60}}, want: []milestone{{"400", 40}, {"600", 60}}},
+		{name: "rounding difference", total: "100", currency: "USD", milestones: []milestone{{"0", 33.33}, {"0", 33.33}, {"0", 33.33}}, want: []milestone{{"33.33", 33.33}, {"33.33", 33.33}, {"33.34", 33.33}}},
+		{name: "currency without minor unit", total: "1000", currency: "jpy", milestones: []milestone{{"0", 33.33}, {"0", 33.33}, {"0", 33.33}}, want: []milestone{{"333", 33.33}, {"333", 33.33}, {"334", 33.33}}},
+		{name: "missing percentage", total: "1000", currency: "USD", milestones: []milestone{{"250", 0}, {"750", 0}, {"0", 0}}, want: []milestone{{"250", 25}, {"750", 75}, {"0", 0}}},
+		{name: "amount is not its percentage", total: "1000", currency: "USD", milestones: []milestone{{"450", 40}, {"0", 60}}, want: []milestone{{"450", 40}, {"600", 60}}, findings: []string{"amount_mismatch milestones[0].amount", "amount_sum milestones"}},
+		{name: "percentages do not add up", total: "1000", currency: "USD", milestones: []milestone{{"0", 40}, {"0", 50}}, want: []milestone{{"400", 40}, {"500", 50}}, findings: []string{"percentage_sum milestones"}},
+		{name: "amounts do not add up", total: "1000", currency: "USD", milestones: []milestone{{"300", 0}, {"600", 0}}, want: []milestone{{"300", 30}, {"600", 60}}, findings: []string{"amount_sum milestones"}},
+		{name: "no total", total: "0", milestones: []milestone{{"0", 50}, {"500", 50}}, want: []milestone{{"0", 50}, {"500", 50}}, findings: []string{"missing_total milestones[0].amount"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := &models.ContractSummary{TotalValue: decimal.RequireFromString(tt.total), Currency: tt.currency}
+			var milestones []*models.Milestone
+			for _, m := range tt.milestones {
+				milestones = append(milestones, &models.Milestone{Amount: decimal.RequireFromString(m.amount), Percentage: m.percentage})
+			}
+
+			var findings []string
+			for _, f := range reconcileMilestones(summary, milestones) {
+				findings = append(findings, f.Code+" "+f.Field)
+			}
+			assert.Equal(t, tt.findings, findings)
+			for i, m := range milestones {
+				assert.True(t, decimal.RequireFromString(tt.want[i].amount).Equal(m.Amount), "amount %d: %s", i, m.Amount)
+				assert.Equal(t, tt.want[i].percentage, m.Percentage, "percentage %d", i)
+			}
+		})
+	}
+
+	findings := reconcileMilestones(&models.ContractSummary{TotalValue: decimal.NewFromInt(1000), Currency: "USD"},
+		[]*models.Milestone{{Amount: decimal.NewFromInt(450), Percentage: 40}, {Amount: decimal.NewFromInt(550), Percentage: 60}})
+	assert.Equal(t, []models.Finding{{
+		Code:    models.FindingAmountMismatch,
+		Field:   "milestones[0].amount",
+		Message: "The amount 450.00 is not 40% of the total value 1000.00, which is 400.00",
+	}, {
+		Code:    models.FindingAmountMismatch,
+		Field:   "milestones[1].amount",
+		Message: "The amount 550.00 is not 60% of the total value 1000.00, which is 600.00",
+	}}, findings)
+}
@@ -3,14 +3,22 @@ package document
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"contract-analysis-service/internal/models"
 	"contract-analysis-service/internal/pkg/storage"
+	"contract-analysis-service/internal/pkg/tenant"
 	"contract-analysis-service/internal/repositories"
+	"contract-analysis-service/internal/services/extraction"
+	"contract-analysis-service/internal/services/usage"
 	"contract-analysis-service/internal/services/validation"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -29,10 +37,22 @@ var allowedMimeTypes = map[string]bool{
 	"image/tiff":              true,
 }
 
+// DuplicateContractError is returned when an uploaded document is identical to
+// one the tenant has already uploaded.
+type DuplicateContractError struct {
+	ContractID string
+}
+
+func (e *DuplicateContractError) Error() string {
+	return fmt.Sprintf("document has already been uploaded as contract %s", e.ContractID)
+}
+
 // Service defines the interface for the document service.
 type Service interface {
 	Upload(ctx context.Context, file io.Reader, fileHeader *multipart.FileHeader) (string, error)
 	GetByID(ctx context.Context, id string) (*models.Contract, error)
+	OpenFile(ctx context.Context, id string) (io.ReadCloser, *storage.FileInfo, error)
+	FileURL(ctx context.Context, id string, expiry time.Duration) (string, error)
 	Delete(ctx context.Context, id string) error
 }
 
@@ -41,74 +61,139 @@ type documentService struct {
 	logger            *zap.Logger
 	storage           storage.FileStorage
 	contractRepo      repositories.ContractRepository
+	documentRepo      repositories.ExtractedDocumentRepository
+	stageRepo         repositories.AnalysisStageRepository
+	extractionService extraction.Service
 	validationService validation.Service
 }
 
 // NewDocumentService creates a new document service instance.
-func NewDocumentService(logger *zap.Logger, storage storage.FileStorage, contractRepo repositories.ContractRepository, validationService validation.Service) Service {
+func NewDocumentService(logger *zap.Logger, storage storage.FileStorage, contractRepo repositories.ContractRepository, documentRepo repositories.ExtractedDocumentRepository, stageRepo repositories.AnalysisStageRepository, extractionService extraction.Service, validationService validation.Service) Service {
 	return &documentService{
 		logger:            logger,
 		storage:           storage,
 		contractRepo:      contractRepo,
+		documentRepo:      documentRepo,
+		stageRepo:         stageRepo,
+		extractionService: extractionService,
 		validationService: validationService,
 	}
 }
 
 // Upload handles a single file upload, validates it, and stores it.
+// If the tenant has already uploaded the same document, the existing contract
+// ID is returned together with a *DuplicateContractError.
 func (s *documentService) Upload(ctx context.Context, file io.Reader, fileHeader *multipart.FileHeader) (string, error) {
 	// Validate file size
 	if fileHeader.Size > maxFileSize {
 		return "", fmt.Errorf("file size %d exceeds the limit of %d bytes", fileHeader.Size, maxFileSize)
 	}
 
-	// For validation, we still need to read a chunk of the file.
-	// A better approach would be to wrap the reader to allow for peeking,
-	// but for now, we will read and then pass a new reader to the storage.
-	buf := make([]byte, 512)
-	n, err := file.Read(buf)
-	if err != nil && err != io.EOF {
-		return "", fmt.Errorf("failed to read file for validation: %w", err)
+	// Read the whole file: text extraction needs random access for PDFs and DOCX.
+	// The content hash is computed on the way through.
+	hasher := sha256.New()
+	content, err := io.ReadAll(io.TeeReader(io.LimitReader(file, maxFileSize+1), hasher))
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(content) > maxFileSize {
+		return "", fmt.Errorf("file size exceeds the limit of %d bytes", maxFileSize)
 	}
 
-	// Create a new reader that combines the buffer and the rest of the file reader
-	// so the storage service gets the full file.
-	combinedReader := io.MultiReader(bytes.NewReader(buf[:n]), file)
-
-	ext := filepath.Ext(fileHeader.Filename)
-	mimeType := getMimeType(buf, ext)
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	mimeType, err := resolveMimeType(content, ext)
+	if err != nil {
+		return "", err
+	}
 
-	if !allowedMimeTypes[mimeType] {
-		return "", fmt.Errorf("file type '%s' is not allowed", mimeType)
+	// Inspect PDFs for encryption and active content before anything parses them
+	var securityFlags []string
+	if mimeType == "application/pdf" {
+		scan := scanPDF(content)
+		if scan.encrypted {
+			return "", ErrEncryptedDocument
+		}
+		if len(scan.flags) > 0 {
+			s.logger.Warn("Uploaded PDF contains active or embedded content", zap.String("filename", fileHeader.Filename), zap.Strings("flags", scan.flags))
+			securityFlags = scan.flags
+		}
 	}
 
 	s.logger.Info("File validated successfully", zap.String("filename", fileHeader.Filename), zap.String("mime_type", mimeType))
 
-	// Save the file
-	filePath, err := s.storage.Save(combinedReader, fileHeader.Filename)
+	// Identical documents are only processed once per tenant
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	tenantID := tenant.FromContext(ctx)
+	existing, err := s.contractRepo.GetByHash(tenantID, hash)
+	if err == nil {
+		s.logger.Info("Document already uploaded", zap.String("hash", hash), zap.String("contract_id", existing.ID))
+		return existing.ID, &DuplicateContractError{ContractID: existing.ID}
+	}
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return "", fmt.Errorf("failed to check for duplicate contract: %w", err)
+	}
+
+	// Extract the document text
+	doc, err := s.extractionService.Extract(ctx, content, mimeType)
 	if err != nil {
-		return "", fmt.Errorf("failed to save file: %w", err)
+		return "", fmt.Errorf("failed to extract document text: %w", err)
 	}
 
-	// Validate the contract type
-	validationResult, err := s.validationService.ValidateContract(ctx, string(buf[:n]))
+	// Validate the contract type. The LLM call is accounted to the new
+	// contract under the stage the analysis reuses the result for.
+	contractID := uuid.New().String()
+	validationCtx := usage.WithStage(usage.WithContract(ctx, contractID, tenantID), "validation")
+	validationResult, err := s.validationService.ValidateContract(validationCtx, doc.Text)
 	if err != nil {
 		return "", fmt.Errorf("failed to validate contract: %w", err)
 	}
 
+	// Save the file
+	filePath, err := s.storage.Save(bytes.NewReader(content), fileHeader.Filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
 	// Create a new contract record in the database
 	newContract := &models.Contract{
-		ID:           uuid.New().String(),
+		ID:           contractID,
+		TenantID:     tenantID,
 		FilePath:     filePath,
+		FileName:     filepath.Base(fileHeader.Filename),
+		Hash:         hash,
 		Status:       models.Validated,
 		ContractType: validationResult.ContractType,
 		Validation:   validationResult,
-		// Other fields like Hash will be populated later
+		SecurityFlags: securityFlags,
 	}
 
 	if err := s.contractRepo.Create(newContract); err != nil {
+		s.deleteFile(filePath)
+		if errors.Is(err, repositories.ErrDuplicate) {
+			// An identical upload was stored since the check above
+			if existing, getErr := s.contractRepo.GetByHash(tenantID, hash); getErr == nil {
+				return existing.ID, &DuplicateContractError{ContractID: existing.ID}
+			}
+		}
 		return "", fmt.Errorf("failed to create contract record: %w", err)
 	}
 
+	// Persist the extracted text and page map alongside the contract
+	doc.ID = uuid.New().String()
+	doc.ContractID = newContract.ID
+	for _, page := range doc.Pages {
+		page.ID = uuid.New().String()
+		page.DocumentID = doc.ID
+	}
+	if err := s.documentRepo.Create(doc); err != nil {
+		if delErr := s.contractRepo.Delete(newContract.ID); delErr != nil {
+			s.logger.Error("failed to roll back contract record", zap.String("id", newContract.ID), zap.Error(delErr))
+		} else {
+			s.deleteFile(filePath)
+		}
+		return "", fmt.Errorf("failed to store extracted document: %w", err)
+	}
+
 	return newContract.ID, nil
 }
 
@@ -117,7 +202,45 @@ func (s *documentService) GetByID(ctx context.Context, id string) (*models.Contr
 	return s.contractRepo.GetByID(id)
 }
 
-// Delete removes a contract and its associated file.
+// OpenFile returns the original uploaded file of a contract. The caller must
+// close the returned reader.
+func (s *documentService) OpenFile(ctx context.Context, id string) (io.ReadCloser, *storage.FileInfo, error) {
+	contract, err := s.contractRepo.GetByID(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get contract: %w", err)
+	}
+
+	info, err := s.storage.Stat(contract.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat contract file: %w", err)
+	}
+	file, err := s.storage.Open(contract.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open contract file: %w", err)
+	}
+	// Stored files are named by hash; report the name the file was uploaded with
+	if contract.FileName != "" {
+		info.Name = contract.FileName
+	}
+	return file, info, nil
+}
+
+// FileURL returns a time-limited download link for the original uploaded file.
+func (s *documentService) FileURL(ctx context.Context, id string, expiry time.Duration) (string, error) {
+	contract, err := s.contractRepo.GetByID(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get contract: %w", err)
+	}
+
+	url, err := s.storage.SignedURL(contract.FilePath, expiry)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign contract file URL: %w", err)
+	}
+	return url, nil
+}
+
+// Delete removes a contract, its associated file, its extracted text and the
+// records of its analysis.
 func (s *documentService) Delete(ctx context.Context, id string) error {
 	// First, get the contract to find the file path
 	contract, err := s.contractRepo.GetByID(id)
@@ -125,32 +248,70 @@ func (s *documentService) Delete(ctx context.Context, id string) error {
 		return fmt.Errorf("failed to get contract for deletion: %w", err)
 	}
 
-	// Delete the file from storage
-	if err := s.storage.Delete(contract.FilePath); err != nil {
-		// Log the error but continue to delete the database record
-		s.logger.Error("failed to delete file from storage", zap.String("path", contract.FilePath), zap.Error(err))
+	// Delete the extracted text and analysis stages before the contract they belong to
+	if err := s.documentRepo.DeleteByContractID(id); err != nil {
+		return fmt.Errorf("failed to delete extracted document: %w", err)
+	}
+	if err := s.stageRepo.DeleteByContractID(id); err != nil {
+		return fmt.Errorf("failed to delete analysis stages: %w", err)
 	}
 
 	// Delete the record from the database
-	return s.contractRepo.Delete(id)
+	if err := s.contractRepo.Delete(id); err != nil {
+		return err
+	}
+
+	// The file goes last, once no record points at it
+	s.deleteFile(contract.FilePath)
+	return nil
+}
+
+// deleteFile removes a stored file unless a contract still points at it.
+// Files are named by their content, so identical uploads share one. Errors
+// are logged, as the records have already been dealt with.
+func (s *documentService) deleteFile(filePath string) {
+	refs, err := s.contractRepo.CountByFilePath(filePath)
+	if err != nil {
+		s.logger.Error("failed to check whether file is in use", zap.String("path", filePath), zap.Error(err))
+		return
+	}
+	if refs > 0 {
+		return
+	}
+	if err := s.storage.Delete(filePath); err != nil {
+		s.logger.Error("failed to delete file from storage", zap.String("path", filePath), zap.Error(err))
+	}
+}
+
+// resolveMimeType determines the type of an upload from its content and
+// checks that it agrees with the filename extension.
+func resolveMimeType(content []byte, extension string) (string, error) {
+	expected := mimeTypeForExtension(extension)
+	if !allowedMimeTypes[expected] {
+		return "", fmt.Errorf("file type '%s' is not allowed", expected)
+	}
+
+	detected := detectMimeType(content)
+	if detected != expected {
+		return "", &ContentTypeMismatchError{Extension: extension, Expected: expected, Detected: detected}
+	}
+	return detected, nil
 }
 
-// getMimeType is a helper to determine the mime type.
-// This is a simplified version; a more robust solution would use a library.
-func getMimeType(buffer []byte, extension string) string {
-	// Simple check based on extension for document types
+// mimeTypeForExtension maps a filename extension to the mime type it claims.
+func mimeTypeForExtension(extension string) string {
 	switch extension {
 	case ".pdf":
 		return "application/pdf"
 	case ".docx":
-		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+		return docxMimeType
 	case ".txt":
 		return "text/plain"
 	case ".jpeg", ".jpg":
 		return "image/jpeg"
 	case ".png":
 		return "image/png"
-	case ".tiff":
+	case ".tiff", ".tif":
 		return "image/tiff"
 	}
 	return "application/octet-stream" // default
@@ -8,11 +8,14 @@ import (
 	"testing"
 
 	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/models"
 	"contract-analysis-service/internal/pkg/database"
 	"contract-analysis-service/internal/pkg/storage"
 	"contract-analysis-service/internal/repositories/sqlite"
 	"contract-analysis-service/internal/services/document"
+	"contract-analysis-service/internal/services/extraction"
 	"contract-analysis-service/internal/services/llm"
+	"contract-analysis-service/internal/services/llm/prompts"
 	"contract-analysis-service/internal/services/validation"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,13 +36,16 @@ func TestDocumentService_Integration_Upload_Get_Delete(t *testing.T) {
 	db, err := database.NewDB(cfg.Database)
 	require.NoError(t, err, "Failed to connect to test database")
 	contractRepo := sqlite.NewContractRepository(db)
+	documentRepo := sqlite.NewExtractedDocumentRepository(db)
+	stageRepo := sqlite.NewAnalysisStageRepository(db)
 	fileStorage, err := storage.NewLocalStorage("../../../../test-uploads")
 	require.NoError(t, err, "Failed to create test storage")
 
 	// Create services
 	llmService := llm.NewLLMService(logger)
-	validationService := validation.NewValidationService(llmService, logger)
-	service := document.NewDocumentService(logger, fileStorage, contractRepo, validationService)
+	validationService := validation.NewValidationService(llmService, prompts.Default(), logger)
+	extractionService := extraction.NewExtractionService(nil, nil, logger)
+	service := document.NewDocumentService(logger, fileStorage, contractRepo, documentRepo, stageRepo, extractionService, validationService)
 
 	// --- Test Upload ---
 	fileContent := "integration test file content"
@@ -58,6 +64,16 @@ func TestDocumentService_Integration_Upload_Get_Delete(t *testing.T) {
 	require.NotNil(t, contract)
 	assert.Equal(t, documentID, contract.ID)
 
+	extracted, err := documentRepo.GetByContractID(documentID)
+	require.NoError(t, err)
+	assert.Equal(t, fileContent, extracted.Text)
+	require.Len(t, extracted.Pages, 1)
+
+	// Analysis results and jobs are deleted with the contract
+	require.NoError(t, db.Create(&models.Milestone{ID: documentID + "-milestone", ContractID: documentID}).Error)
+	require.NoError(t, db.Create(&models.RiskAssessment{ID: documentID + "-risk", ContractID: documentID}).Error)
+	require.NoError(t, db.Create(&models.Job{ID: documentID + "-job", ContractID: documentID, Type: "analysis"}).Error)
+
 	// --- Test Delete ---
 	err = service.Delete(context.Background(), documentID)
 	assert.NoError(t, err)
@@ -65,6 +81,11 @@ func TestDocumentService_Integration_Upload_Get_Delete(t *testing.T) {
 	// Verify deletion
 	_, err = service.GetByID(context.Background(), documentID)
 	assert.Error(t, err, "Expected error when getting deleted contract")
+	for _, owned := range []interface{}{&models.Milestone{}, &models.RiskAssessment{}, &models.Job{}} {
+		var count int64
+		require.NoError(t, db.Model(owned).Where("contract_id = ?", documentID).Count(&count).Error)
+		assert.Zero(t, count, "Expected %T rows of the contract to be deleted", owned)
+	}
 
 	// Clean up the created file
 	_, err = os.Stat(contract.FilePath)
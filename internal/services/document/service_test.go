@@ -1,14 +1,24 @@
 package document_test
 
 import (
+	"bytes"
+	"compress/zlib"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
 	"strings"
 	"testing"
+	"time"
 
 	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/pkg/storage"
+	"contract-analysis-service/internal/pkg/tenant"
+	"contract-analysis-service/internal/repositories"
 	repo_mocks "contract-analysis-service/internal/repositories/mocks"
 	"contract-analysis-service/internal/services/document"
+	extraction_mocks "contract-analysis-service/internal/services/extraction/mocks"
 	validation_mocks "contract-analysis-service/internal/services/validation/mocks"
 	storage_mocks "contract-analysis-service/internal/pkg/storage/mocks"
 	"github.com/stretchr/testify/assert"
@@ -20,9 +30,11 @@ func TestDocumentService_Upload(t *testing.T) {
 	logger := zap.NewNop()
 	storageMock := new(storage_mocks.FileStorage)
 	contractRepoMock := new(repo_mocks.ContractRepository)
+	documentRepoMock := new(repo_mocks.ExtractedDocumentRepository)
+	extractionMock := new(extraction_mocks.Service)
 	validationMock := new(validation_mocks.Service)
 
-	service := document.NewDocumentService(logger, storageMock, contractRepoMock, validationMock)
+	service := document.NewDocumentService(logger, storageMock, contractRepoMock, documentRepoMock, new(repo_mocks.AnalysisStageRepository), extractionMock, validationMock)
 
 	fileContent := "this is a test file"
 	fileHeader := &multipart.FileHeader{
@@ -30,26 +42,106 @@ func TestDocumentService_Upload(t *testing.T) {
 		Size:     int64(len(fileContent)),
 	}
 
+	// sha256("this is a test file")
+	fileHash := "5881707e54b0112f901bc83a1ffbacac8fab74ea46a6f706a3efc5f7d4c1c625"
+	extracted := &models.ExtractedDocument{
+		Text:  "this is a test file",
+		Pages: []*models.ExtractedPage{{Number: 1, Text: "this is a test file", Source: models.PageSourceTextLayer, Confidence: 1.0}},
+	}
+	extractionMock.On("Extract", mock.Anything, []byte(fileContent), "text/plain").Return(extracted, nil)
 	validationMock.On("ValidateContract", mock.Anything, "this is a test file").Return(&models.ValidationResult{IsValidContract: true, ContractType: "Sale of Goods"}, nil)
 	storageMock.On("Save", mock.Anything, "test.txt").Return("/path/to/file.txt", nil)
-	contractRepoMock.On("Create", mock.AnythingOfType("*models.Contract")).Return(nil)
+	contractRepoMock.On("GetByHash", tenant.Default, fileHash).Return(nil, repositories.ErrNotFound)
+	contractRepoMock.On("Create", mock.MatchedBy(func(c *models.Contract) bool { return c.Hash == fileHash })).Return(nil)
+	documentRepoMock.On("Create", extracted).Return(nil)
 
 	documentID, err := service.Upload(context.Background(), strings.NewReader(fileContent), fileHeader)
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, documentID)
+	assert.Equal(t, documentID, extracted.ContractID)
+	assert.NotEmpty(t, extracted.ID)
+	assert.Equal(t, extracted.ID, extracted.Pages[0].DocumentID)
 	storageMock.AssertExpectations(t)
 	contractRepoMock.AssertExpectations(t)
+	documentRepoMock.AssertExpectations(t)
+	extractionMock.AssertExpectations(t)
 	validationMock.AssertExpectations(t)
 }
 
+func TestDocumentService_Upload_RollsBackContractWhenDocumentNotStored(t *testing.T) {
+	logger := zap.NewNop()
+	storageMock := new(storage_mocks.FileStorage)
+	contractRepoMock := new(repo_mocks.ContractRepository)
+	documentRepoMock := new(repo_mocks.ExtractedDocumentRepository)
+	extractionMock := new(extraction_mocks.Service)
+	validationMock := new(validation_mocks.Service)
+
+	service := document.NewDocumentService(logger, storageMock, contractRepoMock, documentRepoMock, new(repo_mocks.AnalysisStageRepository), extractionMock, validationMock)
+
+	fileContent := "this is a test file"
+	fileHeader := &multipart.FileHeader{
+		Filename: "test.txt",
+		Size:     int64(len(fileContent)),
+	}
+
+	extractionMock.On("Extract", mock.Anything, mock.Anything, "text/plain").Return(&models.ExtractedDocument{Text: fileContent}, nil)
+	validationMock.On("ValidateContract", mock.Anything, fileContent).Return(&models.ValidationResult{IsValidContract: true}, nil)
+	storageMock.On("Save", mock.Anything, "test.txt").Return("/path/to/file.txt", nil)
+	contractRepoMock.On("GetByHash", mock.Anything, mock.Anything).Return(nil, repositories.ErrNotFound)
+	contractRepoMock.On("Create", mock.AnythingOfType("*models.Contract")).Return(nil)
+	documentRepoMock.On("Create", mock.Anything).Return(errors.New("disk full"))
+	contractRepoMock.On("Delete", mock.AnythingOfType("string")).Return(nil)
+	contractRepoMock.On("CountByFilePath", "/path/to/file.txt").Return(int64(0), nil)
+	storageMock.On("Delete", "/path/to/file.txt").Return(nil)
+
+	_, err := service.Upload(context.Background(), strings.NewReader(fileContent), fileHeader)
+
+	assert.ErrorContains(t, err, "failed to store extracted document")
+	contractRepoMock.AssertExpectations(t)
+	storageMock.AssertExpectations(t)
+}
+
+func TestDocumentService_Upload_ReturnsContractStoredConcurrently(t *testing.T) {
+	logger := zap.NewNop()
+	storageMock := new(storage_mocks.FileStorage)
+	contractRepoMock := new(repo_mocks.ContractRepository)
+	extractionMock := new(extraction_mocks.Service)
+	validationMock := new(validation_mocks.Service)
+
+	service := document.NewDocumentService(logger, storageMock, contractRepoMock, new(repo_mocks.ExtractedDocumentRepository), new(repo_mocks.AnalysisStageRepository), extractionMock, validationMock)
+
+	fileContent := "this is a test file"
+	fileHeader := &multipart.FileHeader{Filename: "test.txt", Size: int64(len(fileContent))}
+
+	extractionMock.On("Extract", mock.Anything, mock.Anything, "text/plain").Return(&models.ExtractedDocument{Text: fileContent}, nil)
+	validationMock.On("ValidateContract", mock.Anything, fileContent).Return(&models.ValidationResult{IsValidContract: true}, nil)
+	storageMock.On("Save", mock.Anything, "test.txt").Return("/path/to/file.txt", nil)
+	// The identical upload is stored between the check and the insert
+	contractRepoMock.On("GetByHash", mock.Anything, mock.Anything).Return(nil, repositories.ErrNotFound).Once()
+	contractRepoMock.On("Create", mock.AnythingOfType("*models.Contract")).Return(repositories.ErrDuplicate)
+	contractRepoMock.On("GetByHash", mock.Anything, mock.Anything).Return(&models.Contract{ID: "existing-id", FilePath: "/path/to/file.txt"}, nil)
+	contractRepoMock.On("CountByFilePath", "/path/to/file.txt").Return(int64(1), nil)
+
+	documentID, err := service.Upload(context.Background(), strings.NewReader(fileContent), fileHeader)
+
+	var duplicate *document.DuplicateContractError
+	assert.ErrorAs(t, err, &duplicate)
+	assert.Equal(t, "existing-id", documentID)
+	// The file is the other contract's too
+	storageMock.AssertNotCalled(t, "Delete", mock.Anything)
+	contractRepoMock.AssertExpectations(t)
+}
+
 func TestDocumentService_GetByID(t *testing.T) {
 	logger := zap.NewNop()
 	storageMock := new(storage_mocks.FileStorage)
 	contractRepoMock := new(repo_mocks.ContractRepository)
+	documentRepoMock := new(repo_mocks.ExtractedDocumentRepository)
+	extractionMock := new(extraction_mocks.Service)
 	validationMock := new(validation_mocks.Service)
 
-	service := document.NewDocumentService(logger, storageMock, contractRepoMock, validationMock)
+	service := document.NewDocumentService(logger, storageMock, contractRepoMock, documentRepoMock, new(repo_mocks.AnalysisStageRepository), extractionMock, validationMock)
 
 	expectedContract := &models.Contract{ID: "test-id"}
 	contractRepoMock.On("GetByID", "test-id").Return(expectedContract, nil)
@@ -65,19 +157,164 @@ func TestDocumentService_Delete(t *testing.T) {
 	logger := zap.NewNop()
 	storageMock := new(storage_mocks.FileStorage)
 	contractRepoMock := new(repo_mocks.ContractRepository)
+	documentRepoMock := new(repo_mocks.ExtractedDocumentRepository)
+	stageRepoMock := new(repo_mocks.AnalysisStageRepository)
+	extractionMock := new(extraction_mocks.Service)
 	validationMock := new(validation_mocks.Service)
 
-	service := document.NewDocumentService(logger, storageMock, contractRepoMock, validationMock)
+	service := document.NewDocumentService(logger, storageMock, contractRepoMock, documentRepoMock, stageRepoMock, extractionMock, validationMock)
 
 	contract := &models.Contract{ID: "test-id", FilePath: "/path/to/file.txt"}
 
 	contractRepoMock.On("GetByID", "test-id").Return(contract, nil)
-	storageMock.On("Delete", "/path/to/file.txt").Return(nil)
+	documentRepoMock.On("DeleteByContractID", "test-id").Return(nil)
+	stageRepoMock.On("DeleteByContractID", "test-id").Return(nil)
 	contractRepoMock.On("Delete", "test-id").Return(nil)
+	contractRepoMock.On("CountByFilePath", "/path/to/file.txt").Return(int64(0), nil)
+	storageMock.On("Delete", "/path/to/file.txt").Return(nil)
 
 	err := service.Delete(context.Background(), "test-id")
 
 	assert.NoError(t, err)
 	storageMock.AssertExpectations(t)
+	documentRepoMock.AssertExpectations(t)
+	stageRepoMock.AssertExpectations(t)
 	contractRepoMock.AssertExpectations(t)
 }
+
+func TestDocumentService_Upload_RejectsContentNotMatchingExtension(t *testing.T) {
+	logger := zap.NewNop()
+	storageMock := new(storage_mocks.FileStorage)
+	extractionMock := new(extraction_mocks.Service)
+	validationMock := new(validation_mocks.Service)
+
+	service := document.NewDocumentService(logger, storageMock, new(repo_mocks.ContractRepository), new(repo_mocks.ExtractedDocumentRepository), new(repo_mocks.AnalysisStageRepository), extractionMock, validationMock)
+
+	// A Windows executable renamed to look like a PDF
+	fileContent := "MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"
+	fileHeader := &multipart.FileHeader{Filename: "contract.pdf", Size: int64(len(fileContent))}
+
+	_, err := service.Upload(context.Background(), strings.NewReader(fileContent), fileHeader)
+
+	var mismatch *document.ContentTypeMismatchError
+	assert.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "application/pdf", mismatch.Expected)
+	assert.Equal(t, "application/octet-stream", mismatch.Detected)
+	extractionMock.AssertNotCalled(t, "Extract", mock.Anything, mock.Anything, mock.Anything)
+	validationMock.AssertNotCalled(t, "ValidateContract", mock.Anything, mock.Anything)
+}
+
+func TestDocumentService_Upload_RejectsEncryptedPDF(t *testing.T) {
+	logger := zap.NewNop()
+	extractionMock := new(extraction_mocks.Service)
+
+	service := document.NewDocumentService(logger, new(storage_mocks.FileStorage), new(repo_mocks.ContractRepository), new(repo_mocks.ExtractedDocumentRepository), new(repo_mocks.AnalysisStageRepository), extractionMock, new(validation_mocks.Service))
+
+	fileContent := "%PDF-1.6\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n<< /Root 1 0 R /Encrypt 5 0 R >>\n%%EOF\n"
+	fileHeader := &multipart.FileHeader{Filename: "contract.pdf", Size: int64(len(fileContent))}
+
+	_, err := service.Upload(context.Background(), strings.NewReader(fileContent), fileHeader)
+
+	assert.ErrorIs(t, err, document.ErrEncryptedDocument)
+	extractionMock.AssertNotCalled(t, "Extract", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDocumentService_Upload_FlagsActiveContentInPDF(t *testing.T) {
+	// JavaScript hidden behind a #xx name escape and an embedded file hidden
+	// inside a compressed object stream.
+	var objStm bytes.Buffer
+	zw := zlib.NewWriter(&objStm)
+	_, _ = zw.Write([]byte("7 0 << /Type /Filespec /EF << /F 8 0 R >> /EmbeddedFiles 9 0 R >>"))
+	_ = zw.Close()
+	fileContent := fmt.Sprintf("%%PDF-1.7\n1 0 obj\n<< /Type /Catalog /OpenAction << /S /JavaScript /J#53 (app.alert(1)) >> >>\nendobj\n"+
+		"2 0 obj\n<< /Type /ObjStm /N 1 /First 4 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream\nendobj\n%%%%EOF\n", objStm.Len(), objStm.String())
+
+	logger := zap.NewNop()
+	storageMock := new(storage_mocks.FileStorage)
+	contractRepoMock := new(repo_mocks.ContractRepository)
+	documentRepoMock := new(repo_mocks.ExtractedDocumentRepository)
+	extractionMock := new(extraction_mocks.Service)
+	validationMock := new(validation_mocks.Service)
+
+	service := document.NewDocumentService(logger, storageMock, contractRepoMock, documentRepoMock, new(repo_mocks.AnalysisStageRepository), extractionMock, validationMock)
+
+	fileHeader := &multipart.FileHeader{Filename: "contract.PDF", Size: int64(len(fileContent))}
+
+	extractionMock.On("Extract", mock.Anything, mock.Anything, "application/pdf").Return(&models.ExtractedDocument{Text: "Sale agreement"}, nil)
+	validationMock.On("ValidateContract", mock.Anything, "Sale agreement").Return(&models.ValidationResult{IsValidContract: true}, nil)
+	storageMock.On("Save", mock.Anything, "contract.PDF").Return("/path/to/file.pdf", nil)
+	contractRepoMock.On("GetByHash", mock.Anything, mock.Anything).Return(nil, repositories.ErrNotFound)
+	contractRepoMock.On("Create", mock.MatchedBy(func(c *models.Contract) bool {
+		return assert.ElementsMatch(t, []string{models.SecurityFlagJavaScript, models.SecurityFlagEmbeddedFile}, c.SecurityFlags)
+	})).Return(nil)
+	documentRepoMock.On("Create", mock.Anything).Return(nil)
+
+	_, err := service.Upload(context.Background(), strings.NewReader(fileContent), fileHeader)
+
+	assert.NoError(t, err)
+	contractRepoMock.AssertExpectations(t)
+}
+
+func TestDocumentService_Upload_ReturnsExistingContractForDuplicate(t *testing.T) {
+	logger := zap.NewNop()
+	storageMock := new(storage_mocks.FileStorage)
+	contractRepoMock := new(repo_mocks.ContractRepository)
+	extractionMock := new(extraction_mocks.Service)
+	validationMock := new(validation_mocks.Service)
+
+	service := document.NewDocumentService(logger, storageMock, contractRepoMock, new(repo_mocks.ExtractedDocumentRepository), new(repo_mocks.AnalysisStageRepository), extractionMock, validationMock)
+
+	fileContent := "this is a test file"
+	fileHeader := &multipart.FileHeader{Filename: "copy.txt", Size: int64(len(fileContent))}
+
+	contractRepoMock.On("GetByHash", "tenant-a", mock.AnythingOfType("string")).Return(&models.Contract{ID: "existing-id"}, nil)
+
+	documentID, err := service.Upload(tenant.WithID(context.Background(), "tenant-a"), strings.NewReader(fileContent), fileHeader)
+
+	var duplicate *document.DuplicateContractError
+	assert.ErrorAs(t, err, &duplicate)
+	assert.Equal(t, "existing-id", duplicate.ContractID)
+	assert.Equal(t, "existing-id", documentID)
+	extractionMock.AssertNotCalled(t, "Extract", mock.Anything, mock.Anything, mock.Anything)
+	validationMock.AssertNotCalled(t, "ValidateContract", mock.Anything, mock.Anything)
+	storageMock.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+}
+
+func TestDocumentService_OpenFile(t *testing.T) {
+	logger := zap.NewNop()
+	storageMock := new(storage_mocks.FileStorage)
+	contractRepoMock := new(repo_mocks.ContractRepository)
+
+	service := document.NewDocumentService(logger, storageMock, contractRepoMock, new(repo_mocks.ExtractedDocumentRepository), new(repo_mocks.AnalysisStageRepository), new(extraction_mocks.Service), new(validation_mocks.Service))
+
+	contractRepoMock.On("GetByID", "test-id").Return(&models.Contract{ID: "test-id", FilePath: "uploads/abc.pdf", FileName: "Supply Agreement.pdf"}, nil)
+	storageMock.On("Stat", "uploads/abc.pdf").Return(&storage.FileInfo{Path: "uploads/abc.pdf", Name: "abc.pdf", Size: 4, ContentType: "application/pdf"}, nil)
+	storageMock.On("Open", "uploads/abc.pdf").Return(io.NopCloser(strings.NewReader("%PDF")), nil)
+
+	file, info, err := service.OpenFile(context.Background(), "test-id")
+
+	assert.NoError(t, err)
+	defer file.Close()
+	assert.Equal(t, "Supply Agreement.pdf", info.Name)
+	assert.Equal(t, "application/pdf", info.ContentType)
+	storageMock.AssertExpectations(t)
+}
+
+func TestDocumentService_FileURL(t *testing.T) {
+	logger := zap.NewNop()
+	storageMock := new(storage_mocks.FileStorage)
+	contractRepoMock := new(repo_mocks.ContractRepository)
+
+	service := document.NewDocumentService(logger, storageMock, contractRepoMock, new(repo_mocks.ExtractedDocumentRepository), new(repo_mocks.AnalysisStageRepository), new(extraction_mocks.Service), new(validation_mocks.Service))
+
+	contractRepoMock.On("GetByID", "test-id").Return(&models.Contract{ID: "test-id", FilePath: "uploads/abc.pdf"}, nil)
+	contractRepoMock.On("GetByID", "missing").Return(nil, repositories.ErrNotFound)
+	storageMock.On("SignedURL", "uploads/abc.pdf", 15*time.Minute).Return("https://files.example.com/abc.pdf?signature=x", nil)
+
+	url, err := service.FileURL(context.Background(), "test-id", 15*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://files.example.com/abc.pdf?signature=x", url)
+
+	_, err = service.FileURL(context.Background(), "missing", 15*time.Minute)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+}
@@ -0,0 +1,190 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"contract-analysis-service/internal/models"
+)
+
+const docxMimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// maxObjectStreamSize caps how much of a single compressed PDF object stream is
+// inflated while scanning for active content.
+const maxObjectStreamSize = 4 * 1024 * 1024
+
+// ErrEncryptedDocument is returned when an uploaded PDF is encrypted or password protected.
+var ErrEncryptedDocument = errors.New("encrypted or password-protected documents are not supported")
+
+// ContentTypeMismatchError is returned when the content of an upload does not
+// match the type implied by its filename extension.
+type ContentTypeMismatchError struct {
+	Extension string
+	Expected  string
+	Detected  string
+}
+
+func (e *ContentTypeMismatchError) Error() string {
+	return fmt.Sprintf("file content (%s) does not match extension '%s' (%s)", e.Detected, e.Extension, e.Expected)
+}
+
+// detectMimeType identifies the type of a document from its leading bytes,
+// ignoring the filename entirely.
+func detectMimeType(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, []byte("%PDF-")):
+		return "application/pdf"
+	case bytes.HasPrefix(content, []byte("II*\x00")), bytes.HasPrefix(content, []byte("MM\x00*")):
+		return "image/tiff"
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")):
+		if isDOCX(content) {
+			return docxMimeType
+		}
+		return "application/zip"
+	}
+
+	detected := http.DetectContentType(content)
+	switch {
+	case detected == "image/jpeg", detected == "image/png":
+		return detected
+	case strings.HasPrefix(detected, "text/plain") && utf8.Valid(content):
+		return "text/plain"
+	}
+	return "application/octet-stream"
+}
+
+// isDOCX reports whether a ZIP archive is a WordprocessingML package.
+func isDOCX(content []byte) bool {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return false
+	}
+	var hasContentTypes, hasDocument bool
+	for _, f := range zr.File {
+		switch f.Name {
+		case "[Content_Types].xml":
+			hasContentTypes = true
+		case "word/document.xml":
+			hasDocument = true
+		}
+	}
+	return hasContentTypes && hasDocument
+}
+
+// pdfScan is the result of inspecting the raw structure of a PDF.
+type pdfScan struct {
+	encrypted bool
+	flags     []string
+}
+
+var (
+	pdfNamePattern     = regexp.MustCompile(`/[^\s/<>\[\]()%{}]+`)
+	pdfObjStreamHeader = regexp.MustCompile(`/Type\s*/ObjStm`)
+)
+
+// scanPDF looks for encryption dictionaries and active or embedded content.
+// Names are matched after resolving #xx escapes, and compressed object streams
+// are inflated, so that trivially obfuscated PDFs are still caught.
+func scanPDF(content []byte) pdfScan {
+	names := pdfNames(content)
+	for _, stream := range objectStreams(content) {
+		for name := range pdfNames(stream) {
+			names[name] = true
+		}
+	}
+
+	var scan pdfScan
+	scan.encrypted = names["/Encrypt"]
+	if names["/JavaScript"] || names["/JS"] {
+		scan.flags = append(scan.flags, models.SecurityFlagJavaScript)
+	}
+	if names["/EmbeddedFile"] || names["/EmbeddedFiles"] {
+		scan.flags = append(scan.flags, models.SecurityFlagEmbeddedFile)
+	}
+	return scan
+}
+
+// pdfNames returns the set of decoded PDF name tokens in data.
+func pdfNames(data []byte) map[string]bool {
+	names := make(map[string]bool)
+	for _, raw := range pdfNamePattern.FindAll(data, -1) {
+		names[decodePDFName(string(raw))] = true
+	}
+	return names
+}
+
+// decodePDFName resolves #xx hexadecimal escapes in a PDF name.
+func decodePDFName(name string) string {
+	if !strings.Contains(name, "#") {
+		return name
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '#' && i+2 < len(name) {
+			if v, err := strconv.ParseUint(name[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// objectStreams returns the inflated contents of every Flate-compressed object
+// stream (/Type /ObjStm) in the PDF. Streams that fail to inflate are skipped.
+func objectStreams(content []byte) [][]byte {
+	var streams [][]byte
+	rest := content
+	for {
+		start := bytes.Index(rest, []byte("stream"))
+		if start < 0 {
+			return streams
+		}
+		// Skip the "stream" inside "endstream".
+		if start >= 3 && bytes.Equal(rest[start-3:start], []byte("end")) {
+			rest = rest[start+len("stream"):]
+			continue
+		}
+
+		dictStart := bytes.LastIndex(rest[:start], []byte("obj"))
+		if dictStart < 0 {
+			dictStart = 0
+		}
+		dict := rest[dictStart:start]
+
+		body := rest[start+len("stream"):]
+		body = bytes.TrimPrefix(body, []byte("\r"))
+		body = bytes.TrimPrefix(body, []byte("\n"))
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			return streams
+		}
+
+		if pdfObjStreamHeader.Match(dict) && bytes.Contains(dict, []byte("/FlateDecode")) {
+			if inflated, err := inflate(body[:end]); err == nil {
+				streams = append(streams, inflated)
+			}
+		}
+		rest = body[end+len("endstream"):]
+	}
+}
+
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(io.LimitReader(zr, maxObjectStreamSize))
+}
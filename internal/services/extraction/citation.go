@@ -0,0 +1,127 @@
+package extraction
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"contract-analysis-service/internal/models"
+)
+
+// ellipsis separates the parts of a quote that leaves text out.
+var ellipsis = regexp.MustCompile(`\s*(?:\.\.\.|…)\s*`)
+
+// Citer locates quoted passages in an extracted document. Matching ignores
+// case, runs of whitespace, line and page breaks, and typographic quote and
+// dash variants, since models rarely reproduce those exactly.
+type Citer struct {
+	doc *models.ExtractedDocument
+	// folded is the normalized document text and offsets maps each of its
+	// bytes to the offset of the rune it came from in the document text.
+	folded  string
+	offsets []int
+}
+
+// NewCiter indexes the text of doc for Cite.
+func NewCiter(doc *models.ExtractedDocument) *Citer {
+	c := &Citer{doc: doc}
+	c.folded = foldText(doc.Text, func(offset int) {
+		c.offsets = append(c.offsets, offset)
+	})
+	return c
+}
+
+// Cite returns the span of the first occurrence of quote in the document. A
+// quote with an ellipsis matches when its parts appear in order. A quote that
+// cannot be found is returned unverified, without offsets, and an empty quote
+// returns nil.
+func (c *Citer) Cite(quote string) *models.SourceSpan {
+	quote = strings.TrimSpace(strings.Trim(strings.TrimSpace(quote), `"“”`))
+	if quote == "" {
+		return nil
+	}
+	span := &models.SourceSpan{Quote: quote}
+
+	start, end, from := -1, -1, 0
+	for _, part := range ellipsis.Split(quote, -1) {
+		part = normalize(part)
+		if part == "" {
+			continue
+		}
+		i := strings.Index(c.folded[from:], part)
+		if i < 0 {
+			return span
+		}
+		if start < 0 {
+			start = from + i
+		}
+		end = from + i + len(part)
+		from = end
+	}
+	if start < 0 {
+		return span
+	}
+
+	span.Start = c.offsets[start]
+	last := c.offsets[end-1]
+	_, size := utf8.DecodeRuneInString(c.doc.Text[last:])
+	span.End = last + size
+	span.Page = c.page(span.Start)
+	span.Verified = true
+	return span
+}
+
+// page returns the number of the page holding offset; pages are separated by
+// form feeds in the document text.
+func (c *Citer) page(offset int) int {
+	i := strings.Count(c.doc.Text[:offset], "\f")
+	if i < len(c.doc.Pages) && c.doc.Pages[i].Number > 0 {
+		return c.doc.Pages[i].Number
+	}
+	return i + 1
+}
+
+func normalize(s string) string {
+	return strings.TrimRight(foldText(s, nil), " ")
+}
+
+// foldText returns s in the form it is compared in, with runs of whitespace
+// collapsed to one space. If mark is not nil it is called with the offset in s
+// of the rune behind every byte of the result.
+func foldText(s string, mark func(offset int)) string {
+	var b strings.Builder
+	space := true
+	for i, r := range s {
+		r = fold(r)
+		if unicode.IsSpace(r) {
+			if space {
+				continue
+			}
+			r = ' '
+			space = true
+		} else {
+			space = false
+		}
+		b.WriteRune(r)
+		if mark != nil {
+			for n := utf8.RuneLen(r); n > 0; n-- {
+				mark(i)
+			}
+		}
+	}
+	return b.String()
+}
+
+// fold maps a rune to the form it is compared in.
+func fold(r rune) rune {
+	switch r {
+	case '‘', '’', '‚', '′':
+		return '\''
+	case '“', '”', '„', '″':
+		return '"'
+	case '‐', '‑', '‒', '–', '—', '−':
+		return '-'
+	}
+	return unicode.ToLower(r)
+}
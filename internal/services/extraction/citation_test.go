@@ -0,0 +1,49 @@
+package extraction
+
+import (
+	"testing"
+
+	"contract-analysis-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCiter_Cite(t *testing.T) {
+	doc := &models.ExtractedDocument{
+		Text:  "MASTER SERVICES AGREEMENT\nThe Supplier’s fees are\npayable within 30 days.\fSCHEDULE 2 – Milestones\nPhase one ends on acceptance of the design. Phase two ends at go-live.",
+		Pages: []*models.ExtractedPage{{Number: 1}, {Number: 2}},
+	}
+	citer := NewCiter(doc)
+
+	tests := []struct {
+		name  string
+		quote string
+		want  string
+		page  int
+	}{
+		{name: "exact", quote: "payable within 30 days", want: "payable within 30 days", page: 1},
+		{name: "case, whitespace and apostrophes", quote: "the supplier's FEES are payable", want: "The Supplier’s fees are\npayable", page: 1},
+		{name: "dash variant", quote: "SCHEDULE 2 - Milestones", want: "SCHEDULE 2 – Milestones", page: 2},
+		{name: "wrapped in quotes", quote: `"Phase two ends at go-live."`, want: "Phase two ends at go-live.", page: 2},
+		{name: "ellipsis", quote: "Phase one ends … at go-live", want: "Phase one ends on acceptance of the design. Phase two ends at go-live", page: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := citer.Cite(tt.quote)
+			require.NotNil(t, span)
+			assert.True(t, span.Verified)
+			assert.Equal(t, tt.want, doc.Text[span.Start:span.End])
+			assert.Equal(t, tt.page, span.Page)
+		})
+	}
+
+	missing := citer.Cite("payable within 60 days")
+	require.NotNil(t, missing)
+	assert.False(t, missing.Verified)
+	assert.Equal(t, "payable within 60 days", missing.Quote)
+	assert.Zero(t, missing.End)
+
+	// Parts of an elided quote must appear in order
+	assert.False(t, citer.Cite("go-live ... Phase one").Verified)
+	assert.Nil(t, citer.Cite("  "))
+}
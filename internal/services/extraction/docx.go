@@ -0,0 +1,580 @@
+package extraction
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"contract-analysis-service/internal/models"
+)
+
+const docxMimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// extractDOCX walks the OOXML package and renders the main document as
+// structured text. Headings are prefixed with '#' markers, list numbering is
+// materialised (e.g. "1.2(a)") and tables are rendered as pipe-delimited rows.
+// Explicit page breaks start a new page.
+func (s *extractionService) extractDOCX(content []byte) ([]*models.ExtractedPage, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX package: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	document, ok := files["word/document.xml"]
+	if !ok {
+		return nil, fmt.Errorf("failed to open DOCX package: word/document.xml not found")
+	}
+
+	p := &docxParser{
+		styles:    map[string]*docxStyle{},
+		numbering: &docxNumbering{nums: map[string]*docxNum{}, abstracts: map[string]map[int]*docxLevel{}},
+		counters:  map[string][]int{},
+		started:   map[string]bool{},
+	}
+	if f, ok := files["word/styles.xml"]; ok {
+		if err := withZipFile(f, p.parseStyles); err != nil {
+			return nil, fmt.Errorf("failed to parse DOCX styles: %w", err)
+		}
+	}
+	if f, ok := files["word/numbering.xml"]; ok {
+		if err := withZipFile(f, p.parseNumbering); err != nil {
+			return nil, fmt.Errorf("failed to parse DOCX numbering: %w", err)
+		}
+	}
+	if err := withZipFile(document, p.parseDocument); err != nil {
+		return nil, fmt.Errorf("failed to parse DOCX document: %w", err)
+	}
+
+	var pages []*models.ExtractedPage
+	for i, text := range p.pages() {
+		pages = append(pages, &models.ExtractedPage{Number: i + 1, Text: text, Source: models.PageSourceTextLayer, Confidence: 1.0})
+	}
+	return pages, nil
+}
+
+func withZipFile(f *zip.File, fn func(io.Reader) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return fn(rc)
+}
+
+// docxStyle is the subset of a paragraph style relevant to text extraction.
+type docxStyle struct {
+	basedOn      string
+	heading      int // 1-based heading level, 0 if not a heading
+	numID        string
+	ilvl         int
+	hasNumbering bool
+}
+
+// docxLevel describes one level of a multi-level list definition.
+type docxLevel struct {
+	start   int
+	numFmt  string
+	lvlText string
+}
+
+// docxNum is a concrete list instance pointing at an abstract definition.
+type docxNum struct {
+	abstractID     string
+	startOverrides map[int]int
+}
+
+type docxNumbering struct {
+	nums      map[string]*docxNum
+	abstracts map[string]map[int]*docxLevel
+}
+
+// docxParagraph accumulates the properties and text of a w:p element.
+type docxParagraph struct {
+	style        string
+	numID        string
+	ilvl         int
+	hasNumbering bool
+	outlineLvl   int
+	hasOutline   bool
+	text         strings.Builder
+}
+
+type docxTable struct {
+	rows [][]string
+	cell []string
+}
+
+type docxParser struct {
+	styles    map[string]*docxStyle
+	numbering *docxNumbering
+	counters  map[string][]int
+	started   map[string]bool
+
+	out        []string
+	pageBreaks []int
+}
+
+func attr(se xml.StartElement, name string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// parseStyles records heading levels and list numbering attached to paragraph styles.
+func (p *docxParser) parseStyles(r io.Reader) error {
+	dec := xml.NewDecoder(r)
+	var cur *docxStyle
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			if ee, ok := tok.(xml.EndElement); ok && ee.Name.Local == "style" {
+				cur = nil
+			}
+			continue
+		}
+		switch se.Name.Local {
+		case "style":
+			if attr(se, "type") != "paragraph" {
+				continue
+			}
+			cur = &docxStyle{}
+			p.styles[attr(se, "styleId")] = cur
+		case "name":
+			if cur != nil {
+				name := strings.ToLower(attr(se, "val"))
+				if name == "title" {
+					cur.heading = 1
+				} else if strings.HasPrefix(name, "heading ") {
+					if lvl, err := strconv.Atoi(strings.TrimPrefix(name, "heading ")); err == nil {
+						cur.heading = lvl
+					}
+				}
+			}
+		case "basedOn":
+			if cur != nil {
+				cur.basedOn = attr(se, "val")
+			}
+		case "outlineLvl":
+			if cur != nil && cur.heading == 0 {
+				if lvl, err := strconv.Atoi(attr(se, "val")); err == nil && lvl < 9 {
+					cur.heading = lvl + 1
+				}
+			}
+		case "numId":
+			if cur != nil {
+				cur.numID = attr(se, "val")
+				cur.hasNumbering = true
+			}
+		case "ilvl":
+			if cur != nil {
+				cur.ilvl, _ = strconv.Atoi(attr(se, "val"))
+			}
+		}
+	}
+}
+
+// parseNumbering reads abstract list definitions and their concrete instances.
+func (p *docxParser) parseNumbering(r io.Reader) error {
+	dec := xml.NewDecoder(r)
+	var abstract map[int]*docxLevel
+	var level *docxLevel
+	var num *docxNum
+	overrideLvl := -1
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "abstractNum":
+				abstract = map[int]*docxLevel{}
+				p.numbering.abstracts[attr(t, "abstractNumId")] = abstract
+			case "lvl":
+				if abstract != nil {
+					ilvl, _ := strconv.Atoi(attr(t, "ilvl"))
+					level = &docxLevel{start: 1, numFmt: "decimal"}
+					abstract[ilvl] = level
+				}
+			case "start":
+				if level != nil {
+					level.start, _ = strconv.Atoi(attr(t, "val"))
+				}
+			case "numFmt":
+				if level != nil {
+					level.numFmt = attr(t, "val")
+				}
+			case "lvlText":
+				if level != nil {
+					level.lvlText = attr(t, "val")
+				}
+			case "num":
+				num = &docxNum{startOverrides: map[int]int{}}
+				p.numbering.nums[attr(t, "numId")] = num
+			case "abstractNumId":
+				if num != nil {
+					num.abstractID = attr(t, "val")
+				}
+			case "lvlOverride":
+				overrideLvl, _ = strconv.Atoi(attr(t, "ilvl"))
+			case "startOverride":
+				if num != nil && overrideLvl >= 0 {
+					num.startOverrides[overrideLvl], _ = strconv.Atoi(attr(t, "val"))
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "abstractNum":
+				abstract = nil
+			case "lvl":
+				level = nil
+			case "num":
+				num = nil
+			case "lvlOverride":
+				overrideLvl = -1
+			}
+		}
+	}
+}
+
+// parseDocument walks the document body in order, emitting paragraphs and tables.
+func (p *docxParser) parseDocument(r io.Reader) error {
+	dec := xml.NewDecoder(r)
+	// Paragraphs can nest through text boxes, so keep a stack of open ones.
+	var paras []*docxParagraph
+	var para *docxParagraph
+	var tables []*docxTable
+	inPPr := false
+	inNumPr := false
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para = &docxParagraph{}
+				paras = append(paras, para)
+			case "pPr":
+				inPPr = true
+			case "numPr":
+				inNumPr = true
+			case "pStyle":
+				if para != nil {
+					para.style = attr(t, "val")
+				}
+			case "ilvl":
+				if para != nil && inNumPr {
+					para.ilvl, _ = strconv.Atoi(attr(t, "val"))
+				}
+			case "numId":
+				if para != nil && inNumPr {
+					para.numID = attr(t, "val")
+					para.hasNumbering = true
+				}
+			case "outlineLvl":
+				if para != nil && inPPr {
+					para.outlineLvl, _ = strconv.Atoi(attr(t, "val"))
+					para.hasOutline = true
+				}
+			case "pageBreakBefore":
+				if attr(t, "val") != "0" && attr(t, "val") != "false" {
+					p.pageBreak()
+				}
+			case "t":
+				var text string
+				if err := dec.DecodeElement(&text, &t); err != nil {
+					return err
+				}
+				if para != nil {
+					para.text.WriteString(text)
+				}
+			case "tab":
+				if para != nil && !inPPr {
+					para.text.WriteString("\t")
+				}
+			case "br", "cr":
+				if attr(t, "type") == "page" {
+					if para != nil && strings.TrimSpace(para.text.String()) != "" {
+						p.emitParagraph(para, currentTable(tables))
+						para = &docxParagraph{}
+						paras[len(paras)-1] = para
+					}
+					p.pageBreak()
+				} else if para != nil {
+					para.text.WriteString("\n")
+				}
+			case "tbl":
+				tables = append(tables, &docxTable{})
+			case "tr":
+				if len(tables) > 0 {
+					tbl := tables[len(tables)-1]
+					tbl.rows = append(tbl.rows, nil)
+				}
+			case "tc":
+				if len(tables) > 0 {
+					tables[len(tables)-1].cell = nil
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "pPr":
+				inPPr = false
+			case "numPr":
+				inNumPr = false
+			case "p":
+				if para != nil {
+					p.emitParagraph(para, currentTable(tables))
+					paras = paras[:len(paras)-1]
+				}
+				para = nil
+				if len(paras) > 0 {
+					para = paras[len(paras)-1]
+				}
+			case "tc":
+				if len(tables) > 0 {
+					tbl := tables[len(tables)-1]
+					if len(tbl.rows) > 0 {
+						text := strings.ReplaceAll(strings.Join(tbl.cell, " "), "|", "\\|")
+						tbl.rows[len(tbl.rows)-1] = append(tbl.rows[len(tbl.rows)-1], text)
+					}
+					tbl.cell = nil
+				}
+			case "tbl":
+				tbl := tables[len(tables)-1]
+				tables = tables[:len(tables)-1]
+				rendered := renderTable(tbl)
+				if len(tables) > 0 {
+					// Nested tables are flattened into the enclosing cell.
+					outer := tables[len(tables)-1]
+					outer.cell = append(outer.cell, strings.ReplaceAll(rendered, "\n", " "))
+				} else if rendered != "" {
+					p.out = append(p.out, rendered)
+				}
+			}
+		}
+	}
+}
+
+// currentTable returns the innermost open table, or nil outside tables.
+func currentTable(tables []*docxTable) *docxTable {
+	if len(tables) == 0 {
+		return nil
+	}
+	return tables[len(tables)-1]
+}
+
+// emitParagraph renders a finished paragraph either into the current cell of
+// tbl or, when tbl is nil, into the document output.
+func (p *docxParser) emitParagraph(para *docxParagraph, tbl *docxTable) {
+	text := strings.TrimSpace(para.text.String())
+	prefix := p.numberFor(para)
+
+	if text == "" {
+		return
+	}
+	if prefix != "" {
+		text = prefix + " " + text
+	}
+	if tbl != nil {
+		tbl.cell = append(tbl.cell, text)
+		return
+	}
+	if level := p.headingLevel(para); level > 0 {
+		text = strings.Repeat("#", level) + " " + text
+	}
+	p.out = append(p.out, text)
+}
+
+// headingLevel returns the heading level of a paragraph, resolving style inheritance.
+func (p *docxParser) headingLevel(para *docxParagraph) int {
+	if para.hasOutline && para.outlineLvl < 9 {
+		return para.outlineLvl + 1
+	}
+	for style, depth := p.styles[para.style], 0; style != nil && depth < 10; style, depth = p.styles[style.basedOn], depth+1 {
+		if style.heading > 0 {
+			return style.heading
+		}
+	}
+	return 0
+}
+
+// numberFor advances the list counters for a numbered paragraph and returns
+// its rendered number, e.g. "1.2(a)". Unnumbered paragraphs return "".
+func (p *docxParser) numberFor(para *docxParagraph) string {
+	numID, ilvl, ok := para.numID, para.ilvl, para.hasNumbering
+	if !ok {
+		for style, depth := p.styles[para.style], 0; style != nil && depth < 10; style, depth = p.styles[style.basedOn], depth+1 {
+			if style.hasNumbering {
+				numID, ilvl, ok = style.numID, style.ilvl, true
+				break
+			}
+		}
+	}
+	if !ok || numID == "0" {
+		return ""
+	}
+
+	num, ok := p.numbering.nums[numID]
+	if !ok {
+		return ""
+	}
+	levels := p.numbering.abstracts[num.abstractID]
+	if levels == nil || levels[ilvl] == nil {
+		return ""
+	}
+
+	counters := p.counters[num.abstractID]
+	if !p.started[numID] {
+		p.started[numID] = true
+		for lvl, start := range num.startOverrides {
+			for len(counters) <= lvl {
+				counters = append(counters, 0)
+			}
+			counters[lvl] = start - 1
+		}
+	}
+	for len(counters) <= ilvl {
+		lvl := len(counters)
+		start := 1
+		if levels[lvl] != nil {
+			start = levels[lvl].start
+		}
+		counters = append(counters, start-1)
+	}
+	counters[ilvl]++
+	// Restart deeper levels.
+	counters = counters[:ilvl+1]
+	p.counters[num.abstractID] = counters
+
+	level := levels[ilvl]
+	if level.numFmt == "bullet" || level.numFmt == "none" {
+		if level.numFmt == "bullet" {
+			return "-"
+		}
+		return ""
+	}
+
+	text := level.lvlText
+	for i := 0; i <= ilvl; i++ {
+		fmtName := "decimal"
+		if levels[i] != nil {
+			fmtName = levels[i].numFmt
+		}
+		text = strings.ReplaceAll(text, "%"+strconv.Itoa(i+1), formatNumber(counters[i], fmtName))
+	}
+	return strings.TrimSpace(text)
+}
+
+// pageBreak records a page boundary before the next emitted block.
+func (p *docxParser) pageBreak() {
+	if n := len(p.pageBreaks); n > 0 && p.pageBreaks[n-1] == len(p.out) {
+		return
+	}
+	if len(p.out) == 0 {
+		return
+	}
+	p.pageBreaks = append(p.pageBreaks, len(p.out))
+}
+
+// pages splits the emitted blocks at recorded page breaks.
+func (p *docxParser) pages() []string {
+	var pages []string
+	start := 0
+	for _, brk := range append(p.pageBreaks, len(p.out)) {
+		if brk > start {
+			pages = append(pages, strings.Join(p.out[start:brk], "\n"))
+		}
+		start = brk
+	}
+	return pages
+}
+
+// renderTable renders a table as pipe-delimited rows with a header separator.
+func renderTable(tbl *docxTable) string {
+	var lines []string
+	for i, row := range tbl.rows {
+		if len(row) == 0 {
+			continue
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			sep := make([]string, len(row))
+			for j := range sep {
+				sep[j] = "---"
+			}
+			lines = append(lines, "| "+strings.Join(sep, " | ")+" |")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatNumber renders a list counter in the given OOXML number format.
+func formatNumber(n int, format string) string {
+	switch format {
+	case "lowerLetter":
+		return toLetters(n)
+	case "upperLetter":
+		return strings.ToUpper(toLetters(n))
+	case "lowerRoman":
+		return strings.ToLower(toRoman(n))
+	case "upperRoman":
+		return toRoman(n)
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// toLetters converts 1 -> a, 26 -> z, 27 -> aa as Word does.
+func toLetters(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	letter := string(rune('a' + (n-1)%26))
+	return strings.Repeat(letter, (n-1)/26+1)
+}
+
+func toRoman(n int) string {
+	if n <= 0 {
+		return strconv.Itoa(n)
+	}
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var b strings.Builder
+	for i, v := range values {
+		for n >= v {
+			b.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return b.String()
+}
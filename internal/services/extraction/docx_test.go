@@ -0,0 +1,103 @@
+package extraction_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/services/extraction"
+	ocr_mocks "contract-analysis-service/internal/services/ocr/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const docxMimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+const testStylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/></w:style>
+  <w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/>
+    <w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr><w:outlineLvl w:val="0"/></w:pPr>
+  </w:style>
+</w:styles>`
+
+const testNumberingXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:abstractNum w:abstractNumId="0">
+    <w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1."/></w:lvl>
+    <w:lvl w:ilvl="1"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%1.%2"/></w:lvl>
+    <w:lvl w:ilvl="2"><w:start w:val="1"/><w:numFmt w:val="lowerLetter"/><w:lvlText w:val="%1.%2(%3)"/></w:lvl>
+  </w:abstractNum>
+  <w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>
+</w:numbering>`
+
+const testDocumentXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+  <w:body>
+    <w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Supply Agreement</w:t></w:r></w:p>
+    <w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Definitions</w:t></w:r></w:p>
+    <w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">"Buyer" means </w:t></w:r><w:r><w:t>Acme Ltd.</w:t></w:r></w:p>
+    <w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Payment</w:t></w:r></w:p>
+    <w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>The Buyer shall pay:</w:t></w:r></w:p>
+    <w:p><w:pPr><w:numPr><w:ilvl w:val="2"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>30% on signing;</w:t></w:r></w:p>
+    <w:p><w:pPr><w:numPr><w:ilvl w:val="2"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>70% on delivery.</w:t></w:r></w:p>
+    <w:p><w:r><w:br w:type="page"/></w:r></w:p>
+    <w:tbl>
+      <w:tr><w:tc><w:p><w:r><w:t>Milestone</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Amount</w:t></w:r></w:p></w:tc></w:tr>
+      <w:tr><w:tc><w:p><w:r><w:t>Signing</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>USD 30,000</w:t></w:r></w:p></w:tc></w:tr>
+    </w:tbl>
+    <w:p><w:r><w:delText>removed text</w:delText></w:r></w:p>
+  </w:body>
+</w:document>`
+
+func buildDOCX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestExtractionService_Extract_DOCX(t *testing.T) {
+	service := extraction.NewExtractionService(new(ocr_mocks.Service), nil, zap.NewNop())
+	content := buildDOCX(t, map[string]string{
+		"word/document.xml":  testDocumentXML,
+		"word/styles.xml":    testStylesXML,
+		"word/numbering.xml": testNumberingXML,
+	})
+
+	result, err := service.Extract(context.Background(), content, docxMimeType)
+
+	require.NoError(t, err)
+	require.Len(t, result.Pages, 2)
+	assert.Equal(t, models.PageSourceTextLayer, result.Pages[0].Source)
+	assert.Equal(t, `# Supply Agreement
+# 1. Definitions
+1.1 "Buyer" means Acme Ltd.
+# 2. Payment
+2.1 The Buyer shall pay:
+2.1(a) 30% on signing;
+2.1(b) 70% on delivery.`, result.Pages[0].Text)
+	assert.Equal(t, 2, result.Pages[1].Number)
+	assert.Equal(t, `| Milestone | Amount |
+| --- | --- |
+| Signing | USD 30,000 |`, result.Pages[1].Text)
+}
+
+func TestExtractionService_Extract_DOCXMissingDocument(t *testing.T) {
+	service := extraction.NewExtractionService(new(ocr_mocks.Service), nil, zap.NewNop())
+	content := buildDOCX(t, map[string]string{"word/styles.xml": testStylesXML})
+
+	_, err := service.Extract(context.Background(), content, docxMimeType)
+
+	assert.ErrorContains(t, err, "word/document.xml not found")
+}
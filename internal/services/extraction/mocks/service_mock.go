@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"context"
+
+	"contract-analysis-service/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// Service is a mock implementation of the extraction.Service interface.
+type Service struct {
+	mock.Mock
+}
+
+// Extract mocks the Extract method.
+func (m *Service) Extract(ctx context.Context, content []byte, mimeType string) (*models.ExtractedDocument, error) {
+	args := m.Called(ctx, content, mimeType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExtractedDocument), args.Error(1)
+}
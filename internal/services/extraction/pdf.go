@@ -0,0 +1,92 @@
+package extraction
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"contract-analysis-service/internal/models"
+	"github.com/ledongthuc/pdf"
+	"go.uber.org/zap"
+)
+
+// minTextLayerLength is the number of non-space characters a page needs
+// before its text layer is trusted; anything shorter is treated as scanned.
+const minTextLayerLength = 10
+
+// extractPDF reads the text layer of every page and falls back to OCR for
+// pages that have none.
+func (s *extractionService) extractPDF(ctx context.Context, content []byte) ([]*models.ExtractedPage, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+
+	numPages := reader.NumPage()
+	pages := make([]*models.ExtractedPage, 0, numPages)
+
+	for i := 1; i <= numPages; i++ {
+		text, err := reader.Page(i).GetPlainText(nil)
+		if err != nil {
+			s.logger.Warn("Failed to read PDF text layer", zap.Int("page", i), zap.Error(err))
+			text = ""
+		}
+		text = normalizePageText(text)
+
+		if hasTextLayer(text) {
+			pages = append(pages, &models.ExtractedPage{Number: i, Text: text, Source: models.PageSourceTextLayer, Confidence: 1.0})
+			continue
+		}
+
+		pages = append(pages, s.ocrPage(ctx, content, i))
+	}
+
+	return pages, nil
+}
+
+// ocrPage renders a single PDF page and runs it through the OCR service.
+// Failures are logged and produce an empty page so that the remaining pages
+// can still be used.
+func (s *extractionService) ocrPage(ctx context.Context, content []byte, pageNum int) *models.ExtractedPage {
+	page := &models.ExtractedPage{Number: pageNum, Source: models.PageSourceOCR}
+
+	if s.rasterizer == nil {
+		s.logger.Warn("PDF page has no text layer and no rasterizer is configured", zap.Int("page", pageNum))
+		return page
+	}
+
+	imagePath, err := s.rasterizer.RenderPage(ctx, content, pageNum)
+	if err != nil {
+		s.logger.Warn("Failed to render PDF page for OCR", zap.Int("page", pageNum), zap.Error(err))
+		return page
+	}
+	defer os.Remove(imagePath)
+
+	ocrResult, err := s.ocrService.ExtractTextFromImage(ctx, imagePath)
+	if err != nil {
+		s.logger.Warn("OCR failed for PDF page", zap.Int("page", pageNum), zap.Error(err))
+		return page
+	}
+
+	page.Text = ocrResult.Text
+	page.Confidence = ocrResult.Confidence
+	return page
+}
+
+// hasTextLayer reports whether the page text is substantial enough to skip OCR.
+func hasTextLayer(text string) bool {
+	return utf8.RuneCountInString(strings.Join(strings.Fields(text), "")) >= minTextLayerLength
+}
+
+// normalizePageText trims trailing spaces from lines and drops leading and
+// trailing blank lines produced by the text layer reader.
+func normalizePageText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
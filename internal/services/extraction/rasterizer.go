@@ -0,0 +1,77 @@
+package extraction
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// PageRasterizer renders a single page of a PDF to an image file.
+// The caller is responsible for removing the returned file.
+type PageRasterizer interface {
+	RenderPage(ctx context.Context, content []byte, pageNum int) (string, error)
+}
+
+// pdftoppmRasterizer renders pages using the poppler pdftoppm binary.
+type pdftoppmRasterizer struct {
+	binaryPath string
+	dpi        int
+}
+
+// NewPdftoppmRasterizer creates a rasterizer backed by pdftoppm.
+// If binaryPath is empty, pdftoppm is looked up on the PATH.
+func NewPdftoppmRasterizer(binaryPath string) PageRasterizer {
+	if binaryPath == "" {
+		binaryPath = "pdftoppm"
+	}
+	return &pdftoppmRasterizer{
+		binaryPath: binaryPath,
+		dpi:        200,
+	}
+}
+
+// RenderPage writes the PDF to a temporary directory and renders the requested page as JPEG.
+func (r *pdftoppmRasterizer) RenderPage(ctx context.Context, content []byte, pageNum int) (string, error) {
+	dir, err := os.MkdirTemp("", "rasterize-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input.pdf")
+	if err := os.WriteFile(input, content, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write PDF for rendering: %w", err)
+	}
+
+	page := strconv.Itoa(pageNum)
+	prefix := filepath.Join(dir, "page")
+	cmd := exec.CommandContext(ctx, r.binaryPath,
+		"-f", page, "-l", page,
+		"-r", strconv.Itoa(r.dpi),
+		"-jpeg", "-singlefile",
+		input, prefix,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("pdftoppm failed for page %d: %w: %s", pageNum, err, out)
+	}
+
+	// Move the rendered image out of the temporary directory before it is removed.
+	rendered, err := os.ReadFile(prefix + ".jpg")
+	if err != nil {
+		return "", fmt.Errorf("failed to read rendered page %d: %w", pageNum, err)
+	}
+	out, err := os.CreateTemp("", "page-*.jpg")
+	if err != nil {
+		return "", fmt.Errorf("failed to create image file: %w", err)
+	}
+	defer out.Close()
+	if _, err := out.Write(rendered); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to write image file: %w", err)
+	}
+
+	return out.Name(), nil
+}
@@ -0,0 +1,138 @@
+package rules
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date is a date stated in the text: either a calendar date, such as
+// "15 March 2024", or a date relative to an event, such as "within 30 days
+// of delivery".
+type Date struct {
+	Match
+	// Date is the calendar date; it is zero for a relative date.
+	Date time.Time
+	// Offset and Unit are the time from the event, e.g. 30 and "business
+	// day". Unit is one of "day", "business day", "week", "month" and "year".
+	Offset int
+	Unit   string
+	// Relation is "after" or "before" the event.
+	Relation string
+	// Event is what the date is relative to, e.g. "delivery".
+	Event string
+}
+
+// Relative reports whether the date is relative to an event.
+func (d Date) Relative() bool {
+	return d.Event != ""
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// numberWords are the numbers of days, weeks and months that contracts
+// commonly spell out.
+var numberWords = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8,
+	"nine": 9, "ten": 10, "eleven": 11, "twelve": 12, "fourteen": 14, "fifteen": 15,
+	"twenty": 20, "thirty": 30, "forty-five": 45, "sixty": 60, "ninety": 90,
+}
+
+const monthName = `(January|February|March|April|May|June|July|August|September|October|November|December|Jan|Feb|Mar|Apr|Jun|Jul|Aug|Sept|Sep|Oct|Nov|Dec)\.?`
+
+var (
+	isoDate  = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	dayMonth = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?(?:\s+day\s+of)?\s+` + monthName + `,?\s+(\d{4})\b`)
+	monthDay = regexp.MustCompile(`(?i)\b` + monthName + `\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`)
+	netTerms = regexp.MustCompile(`(?i)\bnet\s?(\d{1,3})\b`)
+	relative = regexp.MustCompile(`(?i)\b(?:(?:within|no later than|not later than|at least)\s+)?(?:[a-z-]+\s+\((\d{1,4})\)|(\d{1,4})|(` + numberWordPattern() + `))\s+((?:business|working|calendar)\s+)?(day|week|month|year)s?\s+(of|after|from|following|before|prior to)\s+(?:the\s+)?([^.,;:()\n]{1,60}?)(?:\s+(?:and|or|unless|provided|subject)\b|[.,;:()\n]|$)`)
+)
+
+// numberWordPattern matches the numberWords, longest first.
+func numberWordPattern() string {
+	words := make([]string, 0, len(numberWords))
+	for w := range numberWords {
+		words = append(words, w)
+	}
+	sort.Slice(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+	return strings.Join(words, "|")
+}
+
+// findDates returns the calendar and relative dates in text, in order.
+func findDates(text string) []Date {
+	var dates []Date
+	for _, m := range isoDate.FindAllStringSubmatchIndex(text, -1) {
+		year, _ := strconv.Atoi(text[m[2]:m[3]])
+		mon, _ := strconv.Atoi(text[m[4]:m[5]])
+		day, _ := strconv.Atoi(text[m[6]:m[7]])
+		if date, ok := calendarDate(year, time.Month(mon), day); ok {
+			dates = append(dates, Date{Match: newMatch(text, m[0], m[1]), Date: date})
+		}
+	}
+	for _, m := range dayMonth.FindAllStringSubmatchIndex(text, -1) {
+		day, _ := strconv.Atoi(text[m[2]:m[3]])
+		year, _ := strconv.Atoi(text[m[6]:m[7]])
+		if date, ok := calendarDate(year, monthOf(text[m[4]:m[5]]), day); ok {
+			dates = append(dates, Date{Match: newMatch(text, m[0], m[1]), Date: date})
+		}
+	}
+	for _, m := range monthDay.FindAllStringSubmatchIndex(text, -1) {
+		day, _ := strconv.Atoi(text[m[4]:m[5]])
+		year, _ := strconv.Atoi(text[m[6]:m[7]])
+		if date, ok := calendarDate(year, monthOf(text[m[2]:m[3]]), day); ok {
+			dates = append(dates, Date{Match: newMatch(text, m[0], m[1]), Date: date})
+		}
+	}
+	for _, m := range relative.FindAllStringSubmatchIndex(text, -1) {
+		var offset int
+		switch {
+		case m[2] >= 0:
+			offset, _ = strconv.Atoi(text[m[2]:m[3]])
+		case m[4] >= 0:
+			offset, _ = strconv.Atoi(text[m[4]:m[5]])
+		default:
+			offset = numberWords[strings.ToLower(text[m[6]:m[7]])]
+		}
+		unit := strings.ToLower(text[m[10]:m[11]])
+		if m[8] >= 0 && !strings.HasPrefix(strings.ToLower(text[m[8]:m[9]]), "calendar") {
+			unit = "business " + unit
+		}
+		relation := "after"
+		if r := strings.ToLower(text[m[12]:m[13]]); r == "before" || strings.HasPrefix(r, "prior") {
+			relation = "before"
+		}
+		// The match ends with the delimiter of the event, which is not part of the date
+		end := m[15]
+		dates = append(dates, Date{
+			Match:    newMatch(text, m[0], end),
+			Offset:   offset,
+			Unit:     unit,
+			Relation: relation,
+			Event:    strings.Join(strings.Fields(text[m[14]:m[15]]), " "),
+		})
+	}
+	for _, m := range netTerms.FindAllStringSubmatchIndex(text, -1) {
+		offset, _ := strconv.Atoi(text[m[2]:m[3]])
+		dates = append(dates, Date{Match: newMatch(text, m[0], m[1]), Offset: offset, Unit: "day", Relation: "after", Event: "invoice"})
+	}
+	return withoutOverlaps(dates, func(d Date) Match { return d.Match })
+}
+
+func monthOf(name string) time.Month {
+	return months[strings.ToLower(name[:3])]
+}
+
+// calendarDate returns the date, unless the day does not exist in the month.
+func calendarDate(year int, month time.Month, day int) (time.Time, bool) {
+	if month < time.January || month > time.December {
+		return time.Time{}, false
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return date, date.Day() == day && date.Month() == month
+}
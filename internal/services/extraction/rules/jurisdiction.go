@@ -0,0 +1,29 @@
+package rules
+
+import "regexp"
+
+// Jurisdiction is a statement of the law governing the contract, e.g. "governed
+// by the laws of the State of New York".
+type Jurisdiction struct {
+	Match
+	// Name is the jurisdiction, e.g. "New York" or "England and Wales".
+	Name string
+}
+
+// governingLaw matches a choice of law clause. The name is a run of
+// capitalized words, which may be joined by "and" or "of".
+var governingLaw = regexp.MustCompile(`(?i:governed\s+by|construed\s+(?:in\s+accordance\s+with|under)|subject\s+to)(?i:\s+and\s+construed\s+in\s+accordance\s+with)?\s+(?i:the\s+)?(?i:laws?)\s+(?i:of\s+)(?i:the\s+)?(?i:(?:state|commonwealth|province|republic)\s+of\s+)?(\p{Lu}[\p{L}.'-]*(?:\s+(?:(?:and|of)\s+)?\p{Lu}[\p{L}.'-]*){0,4})`)
+
+// findJurisdictions returns the choice of law clauses in text, in order.
+func findJurisdictions(text string) []Jurisdiction {
+	var jurisdictions []Jurisdiction
+	for _, m := range governingLaw.FindAllStringSubmatchIndex(text, -1) {
+		// A full stop after the name ends the sentence
+		end := m[3]
+		for text[end-1] == '.' {
+			end--
+		}
+		jurisdictions = append(jurisdictions, Jurisdiction{Match: newMatch(text, m[0], end), Name: text[m[2]:end]})
+	}
+	return jurisdictions
+}
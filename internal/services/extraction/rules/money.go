@@ -0,0 +1,151 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Amount is a sum of money stated in the text.
+type Amount struct {
+	Match
+	Value decimal.Decimal
+	// Currency is the ISO 4217 code of the currency, or empty when the
+	// amount is not stated with one.
+	Currency string
+}
+
+// Percentage is a percentage stated in the text, e.g. "40%" or "12.5 per cent".
+type Percentage struct {
+	Match
+	Value float64
+}
+
+// currencyCodes are the ISO 4217 codes recognised before or after an amount.
+var currencyCodes = []string{
+	"USD", "EUR", "GBP", "JPY", "CHF", "CAD", "AUD", "NZD", "CNY", "HKD",
+	"SGD", "INR", "SEK", "NOK", "DKK", "ZAR", "BRL", "MXN", "AED",
+}
+
+// currencySigns maps the lower-cased signs and names of currencies to their
+// ISO 4217 codes.
+var currencySigns = map[string]string{
+	"$": "USD", "us$": "USD", "c$": "CAD", "a$": "AUD", "€": "EUR", "£": "GBP", "¥": "JPY", "₹": "INR",
+	"dollars": "USD", "us dollars": "USD", "euros": "EUR", "euro": "EUR", "pounds sterling": "GBP",
+	"pounds": "GBP", "swiss francs": "CHF", "yen": "JPY", "rupees": "INR",
+}
+
+const (
+	// number is a number with its thousands grouped by commas, dots,
+	// apostrophes or non-breaking spaces, or not grouped at all.
+	number = `\d{1,3}(?:[,.'\x{a0}\x{202f}]\d{3})+(?:[.,]\d+)?|\d+(?:[.,]\d+)?`
+	// scale multiplies the number before it.
+	scale = `(?:\s*(million|billion|thousand)\b|(mn|bn|m|k)\b)?`
+)
+
+var (
+	codes = `\b(?:` + strings.Join(currencyCodes, "|") + `)\b`
+	// amountBefore matches amounts with the currency first, e.g. "USD 1,000"
+	// or "$2.5 million"
+	amountBefore = regexp.MustCompile(`(?i)(US\$|C\$|A\$|\$|€|£|¥|₹|` + codes + `)\s?(` + number + `)` + scale)
+	// amountAfter matches amounts with the currency last, e.g. "1.000,00 €"
+	// or "500 dollars"
+	amountAfter = regexp.MustCompile(`(?i)\b(` + number + `)` + scale + `\s?(€|£|` + codes + `|\b(?:us dollars|dollars|euros?|pounds sterling|pounds|swiss francs|yen|rupees)\b)`)
+	percentage  = regexp.MustCompile(`(?i)\b(\d+(?:[.,]\d+)?)\s?(?:%|percent\b|per cent\b)`)
+)
+
+var scales = map[string]int32{"thousand": 3, "k": 3, "million": 6, "m": 6, "mn": 6, "billion": 9, "bn": 9}
+
+// findAmounts returns the amounts of money in text, in order.
+func findAmounts(text string) []Amount {
+	var amounts []Amount
+	for _, m := range amountBefore.FindAllStringSubmatchIndex(text, -1) {
+		if amount, ok := newAmount(text, m, 2, 3); ok {
+			amount.Currency = currencyOf(text[m[2]:m[3]])
+			amounts = append(amounts, amount)
+		}
+	}
+	for _, m := range amountAfter.FindAllStringSubmatchIndex(text, -1) {
+		if amount, ok := newAmount(text, m, 1, 2); ok {
+			amount.Currency = currencyOf(text[m[8]:m[9]])
+			amounts = append(amounts, amount)
+		}
+	}
+	return withoutOverlaps(amounts, func(a Amount) Match { return a.Match })
+}
+
+// newAmount reads the amount of the submatch indices m of a money pattern,
+// whose number is group n and whose scale is one of the two groups from s.
+func newAmount(text string, m []int, n, s int) (Amount, bool) {
+	unit := ""
+	for _, g := range []int{s, s + 1} {
+		if m[2*g] >= 0 {
+			unit = strings.ToLower(text[m[2*g]:m[2*g+1]])
+		}
+	}
+	value, ok := parseNumber(text[m[2*n]:m[2*n+1]], unit != "")
+	if !ok {
+		return Amount{}, false
+	}
+	if unit != "" {
+		value = value.Shift(scales[unit])
+	}
+	return Amount{Match: newMatch(text, m[0], m[1]), Value: value}, true
+}
+
+func currencyOf(sign string) string {
+	if code, ok := currencySigns[strings.ToLower(sign)]; ok {
+		return code
+	}
+	return strings.ToUpper(sign)
+}
+
+// findPercentages returns the percentages in text, in order.
+func findPercentages(text string) []Percentage {
+	var percentages []Percentage
+	for _, m := range percentage.FindAllStringSubmatchIndex(text, -1) {
+		value, ok := parseNumber(text[m[2]:m[3]], true)
+		if !ok {
+			continue
+		}
+		f, _ := value.Float64()
+		percentages = append(percentages, Percentage{Match: newMatch(text, m[0], m[1]), Value: f})
+	}
+	return percentages
+}
+
+// parseNumber parses a number written with any of the usual thousands and
+// decimal separators. Of two different separators the last one is the
+// decimal separator; a single separator followed by three digits groups
+// thousands, as in "1,000" or "1.000", unless the number is scaled, as in
+// "2.500 million", or starts with a zero.
+func parseNumber(s string, scaled bool) (decimal.Decimal, bool) {
+	s = strings.NewReplacer("'", "", "\u00a0", "", "\u202f", "").Replace(s)
+	comma, dot := strings.LastIndex(s, ","), strings.LastIndex(s, ".")
+	switch {
+	case comma >= 0 && dot >= 0:
+		decimalSep, groupSep := ".", ","
+		if comma > dot {
+			decimalSep, groupSep = ",", "."
+		}
+		s = strings.ReplaceAll(s, groupSep, "")
+		s = strings.Replace(s, decimalSep, ".", 1)
+	case comma >= 0:
+		s = decimalPoint(s, ",", scaled)
+	case dot >= 0:
+		s = decimalPoint(s, ".", scaled)
+	}
+	d, err := decimal.NewFromString(s)
+	return d, err == nil
+}
+
+// decimalPoint rewrites a number with one kind of separator, sep, to use a
+// decimal point.
+func decimalPoint(s, sep string, scaled bool) string {
+	parts := strings.Split(s, sep)
+	if len(parts) > 2 || (len(parts[1]) == 3 && !scaled && parts[0] != "0") {
+		return strings.Join(parts, "")
+	}
+	return parts[0] + "." + parts[1]
+}
@@ -0,0 +1,91 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Party roles.
+const (
+	RoleBuyer  = "buyer"
+	RoleSeller = "seller"
+)
+
+// Party is the definition of a contracting party, e.g. `Acme Corp (the
+// "Buyer")` or `"Supplier" means Globex Ltd`.
+type Party struct {
+	Match
+	// Name is the party's name and Term the term the contract defines for it.
+	Name string
+	Term string
+	// Role is RoleBuyer or RoleSeller, by the defined term.
+	Role string
+}
+
+// partyTerms maps the defined terms of contracting parties to their roles.
+// Other defined terms, such as "Effective Date", are not parties.
+var partyTerms = map[string]string{
+	"buyer": RoleBuyer, "purchaser": RoleBuyer, "customer": RoleBuyer, "client": RoleBuyer,
+	"licensee": RoleBuyer, "lessee": RoleBuyer, "tenant": RoleBuyer,
+	"seller": RoleSeller, "supplier": RoleSeller, "vendor": RoleSeller, "provider": RoleSeller,
+	"service provider": RoleSeller, "contractor": RoleSeller, "consultant": RoleSeller,
+	"licensor": RoleSeller, "lessor": RoleSeller, "landlord": RoleSeller,
+}
+
+const (
+	// partyName is a run of capitalized words, such as "Acme Widgets Inc."
+	// or "Bank of Nowhere"
+	partyName = `((?:\p{Lu}[\p{L}\p{N}&.'-]*\s+(?:(?:of|&)\s+)?){0,5}\p{Lu}[\p{L}\p{N}&.'-]*)`
+	// incorporation is a description that may follow the name, such as ",
+	// a Delaware corporation"
+	incorporation = `(?:,\s+an?\s+[^()"“”]{0,80}?)?`
+	// term is the quoted defined term
+	term = `(?:the\s+)?["“]([\p{L} ]{1,30})["”]`
+	// hereinafter introduces a defined term
+	hereinafter = `(?:herein(?:after)?\s+(?:(?:referred\s+to|called|known)\s+as\s+)?)`
+)
+
+var (
+	// definedAfter matches a name followed by its term in parentheses
+	definedAfter = regexp.MustCompile(partyName + incorporation + `,?\s*\(` + hereinafter + `?` + term + `\)`)
+	// definedHereinafter matches a name followed by "hereinafter" and its term
+	definedHereinafter = regexp.MustCompile(partyName + incorporation + `,?\s+` + hereinafter + term)
+	// definedMeans matches a term defined to mean a name
+	definedMeans = regexp.MustCompile(`["“]([\p{L} ]{1,30})["”]\s+(?:shall\s+)?means?\s+(?:the\s+)?(\p{Lu}[^,;:()\n]*?)(?:\s*[,;:(\n]|\.\s|\.$|$)`)
+)
+
+// findParties returns the definitions of contracting parties in text, in
+// order.
+func findParties(text string) []Party {
+	var parties []Party
+	for _, pattern := range []*regexp.Regexp{definedAfter, definedHereinafter} {
+		for _, m := range pattern.FindAllStringSubmatchIndex(text, -1) {
+			if party, ok := newParty(text, m[0], m[1], text[m[2]:m[3]], text[m[4]:m[5]]); ok {
+				parties = append(parties, party)
+			}
+		}
+	}
+	for _, m := range definedMeans.FindAllStringSubmatchIndex(text, -1) {
+		// The match ends with the delimiter of the name, which is not part of the definition
+		if party, ok := newParty(text, m[0], m[5], text[m[4]:m[5]], text[m[2]:m[3]]); ok {
+			parties = append(parties, party)
+		}
+	}
+	return withoutOverlaps(parties, func(p Party) Match { return p.Match })
+}
+
+// newParty returns the party defined by name and term, unless the term is
+// not one of a contracting party.
+func newParty(text string, start, end int, name, term string) (Party, bool) {
+	term = strings.Join(strings.Fields(term), " ")
+	role, ok := partyTerms[strings.ToLower(term)]
+	if !ok {
+		return Party{}, false
+	}
+	return Party{
+		Match: newMatch(text, start, end),
+		Name:  strings.Join(strings.Fields(name), " "),
+		Term:  term,
+		Role:  role,
+	}, true
+}
@@ -0,0 +1,234 @@
+// Package rules extracts amounts of money, percentages, dates, party
+// definitions and the governing law from the text of a contract with
+// deterministic patterns. It is
+// used to cross-check what the LLM extracted, and in place of the LLM when no
+// provider is available.
+package rules
+
+import (
+	"sort"
+	"strings"
+
+	"contract-analysis-service/internal/models"
+)
+
+// Match is the passage of the text a value was read from.
+type Match struct {
+	Text string
+	// Start and End are byte offsets into the text.
+	Start int
+	End   int
+}
+
+func newMatch(text string, start, end int) Match {
+	return Match{Text: text[start:end], Start: start, End: end}
+}
+
+// Extraction holds the values found in a text, each in the order they appear.
+type Extraction struct {
+	Amounts     []Amount
+	Percentages []Percentage
+	Dates       []Date
+	Parties     []Party
+	// Jurisdictions are the choice of law clauses.
+	Jurisdictions []Jurisdiction
+	text          string
+}
+
+// Extract finds the amounts, percentages, dates, party definitions and choice
+// of law clauses in text.
+func Extract(text string) *Extraction {
+	return &Extraction{
+		Amounts:       findAmounts(text),
+		Percentages:   findPercentages(text),
+		Dates:         findDates(text),
+		Parties:       findParties(text),
+		Jurisdictions: findJurisdictions(text),
+		text:          text,
+	}
+}
+
+// Party returns the first party defined with role, if any.
+func (e *Extraction) Party(role string) (Party, bool) {
+	for _, p := range e.Parties {
+		if p.Role == role {
+			return p, true
+		}
+	}
+	return Party{}, false
+}
+
+// GoverningLaw returns the jurisdiction of the first choice of law clause, if
+// any.
+func (e *Extraction) GoverningLaw() (Jurisdiction, bool) {
+	if len(e.Jurisdictions) == 0 {
+		return Jurisdiction{}, false
+	}
+	return e.Jurisdictions[0], true
+}
+
+// DateIn returns the first date stated between the byte offsets start and
+// end of the text, if any.
+func (e *Extraction) DateIn(start, end int) (Date, bool) {
+	for _, d := range e.Dates {
+		if d.Start >= start && d.End <= end {
+			return d, true
+		}
+	}
+	return Date{}, false
+}
+
+// totalCues are words that, in the sentence of an amount, mark it as the
+// total value of the contract.
+var totalCues = []string{"total", "aggregate", "contract price", "contract value", "purchase price", "sum of"}
+
+// paymentCues are words that, in the sentence of a percentage, mark it as a
+// share of the price to be paid, and otherCues words that mark it as
+// something else, such as an interest rate.
+var (
+	paymentCues = []string{"pay", "due", "invoice", "instal"}
+	otherCues   = []string{"interest", "late", "penalt", "discount", "tax", "vat", "uptime", "availability", "liabil"}
+)
+
+// Analysis builds a contract analysis from what the rules found, for when no
+// LLM is available. The buyer and seller are the first parties defined in
+// those roles. The total value is the largest amount stated as a total, or
+// failing that the largest amount. Each sentence that states a percentage of
+// a payment is a milestone. Risk factors are not found by rules.
+func (e *Extraction) Analysis() *models.ContractAnalysis {
+	analysis := &models.ContractAnalysis{
+		Milestones:   []models.AnalysisMilestone{},
+		RiskFactors:  []models.AnalysisRisk{},
+		SourceQuotes: make(map[string]string),
+	}
+	if buyer, ok := e.Party(RoleBuyer); ok {
+		analysis.Buyer = buyer.Name
+		analysis.SourceQuotes["buyer"] = buyer.Text
+	}
+	if seller, ok := e.Party(RoleSeller); ok {
+		analysis.Seller = seller.Name
+		analysis.SourceQuotes["seller"] = seller.Text
+	}
+
+	if total, ok := e.total(); ok {
+		analysis.TotalValue = total.Value
+		analysis.Currency = total.Currency
+		analysis.SourceQuotes["total_value"] = total.Text
+		if total.Currency != "" {
+			analysis.SourceQuotes["currency"] = total.Text
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range e.Percentages {
+		if p.Value <= 0 || p.Value > 100 {
+			continue
+		}
+		start, end := sentence(e.text, p.Start, p.End)
+		quote := strings.TrimSpace(e.text[start:end])
+		lower := strings.ToLower(quote)
+		if !containsAny(lower, paymentCues) || containsAny(lower, otherCues) {
+			continue
+		}
+		key := quote + "\x00" + p.Text
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		analysis.Milestones = append(analysis.Milestones, models.AnalysisMilestone{
+			Description: strings.TrimRight(strings.Join(strings.Fields(quote), " "), ".;!?"),
+			Percentage:  p.Value,
+			SourceQuote: quote,
+		})
+	}
+	if len(analysis.SourceQuotes) == 0 {
+		analysis.SourceQuotes = nil
+	}
+	return analysis
+}
+
+// total returns the amount most likely to be the total value of the
+// contract.
+func (e *Extraction) total() (Amount, bool) {
+	var stated, all []Amount
+	for _, a := range e.Amounts {
+		all = append(all, a)
+		start, end := sentence(e.text, a.Start, a.End)
+		if containsAny(strings.ToLower(e.text[start:end]), totalCues) {
+			stated = append(stated, a)
+		}
+	}
+	if len(stated) == 0 {
+		stated = all
+	}
+	if len(stated) == 0 {
+		return Amount{}, false
+	}
+	largest := stated[0]
+	for _, a := range stated[1:] {
+		if a.Value.GreaterThan(largest.Value) {
+			largest = a
+		}
+	}
+	return largest, true
+}
+
+// sentence returns the byte offsets of the sentence of text around the
+// passage from start to end. Sentences end at a full stop, question mark,
+// exclamation mark or semicolon followed by a space, or at a blank line or
+// page break; single line breaks are taken to be wrapped lines.
+func sentence(text string, start, end int) (int, int) {
+	isEnd := func(i int) bool {
+		switch text[i] {
+		case '.', '!', '?', ';':
+			return i+1 == len(text) || strings.ContainsRune(" \t\n\f", rune(text[i+1]))
+		case '\f':
+			return true
+		case '\n':
+			return (i+1 < len(text) && text[i+1] == '\n') || (i > 0 && text[i-1] == '\n')
+		}
+		return false
+	}
+	for start > 0 && !isEnd(start-1) {
+		start--
+	}
+	for end < len(text) && !isEnd(end) {
+		end++
+	}
+	if end < len(text) && text[end] != '\n' && text[end] != '\f' {
+		// The punctuation ends the sentence
+		end++
+	}
+	return start, end
+}
+
+func containsAny(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutOverlaps sorts matches by position and drops each one that overlaps
+// an earlier one, or a longer one starting at the same position.
+func withoutOverlaps[T any](matches []T, match func(T) Match) []T {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := match(matches[i]), match(matches[j])
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return a.End > b.End
+	})
+	var kept []T
+	end := -1
+	for _, m := range matches {
+		if match(m).Start < end {
+			continue
+		}
+		kept = append(kept, m)
+		end = match(m).End
+	}
+	return kept
+}
@@ -0,0 +1,130 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract_Amounts(t *testing.T) {
+	tests := []struct {
+		text     string
+		value    string
+		currency string
+	}{
+		{text: "USD 1,250,000.00", value: "1250000", currency: "USD"},
+		{text: "$2.5 million", value: "2500000", currency: "USD"},
+		{text: "EUR 2.500 million", value: "2500000", currency: "EUR"},
+		{text: "1.000,50 €", value: "1000.5", currency: "EUR"},
+		{text: "€1.000", value: "1000", currency: "EUR"},
+		{text: "CHF 12'500", value: "12500", currency: "CHF"},
+		{text: "£75k", value: "75000", currency: "GBP"},
+		{text: "500 dollars", value: "500", currency: "USD"},
+		{text: "gbp 99.95", value: "99.95", currency: "GBP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			amounts := Extract("The fee is " + tt.text + " in total.").Amounts
+			require.Len(t, amounts, 1)
+			assert.Equal(t, tt.text, amounts[0].Text)
+			assert.True(t, decimal.RequireFromString(tt.value).Equal(amounts[0].Value), amounts[0].Value.String())
+			assert.Equal(t, tt.currency, amounts[0].Currency)
+		})
+	}
+
+	// Numbers without a currency are not amounts
+	assert.Empty(t, Extract("Clause 12.3 applies for 1,000 units.").Amounts)
+}
+
+func TestExtract_PercentagesAndDates(t *testing.T) {
+	text := "30% is payable on signing; 12,5 per cent within thirty (30) days of delivery, the rest " +
+		"no later than 10 business days after acceptance of the goods and net 45. Signed on 15 March 2024, " +
+		"effective March 1st, 2025 until 2026-02-30 or 2026-02-28, and renewed 2 months before expiry."
+	e := Extract(text)
+
+	require.Len(t, e.Percentages, 2)
+	assert.Equal(t, 30.0, e.Percentages[0].Value)
+	assert.Equal(t, 12.5, e.Percentages[1].Value)
+
+	require.Len(t, e.Dates, 7)
+	assert.Equal(t, Date{Match: e.Dates[0].Match, Offset: 30, Unit: "day", Relation: "after", Event: "delivery"}, e.Dates[0])
+	assert.Equal(t, "within thirty (30) days of delivery", e.Dates[0].Text)
+	assert.Equal(t, "acceptance of the goods", e.Dates[1].Event)
+	assert.Equal(t, "business day", e.Dates[1].Unit)
+	assert.Equal(t, "no later than 10 business days after acceptance of the goods", e.Dates[1].Text)
+	assert.Equal(t, 45, e.Dates[2].Offset)
+	assert.Equal(t, "invoice", e.Dates[2].Event)
+	assert.Equal(t, time.Date(2024, time.March, 15, 0, 0, 0, 0, time.UTC), e.Dates[3].Date)
+	assert.Equal(t, time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), e.Dates[4].Date)
+	assert.False(t, e.Dates[4].Relative())
+	// 2026-02-30 does not exist
+	assert.Equal(t, "2026-02-28", e.Dates[5].Text)
+	assert.Equal(t, Date{Match: e.Dates[6].Match, Offset: 2, Unit: "month", Relation: "before", Event: "expiry"}, e.Dates[6])
+
+	date, ok := e.DateIn(0, 80)
+	require.True(t, ok)
+	assert.Equal(t, "delivery", date.Event)
+}
+
+func TestExtract_Parties(t *testing.T) {
+	text := "This Agreement is made between Acme Widgets Inc., a Delaware corporation (the \"Buyer\"), and " +
+		"Globex Ltd (hereinafter referred to as “Supplier”). The \"Effective Date\" means 1 May 2024.\n" +
+		"\"Licensor\" means Initech GmbH, a German company."
+	e := Extract(text)
+
+	require.Len(t, e.Parties, 3)
+	assert.Equal(t, Party{Match: e.Parties[0].Match, Name: "Acme Widgets Inc.", Term: "Buyer", Role: RoleBuyer}, e.Parties[0])
+	assert.Equal(t, `Acme Widgets Inc., a Delaware corporation (the "Buyer")`, e.Parties[0].Text)
+	assert.Equal(t, Party{Match: e.Parties[1].Match, Name: "Globex Ltd", Term: "Supplier", Role: RoleSeller}, e.Parties[1])
+	assert.Equal(t, Party{Match: e.Parties[2].Match, Name: "Initech GmbH", Term: "Licensor", Role: RoleSeller}, e.Parties[2])
+
+	seller, ok := e.Party(RoleSeller)
+	require.True(t, ok)
+	assert.Equal(t, "Globex Ltd", seller.Name)
+}
+
+func TestExtract_GoverningLaw(t *testing.T) {
+	for text, want := range map[string]string{
+		"This Agreement shall be governed by and construed in accordance with the laws of the State of New York, without regard to its conflict of laws rules.": "New York",
+		"This Agreement is governed by the laws of England and Wales.":                                                                                          "England and Wales",
+		"The contract shall be construed under the law of Germany.":                                                                                             "Germany",
+	} {
+		law, ok := Extract(text).GoverningLaw()
+		require.True(t, ok, text)
+		assert.Equal(t, want, law.Name, text)
+	}
+
+	_, ok := Extract("The Supplier shall deliver the goods in accordance with the laws governing transport.").GoverningLaw()
+	assert.False(t, ok)
+}
+
+func TestExtraction_Analysis(t *testing.T) {
+	text := "Acme (the \"Buyer\") and Globex (the \"Seller\") agree as follows.\n\n" +
+		"The Buyer shall pay a total of\nUSD 10,000. 40% is due on delivery; 60% is payable within 30 days of acceptance.\n\n" +
+		"Late payments bear interest at 2% per month. Liability is capped at USD 50,000."
+	analysis := Extract(text).Analysis()
+
+	assert.Equal(t, "Acme", analysis.Buyer)
+	assert.Equal(t, "Globex", analysis.Seller)
+	// The largest amount is not stated as the total
+	assert.True(t, decimal.NewFromInt(10000).Equal(analysis.TotalValue))
+	assert.Equal(t, "USD", analysis.Currency)
+	assert.Equal(t, "USD 10,000", analysis.SourceQuotes["total_value"])
+	assert.Equal(t, `Acme (the "Buyer")`, analysis.SourceQuotes["buyer"])
+
+	require.Len(t, analysis.Milestones, 2)
+	assert.Equal(t, "40% is due on delivery", analysis.Milestones[0].Description)
+	assert.Equal(t, 40.0, analysis.Milestones[0].Percentage)
+	assert.Equal(t, "40% is due on delivery;", analysis.Milestones[0].SourceQuote)
+	assert.Equal(t, "60% is payable within 30 days of acceptance", analysis.Milestones[1].Description)
+	assert.Empty(t, analysis.RiskFactors)
+
+	empty := Extract("Nothing to see here.").Analysis()
+	assert.Empty(t, empty.Buyer)
+	assert.True(t, empty.TotalValue.IsZero())
+	assert.Empty(t, empty.Milestones)
+	assert.Nil(t, empty.SourceQuotes)
+}
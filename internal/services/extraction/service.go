@@ -0,0 +1,103 @@
+package extraction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/services/ocr"
+	"go.uber.org/zap"
+)
+
+// ErrUnsupportedMimeType is returned when no extractor exists for a document type.
+var ErrUnsupportedMimeType = errors.New("unsupported document type for text extraction")
+
+// ErrNoText is returned when a document yields no text from any page.
+var ErrNoText = errors.New("no text could be extracted from document")
+
+// Service defines the interface for turning uploaded documents into text.
+type Service interface {
+	Extract(ctx context.Context, content []byte, mimeType string) (*models.ExtractedDocument, error)
+}
+
+// extractionService dispatches documents to the extractor for their type.
+type extractionService struct {
+	ocrService ocr.Service
+	rasterizer PageRasterizer
+	logger     *zap.Logger
+}
+
+// NewExtractionService creates a new extraction service instance.
+// The rasterizer is used to render scanned PDF pages for OCR; it may be nil,
+// in which case pages without a text layer are left empty.
+func NewExtractionService(ocrService ocr.Service, rasterizer PageRasterizer, logger *zap.Logger) Service {
+	return &extractionService{
+		ocrService: ocrService,
+		rasterizer: rasterizer,
+		logger:     logger,
+	}
+}
+
+// Extract returns the text of the document, preferring embedded text over OCR.
+// The returned document has no IDs assigned; it is persisted by the caller.
+func (s *extractionService) Extract(ctx context.Context, content []byte, mimeType string) (*models.ExtractedDocument, error) {
+	var pages []*models.ExtractedPage
+	var err error
+
+	switch mimeType {
+	case "application/pdf":
+		pages, err = s.extractPDF(ctx, content)
+	case docxMimeType:
+		pages, err = s.extractDOCX(content)
+	case "text/plain":
+		pages = []*models.ExtractedPage{{Number: 1, Text: string(content), Source: models.PageSourceTextLayer, Confidence: 1.0}}
+	case "image/jpeg", "image/png", "image/tiff":
+		pages, err = s.extractImage(ctx, content, mimeType)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMimeType, mimeType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, 0, len(pages))
+	for _, p := range pages {
+		texts = append(texts, p.Text)
+	}
+	// Pages are separated by form feeds so offsets into Text can be mapped back to pages.
+	doc := &models.ExtractedDocument{Text: strings.Join(texts, "\f"), Pages: pages}
+
+	if strings.TrimSpace(doc.Text) == "" {
+		return nil, ErrNoText
+	}
+	return doc, nil
+}
+
+// extractImage runs a single image through the OCR service.
+func (s *extractionService) extractImage(ctx context.Context, content []byte, mimeType string) ([]*models.ExtractedPage, error) {
+	ext := map[string]string{"image/jpeg": ".jpg", "image/png": ".png", "image/tiff": ".tiff"}[mimeType]
+
+	tmp, err := os.CreateTemp("", "extract-*"+ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary image file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write temporary image file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temporary image file: %w", err)
+	}
+
+	ocrResult, err := s.ocrService.ExtractTextFromImage(ctx, tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text from image: %w", err)
+	}
+
+	return []*models.ExtractedPage{{Number: 1, Text: ocrResult.Text, Source: models.PageSourceOCR, Confidence: ocrResult.Confidence}}, nil
+}
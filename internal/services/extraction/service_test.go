@@ -0,0 +1,144 @@
+package extraction_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/services/extraction"
+	"contract-analysis-service/internal/services/ocr"
+	ocr_mocks "contract-analysis-service/internal/services/ocr/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeRasterizer writes a placeholder image for every requested page.
+type fakeRasterizer struct {
+	pages []int
+}
+
+func (r *fakeRasterizer) RenderPage(ctx context.Context, content []byte, pageNum int) (string, error) {
+	r.pages = append(r.pages, pageNum)
+	f, err := os.CreateTemp("", "page-*.jpg")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	_, err = f.WriteString("jpeg")
+	return f.Name(), err
+}
+
+// buildPDF assembles a minimal PDF with one page per entry; empty entries
+// produce pages without any text layer, like a scanned page.
+func buildPDF(pages []string) []byte {
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := ""
+	for i := range pages {
+		kids += fmt.Sprintf("%d 0 R ", 4+i*2)
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(pages)))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	for i, text := range pages {
+		content := ""
+		if text != "" {
+			content = fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		}
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+i*2))
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestExtractionService_Extract_PDFTextLayer(t *testing.T) {
+	ocrMock := new(ocr_mocks.Service)
+	rasterizer := &fakeRasterizer{}
+	service := extraction.NewExtractionService(ocrMock, rasterizer, zap.NewNop())
+
+	content := buildPDF([]string{"This Sale Agreement is made between Buyer and Seller.", "Payment of 30 percent on delivery."})
+
+	result, err := service.Extract(context.Background(), content, "application/pdf")
+
+	require.NoError(t, err)
+	require.Len(t, result.Pages, 2)
+	assert.Equal(t, 1, result.Pages[0].Number)
+	assert.Equal(t, models.PageSourceTextLayer, result.Pages[0].Source)
+	assert.Contains(t, result.Pages[0].Text, "Sale Agreement")
+	assert.Equal(t, 2, result.Pages[1].Number)
+	assert.Contains(t, result.Pages[1].Text, "30 percent")
+	assert.Empty(t, rasterizer.pages)
+	ocrMock.AssertNotCalled(t, "ExtractTextFromImage", mock.Anything, mock.Anything)
+}
+
+func TestExtractionService_Extract_PDFScannedPageFallsBackToOCR(t *testing.T) {
+	ocrMock := new(ocr_mocks.Service)
+	rasterizer := &fakeRasterizer{}
+	service := extraction.NewExtractionService(ocrMock, rasterizer, zap.NewNop())
+
+	content := buildPDF([]string{"This Sale Agreement is made between Buyer and Seller.", ""})
+	ocrMock.On("ExtractTextFromImage", mock.Anything, mock.Anything).Return(&ocr.OCRResult{Text: "Signed by both parties", Confidence: 0.82}, nil).Once()
+
+	result, err := service.Extract(context.Background(), content, "application/pdf")
+
+	require.NoError(t, err)
+	require.Len(t, result.Pages, 2)
+	assert.Equal(t, models.PageSourceTextLayer, result.Pages[0].Source)
+	assert.Equal(t, models.PageSourceOCR, result.Pages[1].Source)
+	assert.Equal(t, "Signed by both parties", result.Pages[1].Text)
+	assert.Equal(t, 0.82, result.Pages[1].Confidence)
+	assert.Equal(t, []int{2}, rasterizer.pages)
+	ocrMock.AssertExpectations(t)
+}
+
+func TestExtractionService_Extract_PDFOCRFailureLeavesPageEmpty(t *testing.T) {
+	ocrMock := new(ocr_mocks.Service)
+	service := extraction.NewExtractionService(ocrMock, &fakeRasterizer{}, zap.NewNop())
+
+	content := buildPDF([]string{"This Sale Agreement is made between Buyer and Seller.", ""})
+	ocrMock.On("ExtractTextFromImage", mock.Anything, mock.Anything).Return(nil, errors.New("all OCR models failed"))
+
+	result, err := service.Extract(context.Background(), content, "application/pdf")
+
+	require.NoError(t, err)
+	require.Len(t, result.Pages, 2)
+	assert.Empty(t, result.Pages[1].Text)
+	assert.Equal(t, 0.0, result.Pages[1].Confidence)
+}
+
+func TestExtractionService_Extract_PDFWithoutAnyText(t *testing.T) {
+	service := extraction.NewExtractionService(new(ocr_mocks.Service), nil, zap.NewNop())
+
+	_, err := service.Extract(context.Background(), buildPDF([]string{""}), "application/pdf")
+
+	assert.ErrorIs(t, err, extraction.ErrNoText)
+}
+
+func TestExtractionService_Extract_UnsupportedType(t *testing.T) {
+	service := extraction.NewExtractionService(new(ocr_mocks.Service), nil, zap.NewNop())
+
+	_, err := service.Extract(context.Background(), []byte("data"), "application/octet-stream")
+
+	assert.ErrorIs(t, err, extraction.ErrUnsupportedMimeType)
+}
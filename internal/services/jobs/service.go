@@ -0,0 +1,145 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/repositories"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// JobTypeAnalysis runs the full analysis pipeline for a contract.
+const JobTypeAnalysis = "analysis"
+
+var (
+	// ErrJobNotFound is returned when a job does not exist.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrContractNotFound is returned when a job is enqueued for an unknown contract.
+	ErrContractNotFound = errors.New("contract not found")
+	// ErrJobFinished is returned when cancelling a job that has already finished.
+	ErrJobFinished = errors.New("job has already finished")
+)
+
+// Budget decides whether a tenant may start more LLM work.
+type Budget interface {
+	// CheckBudget returns an error when the tenant has spent its budget.
+	CheckBudget(ctx context.Context, tenantID string) error
+}
+
+// PrepareFunc readies a contract for a new job before it is queued, e.g. by
+// discarding what an earlier job of the same type left behind.
+type PrepareFunc func(ctx context.Context, contractID string) error
+
+// Service defines the interface for managing background jobs.
+type Service interface {
+	// Enqueue queues a job for a contract. If a job of the same type is
+	// already queued or running for the contract, that job is returned instead.
+	// A new job is rejected with the budget's error when the contract's
+	// tenant has spent its budget, and is prepared for by the PrepareFunc of
+	// its type, if any.
+	Enqueue(ctx context.Context, jobType, contractID string) (*models.Job, error)
+	Get(ctx context.Context, id string) (*models.Job, error)
+	// Cancel cancels a queued job, or asks the worker running it to stop.
+	Cancel(ctx context.Context, id string) (*models.Job, error)
+}
+
+// jobService implements the Service interface.
+type jobService struct {
+	repo         repositories.JobRepository
+	contractRepo repositories.ContractRepository
+	budget       Budget
+	preparers    map[string]PrepareFunc
+	maxAttempts  int
+	logger       *zap.Logger
+	now          func() time.Time
+}
+
+// NewJobService creates a new job service instance. budget may be nil when
+// jobs are not limited, and preparers nil when no job type needs preparing.
+func NewJobService(repo repositories.JobRepository, contractRepo repositories.ContractRepository, budget Budget, preparers map[string]PrepareFunc, maxAttempts int, logger *zap.Logger) Service {
+	return &jobService{
+		repo:         repo,
+		contractRepo: contractRepo,
+		budget:       budget,
+		preparers:    preparers,
+		maxAttempts:  maxAttempts,
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+// Enqueue queues a job for a contract.
+func (s *jobService) Enqueue(ctx context.Context, jobType, contractID string) (*models.Job, error) {
+	contract, err := s.contractRepo.GetByID(contractID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrContractNotFound
+		}
+		return nil, fmt.Errorf("failed to load contract: %w", err)
+	}
+
+	active, err := s.repo.GetActive(jobType, contractID)
+	if err == nil {
+		return active, nil
+	}
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, fmt.Errorf("failed to look up active jobs: %w", err)
+	}
+	if s.budget != nil {
+		if err := s.budget.CheckBudget(ctx, contract.TenantID); err != nil {
+			return nil, err
+		}
+	}
+	if prepare := s.preparers[jobType]; prepare != nil {
+		if err := prepare(ctx, contractID); err != nil {
+			return nil, fmt.Errorf("failed to prepare job: %w", err)
+		}
+	}
+
+	job := &models.Job{
+		ID:          uuid.New().String(),
+		Type:        jobType,
+		ContractID:  contractID,
+		Status:      models.JobQueued,
+		MaxAttempts: s.maxAttempts,
+		RunAt:       s.now(),
+	}
+	if err := s.repo.Create(job); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	s.logger.Info("Job queued", zap.String("job_id", job.ID), zap.String("type", jobType), zap.String("contract_id", contractID))
+	return job, nil
+}
+
+// Get retrieves a job by its ID.
+func (s *jobService) Get(ctx context.Context, id string) (*models.Job, error) {
+	job, err := s.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return job, nil
+}
+
+// Cancel cancels a job that has not finished yet.
+func (s *jobService) Cancel(ctx context.Context, id string) (*models.Job, error) {
+	job, err := s.repo.Cancel(id, s.now())
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrNotFound):
+			return nil, ErrJobNotFound
+		case errors.Is(err, repositories.ErrConflict):
+			return nil, ErrJobFinished
+		default:
+			return nil, fmt.Errorf("failed to cancel job: %w", err)
+		}
+	}
+	return job, nil
+}
@@ -0,0 +1,293 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/repositories"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// HandlerFunc runs a job. The returned result is stored on the job as JSON.
+// The context is cancelled when the job is cancelled, when the worker loses
+// its lease, or when the pool is stopped.
+type HandlerFunc func(ctx context.Context, job *models.Job) (interface{}, error)
+
+// permanentError marks an error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that the job fails immediately instead of being retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+// WorkerPool runs queued jobs with a fixed number of workers. Jobs are claimed
+// with a lease that the worker renews while the job runs, so a job whose
+// worker dies is picked up again once the lease expires.
+type WorkerPool struct {
+	repo     repositories.JobRepository
+	handlers map[string]HandlerFunc
+	cfg      configs.JobsConfig
+	logger   *zap.Logger
+	id       string
+	now      func() time.Time
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWorkerPool creates a worker pool that dispatches jobs to the handler
+// registered for their type.
+func NewWorkerPool(repo repositories.JobRepository, handlers map[string]HandlerFunc, cfg configs.JobsConfig, logger *zap.Logger) *WorkerPool {
+	hostname, _ := os.Hostname()
+	return &WorkerPool{
+		repo:     repo,
+		handlers: handlers,
+		cfg:      cfg,
+		logger:   logger,
+		id:       fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+		now:      time.Now,
+	}
+}
+
+// Start launches the workers. They run until ctx is cancelled or Stop is called.
+func (p *WorkerPool) Start(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		return
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	for i := 0; i < p.cfg.GetWorkers(); i++ {
+		workerID := fmt.Sprintf("%s-%d", p.id, i)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.work(ctx, workerID)
+		}()
+	}
+	p.logger.Info("Job workers started", zap.Int("workers", p.cfg.GetWorkers()))
+}
+
+// Stop stops the workers and waits for them to return. Jobs interrupted by
+// the shutdown are put back in the queue without counting the attempt.
+func (p *WorkerPool) Stop() {
+	p.mu.Lock()
+	cancel := p.cancel
+	p.cancel = nil
+	p.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	p.wg.Wait()
+	p.logger.Info("Job workers stopped")
+}
+
+func (p *WorkerPool) work(ctx context.Context, workerID string) {
+	for ctx.Err() == nil {
+		now := p.now()
+		job, err := p.repo.ClaimNext(workerID, now, now.Add(p.cfg.GetLeaseDuration()))
+		if err == nil {
+			p.run(ctx, job, workerID)
+			continue
+		}
+		if !errors.Is(err, repositories.ErrNotFound) {
+			p.logger.Error("Failed to claim job", zap.String("worker_id", workerID), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(p.cfg.GetPollInterval()):
+		}
+	}
+}
+
+// stopReason records why a running job's context was cancelled by its heartbeat.
+type stopReason int
+
+const (
+	stopNone stopReason = iota
+	stopCancelled
+	stopLeaseLost
+)
+
+func (p *WorkerPool) run(ctx context.Context, job *models.Job, workerID string) {
+	logger := p.logger.With(zap.String("job_id", job.ID), zap.String("type", job.Type), zap.Int("attempt", job.Attempts))
+
+	// The previous worker may have died after the job was flagged for cancellation
+	if job.CancelRequested {
+		p.finish(logger, job, workerID, models.JobCancelled, nil, nil)
+		return
+	}
+
+	handler, ok := p.handlers[job.Type]
+	if !ok {
+		p.finish(logger, job, workerID, models.JobFailed, nil, fmt.Errorf("no handler registered for job type %q", job.Type))
+		return
+	}
+
+	logger.Info("Job started")
+	jobCtx, cancel := context.WithCancel(ctx)
+	reasonCh := make(chan stopReason, 1)
+	go func() {
+		reasonCh <- p.heartbeat(jobCtx, cancel, job.ID, workerID)
+	}()
+
+	result, err := p.invoke(jobCtx, handler, job)
+	cancel()
+	reason := <-reasonCh
+
+	switch {
+	case reason == stopLeaseLost:
+		// Another worker owns the job now and will record its outcome
+		logger.Warn("Lost lease on job, abandoning it", zap.Error(err))
+	case reason == stopCancelled:
+		p.finish(logger, job, workerID, models.JobCancelled, nil, nil)
+	case err == nil:
+		p.finish(logger, job, workerID, models.JobSucceeded, result, nil)
+	case p.cancelRequested(logger, job.ID):
+		// Cancelled since the last heartbeat; the job must not run again
+		p.finish(logger, job, workerID, models.JobCancelled, nil, nil)
+	case ctx.Err() != nil:
+		p.requeue(logger, job, workerID)
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		p.finish(logger, job, workerID, models.JobFailed, nil, err)
+	default:
+		p.retry(logger, job, workerID, err)
+	}
+}
+
+// invoke calls the handler, turning a panic into a permanent error so that a
+// single bad job cannot take the worker down.
+func (p *WorkerPool) invoke(ctx context.Context, handler HandlerFunc, job *models.Job) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(fmt.Errorf("job handler panicked: %v", r))
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// heartbeat renews the job's lease until ctx is done. It cancels the job when
+// cancellation is requested or the lease cannot be renewed.
+func (p *WorkerPool) heartbeat(ctx context.Context, cancel context.CancelFunc, jobID, workerID string) stopReason {
+	ticker := time.NewTicker(p.cfg.GetLeaseDuration() / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return stopNone
+		case <-ticker.C:
+		}
+
+		job, err := p.repo.ExtendLease(jobID, workerID, p.now().Add(p.cfg.GetLeaseDuration()))
+		switch {
+		case errors.Is(err, repositories.ErrConflict), errors.Is(err, repositories.ErrNotFound):
+			cancel()
+			return stopLeaseLost
+		case err != nil:
+			// Keep going; the lease only lapses if renewals keep failing
+			p.logger.Warn("Failed to extend job lease", zap.String("job_id", jobID), zap.Error(err))
+		case job.CancelRequested:
+			cancel()
+			return stopCancelled
+		}
+	}
+}
+
+// cancelRequested reports whether cancellation of the job has been requested,
+// reading the flag from the store rather than the worker's copy of the job.
+func (p *WorkerPool) cancelRequested(logger *zap.Logger, jobID string) bool {
+	stored, err := p.repo.GetByID(jobID)
+	if err != nil {
+		logger.Warn("Failed to check job for cancellation", zap.Error(err))
+		return false
+	}
+	return stored.CancelRequested
+}
+
+func (p *WorkerPool) finish(logger *zap.Logger, job *models.Job, workerID string, status models.JobStatus, result interface{}, jobErr error) {
+	now := p.now()
+	job.Status = status
+	job.FinishedAt = &now
+	job.LeaseExpiresAt = nil
+	job.Error = ""
+	if jobErr != nil {
+		job.Error = jobErr.Error()
+	}
+	if result != nil {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			job.Status = models.JobFailed
+			job.Error = fmt.Sprintf("failed to encode job result: %v", err)
+		} else {
+			job.Result = encoded
+		}
+	}
+
+	if err := p.repo.Release(job, workerID); err != nil {
+		logger.Error("Failed to record job outcome", zap.String("status", string(status)), zap.Error(err))
+		return
+	}
+
+	switch job.Status {
+	case models.JobFailed:
+		logger.Error("Job failed", zap.String("error", job.Error))
+	default:
+		logger.Info("Job finished", zap.String("status", string(job.Status)))
+	}
+}
+
+func (p *WorkerPool) retry(logger *zap.Logger, job *models.Job, workerID string, jobErr error) {
+	delay := p.cfg.GetRetryBackoff() << (job.Attempts - 1)
+	job.Status = models.JobQueued
+	job.Error = jobErr.Error()
+	job.RunAt = p.now().Add(delay)
+	job.LeaseExpiresAt = nil
+
+	if err := p.repo.Release(job, workerID); err != nil {
+		logger.Error("Failed to requeue job", zap.Error(err))
+		return
+	}
+	logger.Warn("Job failed, will retry", zap.Duration("delay", delay), zap.Error(jobErr))
+}
+
+func (p *WorkerPool) requeue(logger *zap.Logger, job *models.Job, workerID string) {
+	job.Status = models.JobQueued
+	job.Attempts--
+	job.RunAt = p.now()
+	job.LeaseExpiresAt = nil
+
+	if err := p.repo.Release(job, workerID); err != nil {
+		logger.Error("Failed to requeue interrupted job", zap.Error(err))
+		return
+	}
+	logger.Info("Job interrupted by shutdown, requeued")
+}
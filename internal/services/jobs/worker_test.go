@@ -0,0 +1,306 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/repositories"
+	"contract-analysis-service/internal/repositories/mocks"
+	sqliterepo "contract-analysis-service/internal/repositories/sqlite"
+	"contract-analysis-service/internal/services/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newJobRepo(t *testing.T) repositories.JobRepository {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "jobs.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	return sqliterepo.NewJobRepository(db)
+}
+
+func newJobService(t *testing.T, repo repositories.JobRepository, maxAttempts int) jobs.Service {
+	t.Helper()
+	contractRepo := new(mocks.ContractRepository)
+	contractRepo.On("GetByID", "contract-1").Return(&models.Contract{ID: "contract-1"}, nil)
+	contractRepo.On("GetByID", "missing").Return(nil, repositories.ErrNotFound)
+	return jobs.NewJobService(repo, contractRepo, nil, nil, maxAttempts, zap.NewNop())
+}
+
+// budgetFunc adapts a function to the jobs.Budget interface.
+type budgetFunc func(ctx context.Context, tenantID string) error
+
+func (f budgetFunc) CheckBudget(ctx context.Context, tenantID string) error {
+	return f(ctx, tenantID)
+}
+
+var testJobsConfig = configs.JobsConfig{
+	Workers:       2,
+	PollInterval:  10 * time.Millisecond,
+	LeaseDuration: 300 * time.Millisecond,
+	RetryBackoff:  10 * time.Millisecond,
+}
+
+func startPool(t *testing.T, repo repositories.JobRepository, handler jobs.HandlerFunc) {
+	t.Helper()
+	pool := jobs.NewWorkerPool(repo, map[string]jobs.HandlerFunc{jobs.JobTypeAnalysis: handler}, testJobsConfig, zap.NewNop())
+	pool.Start(context.Background())
+	t.Cleanup(pool.Stop)
+}
+
+func waitForStatus(t *testing.T, service jobs.Service, id string, status models.JobStatus) *models.Job {
+	t.Helper()
+	var job *models.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = service.Get(context.Background(), id)
+		require.NoError(t, err)
+		return job.Status == status
+	}, 5*time.Second, 10*time.Millisecond, "job did not reach status %s", status)
+	return job
+}
+
+func TestJobService_Enqueue(t *testing.T) {
+	repo := newJobRepo(t)
+	service := newJobService(t, repo, 3)
+	ctx := context.Background()
+
+	job, err := service.Enqueue(ctx, jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobQueued, job.Status)
+	assert.Equal(t, 3, job.MaxAttempts)
+
+	again, err := service.Enqueue(ctx, jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, again.ID, "an active job should be reused")
+
+	_, err = service.Enqueue(ctx, jobs.JobTypeAnalysis, "missing")
+	assert.ErrorIs(t, err, jobs.ErrContractNotFound)
+}
+
+func TestJobService_Enqueue_BudgetExhausted(t *testing.T) {
+	repo := newJobRepo(t)
+	contractRepo := new(mocks.ContractRepository)
+	contractRepo.On("GetByID", "contract-1").Return(&models.Contract{ID: "contract-1", TenantID: "acme"}, nil)
+	errExhausted := errors.New("budget exhausted")
+	var exhausted bool
+	service := jobs.NewJobService(repo, contractRepo, budgetFunc(func(ctx context.Context, tenantID string) error {
+		assert.Equal(t, "acme", tenantID)
+		if exhausted {
+			return errExhausted
+		}
+		return nil
+	}), nil, 3, zap.NewNop())
+	ctx := context.Background()
+
+	job, err := service.Enqueue(ctx, jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+
+	// The job already queued is still returned once the budget runs out
+	exhausted = true
+	again, err := service.Enqueue(ctx, jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, again.ID)
+
+	_, err = service.Cancel(ctx, job.ID)
+	require.NoError(t, err)
+	_, err = service.Enqueue(ctx, jobs.JobTypeAnalysis, "contract-1")
+	assert.ErrorIs(t, err, errExhausted)
+}
+
+func TestJobService_Enqueue_PreparesNewJobs(t *testing.T) {
+	repo := newJobRepo(t)
+	contractRepo := new(mocks.ContractRepository)
+	contractRepo.On("GetByID", "contract-1").Return(&models.Contract{ID: "contract-1"}, nil)
+	var prepared []string
+	service := jobs.NewJobService(repo, contractRepo, nil, map[string]jobs.PrepareFunc{
+		jobs.JobTypeAnalysis: func(ctx context.Context, contractID string) error {
+			prepared = append(prepared, contractID)
+			return nil
+		},
+	}, 3, zap.NewNop())
+	ctx := context.Background()
+
+	job, err := service.Enqueue(ctx, jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+	_, err = service.Enqueue(ctx, jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"contract-1"}, prepared, "an active job should not be prepared again")
+
+	_, err = service.Cancel(ctx, job.ID)
+	require.NoError(t, err)
+	_, err = service.Enqueue(ctx, jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"contract-1", "contract-1"}, prepared)
+}
+
+func TestWorkerPool_Succeeds(t *testing.T) {
+	repo := newJobRepo(t)
+	service := newJobService(t, repo, 3)
+
+	job, err := service.Enqueue(context.Background(), jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+
+	startPool(t, repo, func(ctx context.Context, job *models.Job) (interface{}, error) {
+		return map[string]string{"contract": job.ContractID}, nil
+	})
+
+	done := waitForStatus(t, service, job.ID, models.JobSucceeded)
+	assert.Equal(t, 1, done.Attempts)
+	assert.JSONEq(t, `{"contract":"contract-1"}`, string(done.Result))
+	assert.NotNil(t, done.FinishedAt)
+}
+
+func TestWorkerPool_RetriesUntilMaxAttempts(t *testing.T) {
+	repo := newJobRepo(t)
+	service := newJobService(t, repo, 3)
+
+	job, err := service.Enqueue(context.Background(), jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+
+	var calls int32
+	startPool(t, repo, func(ctx context.Context, job *models.Job) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("provider unavailable")
+	})
+
+	failed := waitForStatus(t, service, job.ID, models.JobFailed)
+	assert.Equal(t, 3, failed.Attempts)
+	assert.Equal(t, "provider unavailable", failed.Error)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+}
+
+func TestWorkerPool_RetrySucceeds(t *testing.T) {
+	repo := newJobRepo(t)
+	service := newJobService(t, repo, 3)
+
+	job, err := service.Enqueue(context.Background(), jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+
+	startPool(t, repo, func(ctx context.Context, job *models.Job) (interface{}, error) {
+		if job.Attempts == 1 {
+			return nil, errors.New("timeout")
+		}
+		return "ok", nil
+	})
+
+	done := waitForStatus(t, service, job.ID, models.JobSucceeded)
+	assert.Equal(t, 2, done.Attempts)
+	assert.Empty(t, done.Error)
+}
+
+func TestWorkerPool_PermanentErrorIsNotRetried(t *testing.T) {
+	repo := newJobRepo(t)
+	service := newJobService(t, repo, 3)
+
+	job, err := service.Enqueue(context.Background(), jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+
+	startPool(t, repo, func(ctx context.Context, job *models.Job) (interface{}, error) {
+		return nil, jobs.Permanent(errors.New("document has no text"))
+	})
+
+	failed := waitForStatus(t, service, job.ID, models.JobFailed)
+	assert.Equal(t, 1, failed.Attempts)
+}
+
+func TestWorkerPool_CancelRunningJob(t *testing.T) {
+	repo := newJobRepo(t)
+	service := newJobService(t, repo, 3)
+
+	job, err := service.Enqueue(context.Background(), jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+
+	stopped := make(chan struct{})
+	startPool(t, repo, func(ctx context.Context, job *models.Job) (interface{}, error) {
+		<-ctx.Done()
+		close(stopped)
+		return nil, ctx.Err()
+	})
+
+	waitForStatus(t, service, job.ID, models.JobRunning)
+	flagged, err := service.Cancel(context.Background(), job.ID)
+	require.NoError(t, err)
+	assert.True(t, flagged.CancelRequested)
+
+	waitForStatus(t, service, job.ID, models.JobCancelled)
+	<-stopped
+
+	_, err = service.Cancel(context.Background(), job.ID)
+	assert.ErrorIs(t, err, jobs.ErrJobFinished)
+}
+
+func TestWorkerPool_CancelBeforeHeartbeatIsNotRetried(t *testing.T) {
+	repo := newJobRepo(t)
+	service := newJobService(t, repo, 3)
+
+	job, err := service.Enqueue(context.Background(), jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+
+	var calls int32
+	startPool(t, repo, func(ctx context.Context, job *models.Job) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		// Cancelled and failed before the heartbeat could notice
+		_, err := service.Cancel(context.Background(), job.ID)
+		require.NoError(t, err)
+		return nil, errors.New("transient failure")
+	})
+
+	cancelled := waitForStatus(t, service, job.ID, models.JobCancelled)
+	assert.True(t, cancelled.CancelRequested)
+	time.Sleep(5 * testJobsConfig.RetryBackoff)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestJobService_CancelQueuedJob(t *testing.T) {
+	repo := newJobRepo(t)
+	service := newJobService(t, repo, 3)
+
+	job, err := service.Enqueue(context.Background(), jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+
+	cancelled, err := service.Cancel(context.Background(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobCancelled, cancelled.Status)
+
+	_, err = service.Cancel(context.Background(), "unknown")
+	assert.ErrorIs(t, err, jobs.ErrJobNotFound)
+}
+
+func TestJobRepository_ClaimsJobWithExpiredLease(t *testing.T) {
+	repo := newJobRepo(t)
+	service := newJobService(t, repo, 3)
+
+	job, err := service.Enqueue(context.Background(), jobs.JobTypeAnalysis, "contract-1")
+	require.NoError(t, err)
+
+	now := time.Now()
+	claimed, err := repo.ClaimNext("worker-a", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, claimed.ID)
+
+	// Nothing else is runnable while the lease is held
+	_, err = repo.ClaimNext("worker-b", now.Add(30*time.Second), now.Add(90*time.Second))
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	reclaimed, err := repo.ClaimNext("worker-b", now.Add(2*time.Minute), now.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, reclaimed.ID)
+	assert.Equal(t, 2, reclaimed.Attempts)
+
+	// The first worker has lost the job and can no longer renew or finish it
+	_, err = repo.ExtendLease(job.ID, "worker-a", now.Add(time.Hour))
+	assert.ErrorIs(t, err, repositories.ErrConflict)
+	claimed.Status = models.JobSucceeded
+	assert.ErrorIs(t, repo.Release(claimed, "worker-a"), repositories.ErrConflict)
+}
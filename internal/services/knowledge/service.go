@@ -7,9 +7,9 @@ import (
 	"time"
 
 	"contract-analysis-service/internal/models"
-	"contract-analysis-service/internal/pkg/external"
 	"contract-analysis-service/internal/repositories"
 	"contract-analysis-service/internal/services/llm"
+	"contract-analysis-service/internal/services/llm/prompts"
 	"github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
 )
@@ -23,6 +23,7 @@ type Service interface {
 // knowledgeService implements the Service interface.
 type knowledgeService struct {
 	llmService  llm.Service
+	prompts     *prompts.Registry
 	logger      *zap.Logger
 	repo        repositories.KnowledgeEntryRepository
 	redisClient *redis.Client
@@ -30,9 +31,10 @@ type knowledgeService struct {
 }
 
 // NewKnowledgeService creates a new knowledge service instance.
-func NewKnowledgeService(llmService llm.Service, logger *zap.Logger, repo repositories.KnowledgeEntryRepository, redisClient *redis.Client) Service {
+func NewKnowledgeService(llmService llm.Service, registry *prompts.Registry, logger *zap.Logger, repo repositories.KnowledgeEntryRepository, redisClient *redis.Client) Service {
 	return &knowledgeService{
 		llmService:  llmService,
+		prompts:     registry,
 		logger:      logger,
 		repo:        repo,
 		redisClient: redisClient,
@@ -42,65 +44,24 @@ func NewKnowledgeService(llmService llm.Service, logger *zap.Logger, repo reposi
 
 // ClassifyIndustry uses the LLM service to determine the industry of a contract.
 func (s *knowledgeService) ClassifyIndustry(ctx context.Context, contractText string) (string, error) {
-	prompt := buildClassificationPrompt(contractText)
-	provider := "openrouter"
-
-	payload := map[string]interface{}{
-		"model": "qwen/qwen-2.5-vl-72b-instruct:free",
-		"messages": []interface{}{
-			map[string]interface{}{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
-		"response_format": map[string]string{"type": "json_object"},
-	}
-	payloadBytes, err := json.Marshal(payload)
+	prompt, err := s.prompts.Render(ctx, prompts.IndustryClassification, struct{ ContractText string }{contractText})
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal classification payload: %w", err)
+		return "", err
 	}
+	req := llm.NewChatRequest("", prompt.Text)
+	req.ResponseFormat = llm.ResponseFormatJSON
 
-	resp, err := s.llmService.ExecuteRequest(ctx, provider, &external.Request{
-		Method:  "POST",
-		URL:     "/chat/completions",
-		Headers: map[string]string{"Content-Type": "application/json"},
-		Body:    payloadBytes,
-	})
+	resp, err := s.llmService.Chat(ctx, llm.TaskClassification, req)
 	if err != nil {
 		return "", fmt.Errorf("industry classification request failed: %w", err)
 	}
 
-	return parseClassificationResponse(resp.Body)
-}
-
-func buildClassificationPrompt(contractText string) string {
-	return fmt.Sprintf(`Analyze the following contract text and classify its industry (e.g., 'Technology', 'Manufacturing', 'Finance', 'Healthcare'). Respond with a JSON object containing a single key 'industry'. Document:\n\n%s`, contractText)
-}
-
-func parseClassificationResponse(body []byte) (string, error) {
-	var response struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("failed to parse classification response: %w", err)
-	}
-
-	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no choices in classification response")
-	}
-
 	var result struct {
 		Industry string `json:"industry"`
 	}
-	if err := json.Unmarshal([]byte(response.Choices[0].Message.Content), &result); err != nil {
+	if err := resp.DecodeJSON(&result); err != nil {
 		return "", fmt.Errorf("failed to parse industry from content: %w", err)
 	}
-
 	return result.Industry, nil
 }
 
@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"strings"
+	"unicode"
+
+	"contract-analysis-service/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// legalSuffixes are dropped when comparing party names, so that "Acme Corp."
+// and "ACME Corporation" are one party.
+var legalSuffixes = map[string]bool{
+	"the": true, "inc": true, "incorporated": true, "llc": true, "llp": true, "ltd": true,
+	"limited": true, "corp": true, "corporation": true, "co": true, "company": true,
+	"plc": true, "gmbh": true, "ag": true, "sa": true, "bv": true,
+}
+
+var severityRank = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// MergeAnalyses combines the analyses of the chunks of one contract, given in
+// chunk order. Parties and currency are the ones most chunks agree on, with
+// earlier chunks winning ties. Milestones and risks found in several chunks
+// are merged and record every chunk they came from. The total is the one most
+// chunks report, or the sum of the milestone amounts when no chunk states one.
+func MergeAnalyses(parts []*models.ContractAnalysis) *models.ContractAnalysis {
+	merged := &models.ContractAnalysis{
+		Milestones:  []models.AnalysisMilestone{},
+		RiskFactors: []models.AnalysisRisk{},
+	}
+	buyers, sellers, currencies := newTally(PartyKey), newTally(PartyKey), newTally(strings.ToUpper)
+	totals := newTally(func(s string) string { return s })
+	milestones := make(map[string]int)
+	risks := make(map[string]int)
+
+	for chunk, part := range parts {
+		if part == nil {
+			continue
+		}
+		buyers.add(part.Buyer)
+		sellers.add(part.Seller)
+		currencies.add(strings.TrimSpace(part.Currency))
+		if !part.TotalValue.IsZero() {
+			totals.add(part.TotalValue.String())
+		}
+		for field, quote := range part.SourceQuotes {
+			if _, ok := merged.SourceQuotes[field]; !ok && quote != "" {
+				if merged.SourceQuotes == nil {
+					merged.SourceQuotes = make(map[string]string)
+				}
+				merged.SourceQuotes[field] = quote
+			}
+		}
+
+		for _, m := range part.Milestones {
+			key := textKey(m.Description)
+			i, seen := milestones[key]
+			if !seen || key == "" {
+				m.SourceChunks = []int{chunk}
+				milestones[key] = len(merged.Milestones)
+				merged.Milestones = append(merged.Milestones, m)
+				continue
+			}
+			existing := &merged.Milestones[i]
+			if existing.Amount.IsZero() {
+				existing.Amount = m.Amount
+			}
+			if existing.Percentage == 0 {
+				existing.Percentage = m.Percentage
+			}
+			if existing.SourceQuote == "" {
+				existing.SourceQuote = m.SourceQuote
+			}
+			existing.SourceChunks = appendChunk(existing.SourceChunks, chunk)
+		}
+
+		for _, r := range part.RiskFactors {
+			key := textKey(r.Type) + "|" + textKey(r.Description)
+			i, seen := risks[key]
+			if !seen || key == "|" {
+				r.SourceChunks = []int{chunk}
+				risks[key] = len(merged.RiskFactors)
+				merged.RiskFactors = append(merged.RiskFactors, r)
+				continue
+			}
+			existing := &merged.RiskFactors[i]
+			if severityRank[strings.ToLower(r.Severity)] > severityRank[strings.ToLower(existing.Severity)] {
+				existing.Severity = r.Severity
+			}
+			if existing.SourceQuote == "" {
+				existing.SourceQuote = r.SourceQuote
+			}
+			existing.SourceChunks = appendChunk(existing.SourceChunks, chunk)
+		}
+	}
+
+	merged.Buyer = buyers.winner()
+	merged.Seller = sellers.winner()
+	merged.Currency = currencies.winner()
+	if total := totals.winner(); total != "" {
+		merged.TotalValue = decimal.RequireFromString(total)
+	} else {
+		for _, m := range merged.Milestones {
+			merged.TotalValue = merged.TotalValue.Add(m.Amount)
+		}
+	}
+	return merged
+}
+
+// tally counts values by key and remembers the first spelling of each.
+type tally struct {
+	key    func(string) string
+	counts map[string]int
+	first  map[string]string
+	order  []string
+}
+
+func newTally(key func(string) string) *tally {
+	return &tally{key: key, counts: make(map[string]int), first: make(map[string]string)}
+}
+
+func (t *tally) add(value string) {
+	k := t.key(value)
+	if k == "" {
+		return
+	}
+	if _, ok := t.first[k]; !ok {
+		t.first[k] = value
+		t.order = append(t.order, k)
+	}
+	t.counts[k]++
+}
+
+// winner returns the most common value, the earliest one on a tie.
+func (t *tally) winner() string {
+	best := ""
+	for _, k := range t.order {
+		if best == "" || t.counts[k] > t.counts[best] {
+			best = k
+		}
+	}
+	return t.first[best]
+}
+
+// textKey normalizes text for comparison: lower case, punctuation dropped and
+// whitespace collapsed.
+func textKey(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// PartyKey normalizes a party name for comparison, so that "Acme Corp." and
+// "ACME Corporation" have the same key.
+func PartyKey(name string) string {
+	var words []string
+	for _, w := range strings.Fields(textKey(name)) {
+		if !legalSuffixes[w] {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func appendChunk(chunks []int, chunk int) []int {
+	if n := len(chunks); n > 0 && chunks[n-1] == chunk {
+		return chunks
+	}
+	return append(chunks, chunk)
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"contract-analysis-service/internal/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeAnalyses(t *testing.T) {
+	merged := MergeAnalyses([]*models.ContractAnalysis{
+		{
+			Buyer: "Acme Corp.", Seller: "Globex Ltd", TotalValue: decimal.NewFromInt(100000), Currency: "usd",
+			Milestones:  []models.AnalysisMilestone{{Description: "Deposit on signing", Percentage: 30}},
+			RiskFactors: []models.AnalysisRisk{{Type: "payment", Description: "No late fee", Severity: "medium"}},
+		},
+		{
+			Buyer: "ACME Corporation",
+			Milestones: []models.AnalysisMilestone{
+				{Description: "Deposit on signing.", Amount: decimal.NewFromInt(30000), Percentage: 30},
+				{Description: "Final acceptance", Amount: decimal.NewFromInt(70000), Percentage: 70},
+			},
+			RiskFactors: []models.AnalysisRisk{{Type: "Payment", Description: "No late fee.", Severity: "High"}},
+		},
+		{Buyer: "Initech", TotalValue: decimal.NewFromInt(100000), Currency: "USD"},
+	})
+
+	assert.Equal(t, "Acme Corp.", merged.Buyer)
+	assert.Equal(t, "Globex Ltd", merged.Seller)
+	assert.Equal(t, "usd", merged.Currency)
+	assert.True(t, merged.TotalValue.Equal(decimal.NewFromInt(100000)))
+
+	require.Len(t, merged.Milestones, 2)
+	assert.True(t, merged.Milestones[0].Amount.Equal(decimal.NewFromInt(30000)))
+	assert.Equal(t, []int{0, 1}, merged.Milestones[0].SourceChunks)
+	assert.Equal(t, []int{1}, merged.Milestones[1].SourceChunks)
+
+	require.Len(t, merged.RiskFactors, 1)
+	assert.Equal(t, "High", merged.RiskFactors[0].Severity)
+	assert.Equal(t, []int{0, 1}, merged.RiskFactors[0].SourceChunks)
+}
+
+func TestMergeAnalyses_TotalFromMilestones(t *testing.T) {
+	merged := MergeAnalyses([]*models.ContractAnalysis{
+		{Milestones: []models.AnalysisMilestone{{Description: "Deposit", Amount: decimal.NewFromInt(400)}}},
+		{Milestones: []models.AnalysisMilestone{{Description: "Balance", Amount: decimal.NewFromInt(600)}}},
+	})
+	assert.True(t, merged.TotalValue.Equal(decimal.NewFromInt(1000)))
+}
+
+func TestContractAnalyzer_AnalyzesLongContractsInChunks(t *testing.T) {
+	service := new(MockLLMService)
+	analyzer := NewContractAnalyzer(service, NewPromptEngine())
+	analyzer.SetChunkTokens(30)
+
+	text := "1. Parties\nThis agreement is between Acme Corp and Globex Ltd for the supply of widgets.\n\n" +
+		"2. Payment\nThe buyer pays a deposit of 30% on signing and the balance on delivery of the widgets.\n\n"
+	isPart := func(n int) interface{} {
+		return mock.MatchedBy(func(req *ChatRequest) bool {
+			return strings.Contains(req.Messages[len(req.Messages)-1].Content, fmt.Sprintf("PART %d OF 2", n))
+		})
+	}
+	service.On("Chat", mock.Anything, TaskAnalysis, isPart(1)).Return(&ChatResponse{
+		Content: `{"buyer":"Acme Corp","seller":"Globex Ltd","total_value":0,"currency":"","milestones":[],"risk_factors":[]}`,
+	}, nil)
+	service.On("Chat", mock.Anything, TaskAnalysis, isPart(2)).Return(&ChatResponse{
+		Content: `{"buyer":"","seller":"","total_value":0,"currency":"","milestones":[
+			{"description":"Deposit","amount":0,"percentage":30},{"description":"Balance","amount":0,"percentage":70}],"risk_factors":[]}`,
+	}, nil)
+
+	analysis, err := analyzer.AnalyzeContract(context.Background(), TaskAnalysis, text)
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Corp", analysis.Buyer)
+	require.Len(t, analysis.Milestones, 2)
+	assert.Equal(t, []int{1}, analysis.Milestones[0].SourceChunks)
+	service.AssertNumberOfCalls(t, "Chat", 2)
+}
+
+func TestContractAnalyzer_IgnoresInvalidChunkTokens(t *testing.T) {
+	service := new(MockLLMService)
+	analyzer := NewContractAnalyzer(service, NewPromptEngine())
+	analyzer.SetChunkTokens(0)
+	analyzer.SetChunkTokens(-1)
+	assert.Equal(t, defaultChunkTokens, analyzer.chunkTokens)
+
+	service.On("Chat", mock.Anything, TaskAnalysis, mock.Anything).Return(&ChatResponse{
+		Content: `{"buyer":"Acme Corp","seller":"Globex Ltd","total_value":0,"currency":"","milestones":[],"risk_factors":[]}`,
+	}, nil)
+	analysis, err := analyzer.AnalyzeContract(context.Background(), TaskAnalysis, "Acme Corp buys widgets from Globex Ltd.")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Corp", analysis.Buyer)
+	service.AssertNumberOfCalls(t, "Chat", 1)
+}
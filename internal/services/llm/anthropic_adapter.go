@@ -0,0 +1,387 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"contract-analysis-service/internal/pkg/external"
+)
+
+const (
+	// DefaultAnthropicModel is used by AnthropicAdapter when neither the
+	// request nor the adapter names a model.
+	DefaultAnthropicModel = "claude-3-5-sonnet-latest"
+	// anthropicDefaultMaxTokens is sent when the request does not set a limit;
+	// the Messages API requires one.
+	anthropicDefaultMaxTokens = 4096
+	// anthropicJSONInstruction stands in for response_format, which the
+	// Messages API does not have.
+	anthropicJSONInstruction = "Respond with a single JSON object and nothing else."
+)
+
+// AnthropicAdapter speaks the Anthropic Messages API format.
+type AnthropicAdapter struct {
+	// DefaultModel is used when the request does not name a model.
+	DefaultModel string
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicRequest struct {
+	Model       string               `json:"model"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature float64              `json:"temperature,omitempty"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Stream      bool                 `json:"stream,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+type anthropicErrorBody struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type anthropicResponse struct {
+	ID         string              `json:"id"`
+	Model      string              `json:"model"`
+	Content    []anthropicBlock    `json:"content"`
+	StopReason string              `json:"stop_reason"`
+	Usage      anthropicUsage      `json:"usage"`
+	Error      *anthropicErrorBody `json:"error"`
+}
+
+// BuildChatRequest translates a chat request to a /v1/messages call. System
+// messages become the system prompt and tool results are sent as user turns,
+// as the Messages API requires.
+func (a AnthropicAdapter) BuildChatRequest(req *ChatRequest) (*external.Request, error) {
+	return a.buildRequest(a.payload(req))
+}
+
+// BuildStreamRequest translates a chat request to a streamed /v1/messages call.
+func (a AnthropicAdapter) BuildStreamRequest(req *ChatRequest) (*external.Request, error) {
+	payload := a.payload(req)
+	payload.Stream = true
+	httpReq, err := a.buildRequest(payload)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.IsStreaming = true
+	return httpReq, nil
+}
+
+// NewStreamDecoder returns a decoder for a streamed message.
+func (a AnthropicAdapter) NewStreamDecoder() StreamDecoder {
+	return &anthropicStreamDecoder{}
+}
+
+func (a AnthropicAdapter) payload(req *ChatRequest) anthropicRequest {
+	model := req.Model
+	if model == "" {
+		model = a.DefaultModel
+	}
+	if model == "" {
+		model = DefaultAnthropicModel
+	}
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
+
+	payload := anthropicRequest{
+		Model:       model,
+		MaxTokens:   maxTokens,
+		Temperature: req.Temperature,
+	}
+
+	var system []string
+	for _, m := range req.Messages {
+		var role string
+		var blocks []anthropicBlock
+		switch m.Role {
+		case RoleSystem:
+			system = append(system, m.Content)
+			continue
+		case RoleTool:
+			role = RoleUser
+			blocks = []anthropicBlock{{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content}}
+		default:
+			role = m.Role
+			if m.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				input := call.Arguments
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+			}
+		}
+
+		// Roles must alternate, so consecutive turns of one role are merged
+		if n := len(payload.Messages); n > 0 && payload.Messages[n-1].Role == role {
+			payload.Messages[n-1].Content = append(payload.Messages[n-1].Content, blocks...)
+			continue
+		}
+		payload.Messages = append(payload.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	if req.ResponseFormat == ResponseFormatJSON {
+		system = append(system, anthropicJSONInstruction)
+	}
+	payload.System = strings.Join(system, "\n\n")
+
+	for _, tool := range req.Tools {
+		schema := tool.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object"}`)
+		}
+		payload.Tools = append(payload.Tools, anthropicTool{Name: tool.Name, Description: tool.Description, InputSchema: schema})
+	}
+	if req.ToolChoice != "" {
+		payload.ToolChoice = &anthropicToolChoice{Type: "tool", Name: req.ToolChoice}
+	}
+	return payload
+}
+
+func (a AnthropicAdapter) buildRequest(payload anthropicRequest) (*external.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	return &external.Request{
+		Method:  "POST",
+		URL:     "/v1/messages",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    body,
+	}, nil
+}
+
+// anthropicErrorKinds maps the Messages API error types.
+var anthropicErrorKinds = map[string]ErrorKind{
+	"invalid_request_error": ErrorKindInvalidRequest,
+	"authentication_error":  ErrorKindAuthentication,
+	"permission_error":      ErrorKindPermission,
+	"not_found_error":       ErrorKindNotFound,
+	"request_too_large":     ErrorKindTooLarge,
+	"rate_limit_error":      ErrorKindRateLimited,
+	"overloaded_error":      ErrorKindOverloaded,
+	"api_error":             ErrorKindServer,
+}
+
+// anthropicStopReasons maps the Messages API stop reasons.
+var anthropicStopReasons = map[string]string{
+	"end_turn":      FinishReasonStop,
+	"stop_sequence": FinishReasonStop,
+	"max_tokens":    FinishReasonLength,
+	"tool_use":      FinishReasonToolCalls,
+	"refusal":       FinishReasonContentFilter,
+}
+
+// ParseChatResponse reads the text and tool use blocks of a message. Stop
+// reasons are normalized to the OpenAI finish reasons and errors are
+// returned as *ProviderError.
+func (a AnthropicAdapter) ParseChatResponse(resp *external.Response) (*ChatResponse, error) {
+	var parsed anthropicResponse
+	decodeErr := json.Unmarshal(resp.Body, &parsed)
+	if parsed.Error != nil {
+		return nil, anthropicError(resp.StatusCode, parsed.Error)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, &ProviderError{Kind: kindForStatus(resp.StatusCode), StatusCode: resp.StatusCode, Message: truncate(string(resp.Body), 200)}
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to parse LLM response: %w", decodeErr)
+	}
+	return parsed.chatResponse(), nil
+}
+
+// anthropicError builds the error for an error reported by the Messages API.
+func anthropicError(status int, body *anthropicErrorBody) *ProviderError {
+	kind, ok := anthropicErrorKinds[body.Type]
+	if !ok {
+		kind = kindForStatus(status)
+	}
+	return &ProviderError{Kind: kind, StatusCode: status, Type: body.Type, Message: body.Message}
+}
+
+// chatResponse translates a message to a chat response.
+func (parsed *anthropicResponse) chatResponse() *ChatResponse {
+	finishReason, ok := anthropicStopReasons[parsed.StopReason]
+	if !ok {
+		finishReason = parsed.StopReason
+	}
+	// Cached prompt tokens are reported apart from input_tokens
+	promptTokens := parsed.Usage.InputTokens + parsed.Usage.CacheCreationInputTokens + parsed.Usage.CacheReadInputTokens
+	chatResp := &ChatResponse{
+		ID:           parsed.ID,
+		Model:        parsed.Model,
+		FinishReason: finishReason,
+		Usage: Usage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: parsed.Usage.OutputTokens,
+			TotalTokens:      promptTokens + parsed.Usage.OutputTokens,
+		},
+	}
+	var text strings.Builder
+	for _, block := range parsed.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			chatResp.ToolCalls = append(chatResp.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: block.Input})
+		}
+	}
+	chatResp.Content = text.String()
+	return chatResp
+}
+
+type anthropicStreamEvent struct {
+	Type         string             `json:"type"`
+	Message      *anthropicResponse `json:"message"`
+	Index        int                `json:"index"`
+	ContentBlock *anthropicBlock    `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage     `json:"usage"`
+	Error *anthropicErrorBody `json:"error"`
+}
+
+// anthropicStreamDecoder assembles a message from its stream of events.
+type anthropicStreamDecoder struct {
+	message *anthropicResponse
+	// inputs accumulates the input of tool use blocks by block index
+	inputs map[int]*strings.Builder
+}
+
+// Decode reads one event of the message stream.
+func (d *anthropicStreamDecoder) Decode(data []byte) (*ChatDelta, error) {
+	var event anthropicStreamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM stream event: %w", err)
+	}
+	if event.Type == "error" && event.Error != nil {
+		// Errors after the stream started arrive with a 200 status
+		return nil, anthropicError(http.StatusOK, event.Error)
+	}
+	if event.Type == "message_start" && event.Message != nil {
+		d.message = event.Message
+		return nil, nil
+	}
+	if d.message == nil {
+		return nil, nil
+	}
+
+	switch event.Type {
+	case "content_block_start":
+		if event.ContentBlock != nil {
+			d.block(event.Index).Type = event.ContentBlock.Type
+			d.block(event.Index).ID = event.ContentBlock.ID
+			d.block(event.Index).Name = event.ContentBlock.Name
+		}
+	case "content_block_delta":
+		switch {
+		case event.Delta.Type == "text_delta" && event.Delta.Text != "":
+			block := d.block(event.Index)
+			block.Text += event.Delta.Text
+			return &ChatDelta{Content: event.Delta.Text}, nil
+		case event.Delta.Type == "input_json_delta" && event.Delta.PartialJSON != "":
+			if d.inputs == nil {
+				d.inputs = make(map[int]*strings.Builder)
+			}
+			input, ok := d.inputs[event.Index]
+			if !ok {
+				input = &strings.Builder{}
+				d.inputs[event.Index] = input
+			}
+			input.WriteString(event.Delta.PartialJSON)
+			return &ChatDelta{Arguments: event.Delta.PartialJSON, ToolCall: d.toolCall(event.Index)}, nil
+		}
+	case "message_delta":
+		if event.Delta.StopReason != "" {
+			d.message.StopReason = event.Delta.StopReason
+		}
+		if event.Usage != nil {
+			// The output token count is cumulative
+			d.message.Usage.OutputTokens = event.Usage.OutputTokens
+		}
+	}
+	return nil, nil
+}
+
+// block returns the content block at index, adding blocks up to it.
+func (d *anthropicStreamDecoder) block(index int) *anthropicBlock {
+	for len(d.message.Content) <= index {
+		d.message.Content = append(d.message.Content, anthropicBlock{Type: "text"})
+	}
+	return &d.message.Content[index]
+}
+
+// toolCall returns the index among the tool calls of the response of the
+// tool use block at index.
+func (d *anthropicStreamDecoder) toolCall(index int) int {
+	n := 0
+	for i := 0; i < index && i < len(d.message.Content); i++ {
+		if d.message.Content[i].Type == "tool_use" {
+			n++
+		}
+	}
+	return n
+}
+
+// Response returns the message assembled from the events.
+func (d *anthropicStreamDecoder) Response() (*ChatResponse, error) {
+	if d.message == nil {
+		return nil, fmt.Errorf("no message_start event in LLM stream")
+	}
+	for i := range d.message.Content {
+		block := &d.message.Content[i]
+		if block.Type != "tool_use" {
+			continue
+		}
+		// The input of the start event is empty; the deltas carry it
+		block.Input = json.RawMessage("{}")
+		if input, ok := d.inputs[i]; ok {
+			block.Input = json.RawMessage(input.String())
+		}
+	}
+	return d.message.chatResponse(), nil
+}
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"contract-analysis-service/internal/pkg/external"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const cassetteContract = `SERVICES AGREEMENT between Acme Corp (the "Client") and Globex Ltd (the "Provider").
+1. Fees. The Client shall pay USD 10,000: 30% on signing and 70% on acceptance of the deliverables.
+2. Liability. The Provider's liability is unlimited.`
+
+// newCassetteService returns a service whose Claude client replays the
+// traffic recorded in testdata/cassettes. Run the test with
+// LLM_CASSETTE_MODE=record and ANTHROPIC_API_KEY set to record it again after
+// changing a prompt or the request payload.
+func newCassetteService(t *testing.T) Service {
+	t.Helper()
+	mode := os.Getenv("LLM_CASSETTE_MODE")
+	if mode == "" {
+		mode = external.CassetteReplay
+	}
+	baseURL := os.Getenv("ANTHROPIC_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	httpClient := external.NewHTTPClient(baseURL, "Anthropic", external.RetryConfig{}, 2*time.Minute)
+	cassette, err := external.NewCassette(httpClient, filepath.Join("testdata", "cassettes", "anthropic"), mode)
+	require.NoError(t, err)
+
+	service := NewLLMService(zap.NewNop())
+	service.AddClient("anthropic", NewClaudeClient(cassette, os.Getenv("ANTHROPIC_API_KEY")))
+	return service
+}
+
+// TestPipeline_RecordedTraffic runs the analysis stages through the real
+// prompt rendering, request building and response parsing. A change to the
+// payload sent to the provider fails it with ErrCassetteMiss.
+func TestPipeline_RecordedTraffic(t *testing.T) {
+	service := newCassetteService(t)
+	engine := NewPromptEngine()
+	ctx := context.Background()
+
+	analysis, err := NewContractAnalyzer(service, engine).AnalyzeContract(ctx, "anthropic", cassetteContract)
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Corp", analysis.Buyer)
+	assert.Equal(t, "Globex Ltd", analysis.Seller)
+	assert.True(t, analysis.TotalValue.Equal(decimal.NewFromInt(10000)))
+	require.Len(t, analysis.Milestones, 2)
+	assert.Equal(t, 70.0, analysis.Milestones[1].Percentage)
+
+	sequenced, err := NewMilestoneSequencer(service, engine).SequenceMilestones(ctx, "anthropic", analysis.Milestones)
+	require.NoError(t, err)
+	require.Len(t, sequenced, 2)
+	assert.Equal(t, []string{sequenced[0].ID}, sequenced[1].Dependencies)
+
+	risks, err := NewRiskAssessor(service, engine).AssessRisks(ctx, "anthropic", cassetteContract, "")
+	require.NoError(t, err)
+	require.Len(t, risks.Risks, 1)
+	assert.Equal(t, "critical", risks.Risks[0].Severity)
+
+	compliance, err := NewComplianceChecker(service, engine).CheckCompliance(ctx, "anthropic", cassetteContract, "international")
+	require.NoError(t, err)
+	assert.Equal(t, "partial", compliance.ComplianceLevel)
+
+	if os.Getenv("LLM_CASSETTE_MODE") == "" {
+		// A request that was never recorded fails instead of reaching the network
+		_, err = NewContractAnalyzer(service, engine).AnalyzeContract(ctx, "anthropic", cassetteContract+"\n3. Term. One year.")
+		assert.ErrorIs(t, err, external.ErrCassetteMiss)
+	}
+}
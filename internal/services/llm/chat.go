@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"contract-analysis-service/internal/pkg/external"
+	"contract-analysis-service/internal/pkg/jsonschema"
+)
+
+// Message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// ResponseFormat constrains the shape of the model output.
+type ResponseFormat string
+
+const (
+	// ResponseFormatText leaves the output unconstrained.
+	ResponseFormatText ResponseFormat = ""
+	// ResponseFormatJSON asks for a single JSON object.
+	ResponseFormatJSON ResponseFormat = "json_object"
+)
+
+// Finish reasons, normalized to the OpenAI values
+const (
+	FinishReasonStop          = "stop"
+	FinishReasonLength        = "length"
+	FinishReasonToolCalls     = "tool_calls"
+	FinishReasonContentFilter = "content_filter"
+)
+
+// ChatRequest is a provider-agnostic chat completion request.
+type ChatRequest struct {
+	// Model overrides the provider's default model when set.
+	Model    string
+	Messages []Message
+	// Temperature is left to the provider default when zero.
+	Temperature float64
+	// MaxTokens is left to the provider default when zero.
+	MaxTokens      int
+	ResponseFormat ResponseFormat
+	// Schema describes the expected output. Adapters for providers that can
+	// enforce a schema send it; others rely on the prompt. See ChatJSON.
+	Schema     *jsonschema.Schema
+	SchemaName string
+	Tools      []Tool
+	// ToolChoice names a tool of Tools the model must call. The model may
+	// answer or call any of the tools when it is empty.
+	ToolChoice string
+}
+
+// Message is a single chat message. Assistant messages may carry tool calls,
+// and tool messages carry the result of the call named by ToolCallID.
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// Tool describes a function the model may call. Parameters is a JSON Schema.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// ToolCall is a function call requested by the model.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ChatResponse is a provider-agnostic chat completion response.
+type ChatResponse struct {
+	ID           string     `json:"id"`
+	Provider     string     `json:"provider"`
+	Model        string     `json:"model"`
+	Content      string     `json:"content"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+	FinishReason string     `json:"finish_reason"`
+	Usage        Usage      `json:"usage"`
+}
+
+// Usage is the token usage reported by the provider.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// DecodeJSON unmarshals the JSON in the response content into v, ignoring
+// code fences and prose around it.
+func (r *ChatResponse) DecodeJSON(v interface{}) error {
+	content := ExtractJSON(r.Content)
+	if content == "" {
+		content = r.Content
+	}
+	if err := json.Unmarshal([]byte(content), v); err != nil {
+		return fmt.Errorf("failed to decode LLM response content: %w", err)
+	}
+	return nil
+}
+
+// ChatAdapter translates chat requests and responses to and from a provider's
+// wire format. Clients registered with the service may implement it; clients
+// that do not are assumed to speak the OpenAI format.
+type ChatAdapter interface {
+	BuildChatRequest(req *ChatRequest) (*external.Request, error)
+	ParseChatResponse(resp *external.Response) (*ChatResponse, error)
+}
+
+// NewChatRequest returns a request with an optional system prompt followed by
+// a user prompt, the shape used by every analysis call.
+func NewChatRequest(systemPrompt, userPrompt string) *ChatRequest {
+	req := &ChatRequest{}
+	if systemPrompt != "" {
+		req.Messages = append(req.Messages, Message{Role: RoleSystem, Content: systemPrompt})
+	}
+	req.Messages = append(req.Messages, Message{Role: RoleUser, Content: userPrompt})
+	return req
+}
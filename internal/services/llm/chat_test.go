@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"contract-analysis-service/internal/pkg/external"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLLMService_Chat_OpenAIFormat(t *testing.T) {
+	mockClient := new(external.MockClient)
+	service := NewLLMService(zap.NewNop())
+	service.AddClient("openai", mockClient)
+
+	var sent map[string]interface{}
+	mockClient.On("ExecuteRequest", mock.Anything, mock.MatchedBy(func(req *external.Request) bool {
+		return req.URL == "/chat/completions" && json.Unmarshal(req.Body, &sent) == nil
+	})).Return(&external.Response{
+		StatusCode: 200,
+		Body: []byte(`{"id":"cmpl-1","model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"{\"ok\":true}",
+			"tool_calls":[{"id":"call_1","type":"function","function":{"name":"lookup","arguments":"{\"q\":\"x\"}"}}]},
+			"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`),
+	}, nil)
+
+	req := NewChatRequest("Be precise.", "Hello")
+	req.Temperature = 0.2
+	req.ResponseFormat = ResponseFormatJSON
+	req.Tools = []Tool{{Name: "lookup", Parameters: json.RawMessage(`{"type":"object"}`)}}
+
+	resp, err := service.Chat(context.Background(), "openai", req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "gpt-4o", sent["model"])
+	assert.Equal(t, 0.2, sent["temperature"])
+	assert.Equal(t, map[string]interface{}{"type": "json_object"}, sent["response_format"])
+	assert.Len(t, sent["messages"], 2)
+	assert.Len(t, sent["tools"], 1)
+
+	assert.Equal(t, "openai", resp.Provider)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, resp.Usage)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "lookup", resp.ToolCalls[0].Name)
+	assert.JSONEq(t, `{"q":"x"}`, string(resp.ToolCalls[0].Arguments))
+
+	var decoded struct{ OK bool }
+	require.NoError(t, resp.DecodeJSON(&decoded))
+	assert.True(t, decoded.OK)
+}
+
+func TestLLMService_Chat_ErrorStatus(t *testing.T) {
+	mockClient := new(external.MockClient)
+	service := NewLLMService(zap.NewNop())
+	service.AddClient("openrouter", mockClient)
+
+	mockClient.On("ExecuteRequest", mock.Anything, mock.Anything).Return(&external.Response{
+		StatusCode: 429,
+		Body:       []byte(`{"error":{"message":"Rate limit exceeded","code":429}}`),
+	}, nil)
+
+	_, err := service.Chat(context.Background(), "openrouter", NewChatRequest("", "Hello"))
+	assert.ErrorContains(t, err, "Rate limit exceeded")
+	assert.ErrorIs(t, err, ErrRateLimited)
+}
+
+func TestAnthropicAdapter_BuildChatRequest(t *testing.T) {
+	req := &ChatRequest{
+		Messages: []Message{
+			{Role: RoleSystem, Content: "You review contracts."},
+			{Role: RoleUser, Content: "Find the parties."},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "tu_1", Name: "search", Arguments: json.RawMessage(`{"q":"party"}`)}}},
+			{Role: RoleTool, ToolCallID: "tu_1", Content: "Acme and Globex"},
+			{Role: RoleUser, Content: "Answer now."},
+		},
+		ResponseFormat: ResponseFormatJSON,
+		Tools:          []Tool{{Name: "search"}},
+		ToolChoice:     "search",
+	}
+
+	httpReq, err := AnthropicAdapter{}.BuildChatRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/messages", httpReq.URL)
+
+	var sent anthropicRequest
+	require.NoError(t, json.Unmarshal(httpReq.Body, &sent))
+	assert.Equal(t, DefaultAnthropicModel, sent.Model)
+	assert.Equal(t, anthropicDefaultMaxTokens, sent.MaxTokens)
+	assert.Equal(t, "You review contracts.\n\n"+anthropicJSONInstruction, sent.System)
+	require.Len(t, sent.Tools, 1)
+	assert.JSONEq(t, `{"type":"object"}`, string(sent.Tools[0].InputSchema))
+	assert.Equal(t, &anthropicToolChoice{Type: "tool", Name: "search"}, sent.ToolChoice)
+
+	// The tool result and the following user turn are merged into one user message
+	require.Len(t, sent.Messages, 3)
+	assert.Equal(t, RoleUser, sent.Messages[0].Role)
+	assert.Equal(t, "tool_use", sent.Messages[1].Content[0].Type)
+	assert.Equal(t, RoleUser, sent.Messages[2].Role)
+	require.Len(t, sent.Messages[2].Content, 2)
+	assert.Equal(t, "tool_result", sent.Messages[2].Content[0].Type)
+	assert.Equal(t, "tu_1", sent.Messages[2].Content[0].ToolUseID)
+	assert.Equal(t, "Answer now.", sent.Messages[2].Content[1].Text)
+}
+
+func TestAnthropicAdapter_ParseChatResponse(t *testing.T) {
+	resp, err := AnthropicAdapter{}.ParseChatResponse(&external.Response{
+		StatusCode: 200,
+		Body: []byte(`{"id":"msg_1","model":"claude","stop_reason":"tool_use",
+			"content":[{"type":"text","text":"Looking it up."},{"type":"tool_use","id":"tu_1","name":"search","input":{"q":"x"}}],
+			"usage":{"input_tokens":12,"output_tokens":8}}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Looking it up.", resp.Content)
+	assert.Equal(t, FinishReasonToolCalls, resp.FinishReason)
+	assert.Equal(t, 20, resp.Usage.TotalTokens)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.JSONEq(t, `{"q":"x"}`, string(resp.ToolCalls[0].Arguments))
+}
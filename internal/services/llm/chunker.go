@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken is the rough number of characters of English legal text per
+// token, used where an exact tokenizer count is not needed.
+const charsPerToken = 4
+
+// clauseStart matches a line that opens a clause, article, section or
+// schedule, e.g. "12.3 Payment", "ARTICLE IV" or "Schedule 2".
+var clauseStart = regexp.MustCompile(`(?m)^[ \t]*(?:(?i:article|section|clause|schedule|exhibit|annex|appendix)\b|\d+(?:\.\d+)*[.)]?[ \t]+\S)`)
+
+// Chunk is a contiguous part of a document.
+type Chunk struct {
+	// Index is the position of the chunk in the document, from 0.
+	Index int
+	Text  string
+	// Start and End are the byte offsets of Text in the document.
+	Start int
+	End   int
+}
+
+// EstimateTokens returns an estimate of the number of tokens in text.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// ChunkText splits text into chunks of at most maxTokens estimated tokens.
+// Chunks end on clause boundaries where possible, then on paragraph breaks,
+// and only split a paragraph that is too long on its own at whitespace.
+func ChunkText(text string, maxTokens int) []Chunk {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	maxChars := maxTokens * charsPerToken
+
+	var chunks []Chunk
+	start, end := 0, 0
+	flush := func() {
+		if strings.TrimSpace(text[start:end]) != "" {
+			chunks = append(chunks, Chunk{Index: len(chunks), Text: text[start:end], Start: start, End: end})
+		}
+		start = end
+	}
+	for _, seg := range segments(text, maxChars) {
+		if end > start && utf8.RuneCountInString(text[start:seg]) > maxChars {
+			flush()
+		}
+		end = seg
+	}
+	flush()
+	return chunks
+}
+
+// segments returns the end offsets of the pieces text may be cut into, none
+// longer than maxChars: clauses, or paragraphs and then words of clauses that
+// are too long.
+func segments(text string, maxChars int) []int {
+	var ends []int
+	for _, clause := range boundaries(text, clauseStart.FindAllStringIndex(text, -1)) {
+		if utf8.RuneCountInString(text[clause[0]:clause[1]]) <= maxChars {
+			ends = append(ends, clause[1])
+			continue
+		}
+		for _, para := range paragraphs(text, clause[0], clause[1]) {
+			if utf8.RuneCountInString(text[para[0]:para[1]]) <= maxChars {
+				ends = append(ends, para[1])
+				continue
+			}
+			ends = append(ends, words(text, para[0], para[1], maxChars)...)
+		}
+	}
+	return ends
+}
+
+// boundaries turns the start offsets of matches into [start, end) pieces
+// covering all of text.
+func boundaries(text string, matches [][]int) [][2]int {
+	var pieces [][2]int
+	prev := 0
+	for _, m := range matches {
+		if m[0] > prev {
+			pieces = append(pieces, [2]int{prev, m[0]})
+			prev = m[0]
+		}
+	}
+	return append(pieces, [2]int{prev, len(text)})
+}
+
+func paragraphs(text string, start, end int) [][2]int {
+	var pieces [][2]int
+	for start < end {
+		i := strings.Index(text[start:end], "\n\n")
+		if i < 0 {
+			break
+		}
+		pieces = append(pieces, [2]int{start, start + i + 2})
+		start += i + 2
+	}
+	if start < end {
+		pieces = append(pieces, [2]int{start, end})
+	}
+	return pieces
+}
+
+// words returns cut points in text[start:end] at most maxChars apart,
+// preferring whitespace.
+func words(text string, start, end, maxChars int) []int {
+	var ends []int
+	for start < end {
+		limit := start
+		for n := 0; limit < end && n < maxChars; n++ {
+			_, size := utf8.DecodeRuneInString(text[limit:end])
+			limit += size
+		}
+		if limit < end {
+			if i := strings.LastIndexAny(text[start:limit], " \t\n"); i > 0 {
+				limit = start + i + 1
+			}
+		}
+		ends = append(ends, limit)
+		start = limit
+	}
+	return ends
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkText_ClauseBoundaries(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("MASTER SUPPLY AGREEMENT between Acme Corp and Globex Ltd.\n\n")
+	for i := 1; i <= 12; i++ {
+		fmt.Fprintf(&b, "%d. Clause %d\nThe parties agree to the terms of clause %d, which are set out here in some detail.\n\n", i, i, i)
+	}
+	text := b.String()
+
+	chunks := ChunkText(text, 60)
+	require.Greater(t, len(chunks), 1)
+
+	var rebuilt strings.Builder
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.Index)
+		assert.Equal(t, text[chunk.Start:chunk.End], chunk.Text)
+		assert.LessOrEqual(t, EstimateTokens(chunk.Text), 60)
+		if i > 0 {
+			// Every chunk after the first opens with a clause heading
+			assert.Regexp(t, `^\d+\. Clause`, chunk.Text)
+		}
+		rebuilt.WriteString(chunk.Text)
+	}
+	assert.Equal(t, text, rebuilt.String())
+}
+
+func TestChunkText_SplitsLongParagraphsAtWhitespace(t *testing.T) {
+	text := strings.Repeat("indemnify ", 100)
+
+	chunks := ChunkText(text, 25)
+	require.Len(t, chunks, 10)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, len(chunk.Text), 100)
+		assert.True(t, strings.HasSuffix(chunk.Text, " "))
+	}
+	assert.Empty(t, ChunkText(" \n\n ", 25))
+}
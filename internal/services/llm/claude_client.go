@@ -6,8 +6,12 @@ import (
 	"contract-analysis-service/internal/pkg/external"
 )
 
+// AnthropicVersion is the Messages API version sent with every request.
+const AnthropicVersion = "2023-06-01"
+
 // ClaudeClient implements Client for Anthropic Claude API
 type ClaudeClient struct {
+	AnthropicAdapter
 	client external.Client
 	apiKey string
 }
@@ -23,8 +27,11 @@ func NewClaudeClient(client external.Client, apiKey string) *ClaudeClient {
 // ExecuteRequest executes a request to Claude API
 func (c *ClaudeClient) ExecuteRequest(ctx context.Context, req *external.Request) (*external.Response, error) {
 	// Set Claude-specific headers
+	if req.Headers == nil {
+		req.Headers = make(map[string]string)
+	}
 	req.Headers["x-api-key"] = c.apiKey
-	req.Headers["anthropic-version"] = "2023-06-01"
+	req.Headers["anthropic-version"] = AnthropicVersion
 	req.Headers["Content-Type"] = "application/json"
 
 	return c.client.ExecuteRequest(ctx, req)
@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"contract-analysis-service/internal/pkg/external"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newAnthropicFixtureServer serves the recorded response in
+// testdata/anthropic/<fixture>.json with the given status, and records the
+// request it received.
+func newAnthropicFixtureServer(t *testing.T, status int, fixture string) (*httptest.Server, *http.Request, *anthropicRequest) {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "anthropic", fixture+".json"))
+	require.NoError(t, err)
+
+	received := &http.Request{}
+	sent := &anthropicRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = *r.Clone(r.Context())
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, sent)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, received, sent
+}
+
+func newClaudeService(baseURL string) Service {
+	service := NewLLMService(zap.NewNop())
+	httpClient := external.NewHTTPClient(baseURL, "Anthropic", external.RetryConfig{}, 5*time.Second)
+	service.AddClient("anthropic", NewClaudeClient(httpClient, "test-key"))
+	return service
+}
+
+func TestClaudeClient_Chat_Message(t *testing.T) {
+	server, received, sent := newAnthropicFixtureServer(t, http.StatusOK, "message")
+
+	req := NewChatRequest("You review contracts.", "Who are the parties?")
+	req.ResponseFormat = ResponseFormatJSON
+
+	resp, err := newClaudeService(server.URL).Chat(context.Background(), "anthropic", req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/v1/messages", received.URL.Path)
+	assert.Equal(t, "test-key", received.Header.Get("x-api-key"))
+	assert.Equal(t, AnthropicVersion, received.Header.Get("anthropic-version"))
+	assert.Equal(t, "You review contracts.\n\n"+anthropicJSONInstruction, sent.System)
+	assert.Equal(t, anthropicDefaultMaxTokens, sent.MaxTokens)
+	require.Len(t, sent.Messages, 1)
+	assert.Equal(t, RoleUser, sent.Messages[0].Role)
+
+	assert.Equal(t, "anthropic", resp.Provider)
+	assert.Equal(t, "msg_01XFDUDYJgAACzvnptvVoYEL", resp.ID)
+	assert.Equal(t, FinishReasonStop, resp.FinishReason)
+	assert.Equal(t, Usage{PromptTokens: 540, CompletionTokens: 19, TotalTokens: 559}, resp.Usage)
+
+	var decoded struct{ Parties []string }
+	require.NoError(t, resp.DecodeJSON(&decoded))
+	assert.Equal(t, []string{"Acme Corp", "Globex Ltd"}, decoded.Parties)
+}
+
+func TestClaudeClient_Chat_StopReasons(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		want      string
+		toolCalls int
+	}{
+		{fixture: "max_tokens", want: FinishReasonLength},
+		{fixture: "tool_use", want: FinishReasonToolCalls, toolCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			server, _, _ := newAnthropicFixtureServer(t, http.StatusOK, tt.fixture)
+
+			resp, err := newClaudeService(server.URL).Chat(context.Background(), "anthropic", NewChatRequest("", "Hello"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, resp.FinishReason)
+			assert.Len(t, resp.ToolCalls, tt.toolCalls)
+		})
+	}
+}
+
+func TestClaudeClient_Chat_Errors(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		status    int
+		want      error
+		retryable bool
+	}{
+		{fixture: "overloaded_error", status: 529, want: ErrOverloaded, retryable: true},
+		{fixture: "rate_limit_error", status: http.StatusTooManyRequests, want: ErrRateLimited, retryable: true},
+		{fixture: "invalid_request_error", status: http.StatusBadRequest, want: ErrInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			server, _, _ := newAnthropicFixtureServer(t, tt.status, tt.fixture)
+
+			_, err := newClaudeService(server.URL).Chat(context.Background(), "anthropic", NewChatRequest("", "Hello"))
+			require.Error(t, err)
+			assert.ErrorIs(t, err, tt.want)
+
+			var providerErr *ProviderError
+			require.ErrorAs(t, err, &providerErr)
+			assert.Equal(t, tt.status, providerErr.StatusCode)
+			assert.Equal(t, tt.fixture, providerErr.Type)
+			assert.Equal(t, tt.retryable, providerErr.Retryable())
+		})
+	}
+}
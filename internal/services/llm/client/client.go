@@ -4,10 +4,12 @@ import (
 	"context"
 
 	"contract-analysis-service/internal/pkg/external"
+	"contract-analysis-service/internal/services/llm"
 )
 
 const (
 	OpenRouterProvider = "openrouter"
+	AnthropicProvider  = "anthropic"
 	DefaultModel     = "qwen/qwen-2.5-vl-72b-instruct:free"
 )
 
@@ -47,3 +49,24 @@ func (c *OpenRouterClient) ExecuteRequest(ctx context.Context, req *external.Req
 
 	return c.client.ExecuteRequest(ctx, req)
 }
+
+// BuildChatRequest translates a chat request to OpenRouter's OpenAI-compatible
+// format, using the configured model unless the request names one.
+func (c *OpenRouterClient) BuildChatRequest(req *llm.ChatRequest) (*external.Request, error) {
+	return llm.NewOpenRouterAdapter(c.model).BuildChatRequest(req)
+}
+
+// ParseChatResponse reads an OpenRouter chat completion.
+func (c *OpenRouterClient) ParseChatResponse(resp *external.Response) (*llm.ChatResponse, error) {
+	return llm.NewOpenRouterAdapter(c.model).ParseChatResponse(resp)
+}
+
+// BuildStreamRequest translates a chat request to a streamed OpenRouter call.
+func (c *OpenRouterClient) BuildStreamRequest(req *llm.ChatRequest) (*external.Request, error) {
+	return llm.NewOpenRouterAdapter(c.model).BuildStreamRequest(req)
+}
+
+// NewStreamDecoder returns a decoder for a streamed OpenRouter chat completion.
+func (c *OpenRouterClient) NewStreamDecoder() llm.StreamDecoder {
+	return llm.NewOpenRouterAdapter(c.model).NewStreamDecoder()
+}
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"path/filepath"
+
 	"contract-analysis-service/configs"
 	"contract-analysis-service/internal/pkg/external"
 	"contract-analysis-service/internal/services/llm"
@@ -8,7 +10,7 @@ import (
 
 // AddOpenRouterClientToService creates a new OpenRouter client and adds it to the LLM service.
 // This function encapsulates the client creation and registration logic.
-func AddOpenRouterClientToService(service llm.Service, cfg *configs.Config) {
+func AddOpenRouterClientToService(service llm.Service, cfg *configs.Config) error {
 	// Create a new resilient HTTP client for the OpenRouter API.
 	resilientClient := external.NewHTTPClient(
 		cfg.LLM.OpenRouter.BaseURL,
@@ -20,10 +22,51 @@ func AddOpenRouterClientToService(service llm.Service, cfg *configs.Config) {
 		},
 		cfg.LLM.OpenRouter.Timeout,
 	)
+	httpClient, err := withCassette(resilientClient, cfg.LLM.Cassette, OpenRouterProvider)
+	if err != nil {
+		return err
+	}
 
 	// Create the OpenRouter-specific client.
-	openRouterClient := NewOpenRouterClient(resilientClient, cfg.LLM.OpenRouter.APIKey)
+	openRouterClient := NewOpenRouterClient(httpClient, cfg.LLM.OpenRouter.APIKey)
 
 	// Add the client to the LLM service.
 	service.AddClient(OpenRouterProvider, openRouterClient)
+	return nil
+}
+
+// AddAnthropicClientToService registers a Claude client for the Anthropic
+// Messages API when an API key is configured, or when replaying recorded
+// traffic, which needs none.
+func AddAnthropicClientToService(service llm.Service, cfg *configs.Config) error {
+	if cfg.LLM.Anthropic.APIKey == "" && cfg.LLM.Cassette.Mode != external.CassetteReplay {
+		return nil
+	}
+
+	resilientClient := external.NewHTTPClient(
+		cfg.LLM.Anthropic.BaseURL,
+		"Anthropic",
+		external.RetryConfig{
+			MaxRetries:      cfg.LLM.Anthropic.RetryCount,
+			InitialInterval: cfg.LLM.Anthropic.RetryWaitTime,
+			MaxInterval:     cfg.LLM.Anthropic.RetryMaxInterval,
+		},
+		cfg.LLM.Anthropic.Timeout,
+	)
+	httpClient, err := withCassette(resilientClient, cfg.LLM.Cassette, AnthropicProvider)
+	if err != nil {
+		return err
+	}
+
+	service.AddClient(AnthropicProvider, llm.NewClaudeClient(httpClient, cfg.LLM.Anthropic.APIKey))
+	return nil
+}
+
+// withCassette wraps client in a cassette for the provider when recording or
+// replaying is configured.
+func withCassette(client external.Client, cfg configs.CassetteConfig, provider string) (external.Client, error) {
+	if cfg.Mode == "" {
+		return client, nil
+	}
+	return external.NewCassette(client, filepath.Join(cfg.Dir, provider), cfg.Mode)
 }
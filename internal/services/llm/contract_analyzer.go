@@ -2,17 +2,39 @@ package llm
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sync"
 
+	"contract-analysis-service/configs"
 	"contract-analysis-service/internal/models"
-	"contract-analysis-service/internal/pkg/external"
+)
+
+const (
+	// defaultChunkTokens is the largest contract, in estimated tokens, that
+	// is analyzed in a single request; longer ones are split into chunks of
+	// this size.
+	defaultChunkTokens = 6000
+	// chunkConcurrency bounds the chunk requests in flight for one contract.
+	chunkConcurrency = 3
+	// ensembleConcurrency bounds the ensemble runs in flight for one contract.
+	ensembleConcurrency = 3
+	// analysisTemperature is the temperature of a single analysis run.
+	analysisTemperature = 0.1
 )
 
 // ContractAnalyzer handles contract analysis using LLM APIs
 type ContractAnalyzer struct {
-	service    Service
+	service      Service
 	promptEngine *PromptEngine
+	chunkTokens  int
+	ensemble     configs.EnsembleConfig
+}
+
+// analysisRun is one analysis of a contract by a provider.
+type analysisRun struct {
+	provider    string
+	model       string
+	temperature float64
 }
 
 // NewContractAnalyzer creates a new contract analyzer
@@ -20,75 +42,131 @@ func NewContractAnalyzer(service Service, promptEngine *PromptEngine) *ContractA
 	return &ContractAnalyzer{
 		service:      service,
 		promptEngine: promptEngine,
+		chunkTokens:  defaultChunkTokens,
 	}
 }
 
-// AnalyzeContract performs comprehensive contract analysis
+// SetChunkTokens overrides the size above which contracts are analyzed in
+// chunks. Sizes that are not positive are ignored, as no chunk could fit them.
+func (c *ContractAnalyzer) SetChunkTokens(tokens int) {
+	if tokens > 0 {
+		c.chunkTokens = tokens
+	}
+}
+
+// SetEnsemble makes the analyzer analyze each contract several times and
+// vote on the result.
+func (c *ContractAnalyzer) SetEnsemble(cfg configs.EnsembleConfig) {
+	c.ensemble = cfg
+}
+
+// AnalyzeContract performs comprehensive contract analysis. Contracts too
+// long for one request are split on clause boundaries, each chunk is
+// analyzed on its own and the results are merged with MergeAnalyses. With an
+// ensemble configured, the contract is analyzed by every run of the
+// ensemble concurrently and the analyses are combined with Vote; runs that
+// fail agree with no other run and are counted in FailedRuns.
 func (c *ContractAnalyzer) AnalyzeContract(ctx context.Context, provider, contractText string) (*models.ContractAnalysis, error) {
-	// Build analysis prompt
-	prompt := c.promptEngine.BuildContractAnalysisPrompt(contractText)
-	
-	// Create request payload for LLM
-	payload := map[string]interface{}{
-		"model": "gpt-4o",
-		"messages": []map[string]interface{}{
-			{
-				"role":    "system",
-				"content": "You are a legal document analysis expert. Always respond with valid JSON.",
-			},
-			{
-				"role":    "user", 
-				"content": prompt,
-			},
-		},
-		"temperature": 0.1,
-		"max_tokens":  2000,
+	runs := c.runs(provider)
+	if len(runs) == 1 {
+		analysis, err := c.analyzeContract(ctx, runs[0], contractText)
+		if err != nil {
+			return nil, err
+		}
+		// A lone run agrees with itself
+		analysis.Confidence = 1
+		return analysis, nil
 	}
-	
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+
+	analyses := make([]*models.ContractAnalysis, len(runs))
+	errs := make([]error, len(runs))
+	sem := make(chan struct{}, ensembleConcurrency)
+	var wg sync.WaitGroup
+	for i, run := range runs {
+		wg.Add(1)
+		go func(i int, run analysisRun) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			analyses[i], errs[i] = c.analyzeContract(ctx, run, contractText)
+		}(i, run)
 	}
-	
-	// Execute request through LLM service
-	resp, err := c.service.ExecuteRequest(ctx, provider, &external.Request{
-		Method:  "POST",
-		URL:     "/chat/completions", // This is the endpoint path, not the full URL
-		Headers: map[string]string{"Content-Type": "application/json"},
-		Body:    payloadBytes,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("LLM API request failed: %w", err)
+	wg.Wait()
+
+	if voted := Vote(analyses); voted != nil {
+		return voted, nil
 	}
-	
-	// Parse response
-	return c.parseAnalysisResponse(resp.Body)
+	return nil, fmt.Errorf("all %d ensemble runs failed: %w", len(runs), errs[0])
 }
 
-// parseAnalysisResponse parses the LLM API response into ContractAnalysis
-func (c *ContractAnalyzer) parseAnalysisResponse(body []byte) (*models.ContractAnalysis, error) {
-	var openAIResp struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+// runs returns the analysis runs of a contract: one on provider, or the
+// samples of each ensemble member.
+func (c *ContractAnalyzer) runs(provider string) []analysisRun {
+	members := c.ensemble.Members
+	if len(members) == 0 {
+		members = []configs.EnsembleMember{{Provider: provider}}
 	}
-	
-	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+	samples := c.ensemble.GetSamples()
+	temperature := analysisTemperature
+	if samples > 1 {
+		temperature = c.ensemble.GetTemperature()
 	}
-	
-	if len(openAIResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in LLM response")
+	var runs []analysisRun
+	for _, member := range members {
+		for i := 0; i < samples; i++ {
+			runs = append(runs, analysisRun{provider: member.Provider, model: member.Model, temperature: temperature})
+		}
 	}
-	
+	return runs
+}
+
+func (c *ContractAnalyzer) analyzeContract(ctx context.Context, run analysisRun, contractText string) (*models.ContractAnalysis, error) {
+	if EstimateTokens(contractText) <= c.chunkTokens {
+		prompt, err := c.promptEngine.BuildContractAnalysisPrompt(ctx, contractText)
+		if err != nil {
+			return nil, err
+		}
+		return c.analyze(ctx, run, prompt.Text)
+	}
+
+	chunks := ChunkText(contractText, c.chunkTokens)
+	parts := make([]*models.ContractAnalysis, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, chunkConcurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk Chunk) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			prompt, err := c.promptEngine.BuildChunkAnalysisPrompt(ctx, chunk, len(chunks))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			parts[i], errs[i] = c.analyze(ctx, run, prompt.Text)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+	}
+	return MergeAnalyses(parts), nil
+}
+
+func (c *ContractAnalyzer) analyze(ctx context.Context, run analysisRun, prompt string) (*models.ContractAnalysis, error) {
+	req := NewChatRequest("You are a legal document analysis expert. Always respond with valid JSON.", prompt)
+	req.Model = run.model
+	req.Temperature = run.temperature
+	req.MaxTokens = 2000
+
 	var analysis models.ContractAnalysis
-	content := openAIResp.Choices[0].Message.Content
-	
-	if err := json.Unmarshal([]byte(content), &analysis); err != nil {
-		return nil, fmt.Errorf("failed to parse contract analysis: %w", err)
+	if _, err := ChatJSON(ctx, c.service, run.provider, req, "contract_analysis", &analysis); err != nil {
+		return nil, fmt.Errorf("contract analysis failed: %w", err)
 	}
-	
 	return &analysis, nil
 }
@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"contract-analysis-service/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// Vote combines the analyses of one contract by several runs, field by
+// field, for self-consistency. Parties, total and currency are the values
+// most runs agree on, with earlier runs winning ties. Milestones are matched
+// across runs by description; those most runs list are kept, and their
+// percentage and amount are voted on by the runs that list them. Every field
+// not all runs agree on is listed in DisputedFields, and Confidence is the
+// share of all runs agreeing on the most disputed one. Nil analyses, of runs
+// that failed, count as runs that agree on nothing and are recorded in
+// FailedRuns; the analysis of a single run is returned as is, with full
+// confidence.
+func Vote(runs []*models.ContractAnalysis) *models.ContractAnalysis {
+	var analyses []*models.ContractAnalysis
+	for _, run := range runs {
+		if run != nil {
+			analyses = append(analyses, run)
+		}
+	}
+	switch {
+	case len(analyses) == 0:
+		return nil
+	case len(runs) == 1:
+		analyses[0].Confidence = 1
+		return analyses[0]
+	}
+
+	// Failed runs agree on nothing
+	b := &ballot{runs: len(runs), confidence: 1}
+	all := make([]int, len(analyses))
+	for i := range all {
+		all[i] = i
+	}
+	voted := &models.ContractAnalysis{Milestones: []models.AnalysisMilestone{}}
+	agreeing := make(map[string][]int)
+	voted.Buyer, agreeing["buyer"] = b.vote("buyer", PartyKey, all, func(i int) string { return analyses[i].Buyer })
+	voted.Seller, agreeing["seller"] = b.vote("seller", PartyKey, all, func(i int) string { return analyses[i].Seller })
+	total, totalRuns := b.vote("total_value", identity, all, func(i int) string { return analyses[i].TotalValue.String() })
+	voted.TotalValue = decimal.RequireFromString(total)
+	agreeing["total_value"] = totalRuns
+	voted.Currency, agreeing["currency"] = b.vote("currency", strings.ToUpper, all, func(i int) string { return strings.TrimSpace(analyses[i].Currency) })
+
+	// Quotes are taken from a run that gave the winning value
+	for field, runs := range agreeing {
+		for _, i := range runs {
+			if quote := analyses[i].SourceQuotes[field]; quote != "" {
+				if voted.SourceQuotes == nil {
+					voted.SourceQuotes = make(map[string]string)
+				}
+				voted.SourceQuotes[field] = quote
+				break
+			}
+		}
+	}
+
+	listed, keys := milestonesByDescription(analyses)
+	for _, key := range keys {
+		runs := listed[key]
+		// Leaving a milestone out is a vote against it
+		kept, _ := b.vote("milestones", identity, all, func(i int) string {
+			if _, ok := runs[i]; ok {
+				return key
+			}
+			return ""
+		})
+		if kept == "" {
+			continue
+		}
+		var group []int
+		for _, i := range all {
+			if _, ok := runs[i]; ok {
+				group = append(group, i)
+			}
+		}
+		milestoneOf := func(i int) models.AnalysisMilestone { return analyses[i].Milestones[runs[i]] }
+
+		m := len(voted.Milestones)
+		source := milestoneOf(group[0])
+		milestone := models.AnalysisMilestone{
+			Description:  source.Description,
+			SourceQuote:  source.SourceQuote,
+			SourceChunks: source.SourceChunks,
+		}
+		percentage, _ := b.vote(fmt.Sprintf("milestones[%d].percentage", m), identity, group, func(i int) string {
+			return strconv.FormatFloat(math.Round(milestoneOf(i).Percentage*100)/100, 'f', -1, 64)
+		})
+		milestone.Percentage, _ = strconv.ParseFloat(percentage, 64)
+		amount, _ := b.vote(fmt.Sprintf("milestones[%d].amount", m), identity, group, func(i int) string {
+			return milestoneOf(i).Amount.String()
+		})
+		milestone.Amount = decimal.RequireFromString(amount)
+		voted.Milestones = append(voted.Milestones, milestone)
+	}
+
+	// Risk factors are not voted on; they come from the first run
+	voted.RiskFactors = analyses[0].RiskFactors
+	voted.Confidence = b.confidence
+	voted.DisputedFields = b.disputed
+	voted.FailedRuns = len(runs) - len(analyses)
+	return voted
+}
+
+// ballot collects the outcome of the votes on the fields of an analysis.
+type ballot struct {
+	runs       int
+	confidence float64
+	disputed   []string
+}
+
+// vote returns the most common value of field among the runs in voters,
+// compared by key, and the runs that gave it. The share of all runs giving
+// it lowers the confidence when it is the lowest so far, and the field is
+// disputed unless all runs gave it. A field voted on more than once is
+// listed once.
+func (b *ballot) vote(field string, key func(string) string, voters []int, value func(run int) string) (string, []int) {
+	// A missing value is a vote like any other
+	keyOf := func(s string) string {
+		if k := key(s); k != "" {
+			return k
+		}
+		return "\x00"
+	}
+	t := newTally(keyOf)
+	for _, i := range voters {
+		t.add(value(i))
+	}
+	winner := t.winner()
+	var agreeing []int
+	for _, i := range voters {
+		if keyOf(value(i)) == keyOf(winner) {
+			agreeing = append(agreeing, i)
+		}
+	}
+	share := float64(len(agreeing)) / float64(b.runs)
+	if share < 1 && !slices.Contains(b.disputed, field) {
+		b.disputed = append(b.disputed, field)
+	}
+	b.confidence = math.Min(b.confidence, share)
+	return winner, agreeing
+}
+
+// milestonesByDescription matches the milestones of the runs by description,
+// compared like MergeAnalyses does. A description listed several times by a
+// run is matched occurrence by occurrence, as are milestones without one. It
+// returns, for each milestone key, the index of the milestone in each run
+// listing it, and the keys in the order the runs first list them.
+func milestonesByDescription(analyses []*models.ContractAnalysis) (map[string]map[int]int, []string) {
+	listed := make(map[string]map[int]int)
+	var keys []string
+	for i, analysis := range analyses {
+		occurrences := make(map[string]int)
+		for index, m := range analysis.Milestones {
+			description := textKey(m.Description)
+			occurrences[description]++
+			key := fmt.Sprintf("%s#%d", description, occurrences[description])
+			if listed[key] == nil {
+				listed[key] = make(map[int]int)
+				keys = append(keys, key)
+			}
+			listed[key][i] = index
+		}
+	}
+	return listed, keys
+}
+
+func identity(s string) string { return s }
@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/pkg/external"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestVote(t *testing.T) {
+	run := func(buyer string, total int64, percentages ...float64) *models.ContractAnalysis {
+		analysis := &models.ContractAnalysis{
+			Buyer:        buyer,
+			Seller:       "Globex",
+			TotalValue:   decimal.NewFromInt(total),
+			Currency:     "usd",
+			SourceQuotes: map[string]string{"total_value": "USD " + decimal.NewFromInt(total).String()},
+		}
+		for _, p := range percentages {
+			analysis.Milestones = append(analysis.Milestones, models.AnalysisMilestone{
+				Description: "Payment", Percentage: p, Amount: decimal.NewFromInt(total).Mul(decimal.NewFromFloat(p / 100)),
+			})
+		}
+		return analysis
+	}
+
+	voted := Vote([]*models.ContractAnalysis{
+		run("Acme Corp", 10000, 30, 70),
+		run("ACME Corporation", 1000, 30, 70),
+		nil,
+		run("Acme", 10000, 40, 60),
+		run("Acme Corp.", 10000, 30, 20, 50),
+	})
+	assert.Equal(t, "Acme Corp", voted.Buyer)
+	assert.Equal(t, "usd", voted.Currency)
+	assert.True(t, voted.TotalValue.Equal(decimal.NewFromInt(10000)))
+	assert.Equal(t, "USD 10000", voted.SourceQuotes["total_value"])
+	require.Len(t, voted.Milestones, 2)
+	assert.Equal(t, 30.0, voted.Milestones[0].Percentage)
+	assert.Equal(t, 70.0, voted.Milestones[1].Percentage)
+	assert.True(t, voted.Milestones[1].Amount.Equal(decimal.NewFromInt(7000)))
+
+	// With a failed run no field is unanimous
+	assert.Equal(t, []string{
+		"buyer", "seller", "total_value", "currency", "milestones",
+		"milestones[0].percentage", "milestones[0].amount",
+		"milestones[1].percentage", "milestones[1].amount",
+	}, voted.DisputedFields)
+	// Only one of the five runs gave the voted amount of the first
+	// milestone; the failed run agrees with none
+	assert.Equal(t, 0.2, voted.Confidence)
+	assert.Equal(t, 1, voted.FailedRuns)
+
+	unanimous := Vote([]*models.ContractAnalysis{run("Acme", 1000, 100), run("Acme", 1000, 100)})
+	assert.Empty(t, unanimous.DisputedFields)
+	assert.Equal(t, 1.0, unanimous.Confidence)
+
+	// Milestones are matched by description rather than position
+	listing := func(milestones ...models.AnalysisMilestone) *models.ContractAnalysis {
+		return &models.ContractAnalysis{Buyer: "Acme", Milestones: milestones}
+	}
+	reordered := Vote([]*models.ContractAnalysis{
+		listing(models.AnalysisMilestone{Description: "Deposit", Percentage: 30}, models.AnalysisMilestone{Description: "Delivery", Percentage: 70}),
+		listing(models.AnalysisMilestone{Description: "delivery.", Percentage: 70}, models.AnalysisMilestone{Description: "Deposit", Percentage: 30}),
+		listing(models.AnalysisMilestone{Description: "Deposit", Percentage: 30}, models.AnalysisMilestone{Description: "Installation", Percentage: 20}, models.AnalysisMilestone{Description: "Delivery", Percentage: 50}),
+	})
+	require.Len(t, reordered.Milestones, 2)
+	assert.Equal(t, "Deposit", reordered.Milestones[0].Description)
+	assert.Equal(t, 30.0, reordered.Milestones[0].Percentage)
+	assert.Equal(t, "Delivery", reordered.Milestones[1].Description)
+	assert.Equal(t, 70.0, reordered.Milestones[1].Percentage)
+	assert.Equal(t, []string{"milestones[1].percentage", "milestones"}, reordered.DisputedFields)
+	assert.InDelta(t, 2.0/3, reordered.Confidence, 1e-9)
+
+	single := run("Acme", 1000)
+	assert.Same(t, single, Vote([]*models.ContractAnalysis{single}))
+	assert.Nil(t, Vote([]*models.ContractAnalysis{nil}))
+
+	// A lone survivor of several runs is not unanimous
+	survivor := Vote([]*models.ContractAnalysis{nil, run("Acme", 1000), nil})
+	assert.InDelta(t, 1.0/3, survivor.Confidence, 1e-9)
+	assert.Equal(t, []string{"buyer", "seller", "total_value", "currency"}, survivor.DisputedFields)
+	assert.Equal(t, 2, survivor.FailedRuns)
+
+	// Two agreeing runs of five are not full confidence
+	agreeing := Vote([]*models.ContractAnalysis{run("Acme", 1000), nil, run("Acme", 1000), nil, nil})
+	assert.Equal(t, 0.4, agreeing.Confidence)
+	assert.Equal(t, 3, agreeing.FailedRuns)
+}
+
+func TestContractAnalyzer_Ensemble(t *testing.T) {
+	service := NewLLMService(zap.NewNop())
+	primary, secondary := new(external.MockClient), new(external.MockClient)
+	service.AddClient("primary", primary)
+	service.AddClient("secondary", secondary)
+
+	var models []string
+	var temperatures []float64
+	record := func(args mock.Arguments) {
+		var sent struct {
+			Model       string  `json:"model"`
+			Temperature float64 `json:"temperature"`
+		}
+		_ = json.Unmarshal(args.Get(1).(*external.Request).Body, &sent)
+		models = append(models, sent.Model)
+		temperatures = append(temperatures, sent.Temperature)
+	}
+	analysis := `{"buyer":"Acme","seller":"Globex","total_value":1000,"currency":"USD","milestones":[],"risk_factors":[]}`
+	primary.On("ExecuteRequest", mock.Anything, mock.Anything).Run(record).Return(completion(analysis), nil)
+	secondary.On("ExecuteRequest", mock.Anything, mock.Anything).Return(&external.Response{StatusCode: 503, Body: []byte(`{"error":{"message":"unavailable"}}`)}, nil)
+
+	analyzer := NewContractAnalyzer(service, NewPromptEngine())
+	analyzer.SetEnsemble(configs.EnsembleConfig{
+		Samples: 2,
+		Members: []configs.EnsembleMember{{Provider: "primary", Model: "model-a"}, {Provider: "secondary"}},
+	})
+	voted, err := analyzer.AnalyzeContract(context.Background(), "analysis", "Acme buys from Globex for USD 1,000.")
+	require.NoError(t, err)
+
+	// The failed runs of the secondary agree with none
+	assert.Equal(t, "Acme", voted.Buyer)
+	assert.Equal(t, 0.5, voted.Confidence)
+	assert.Equal(t, 2, voted.FailedRuns)
+	assert.Equal(t, []string{"model-a", "model-a"}, models)
+	assert.Equal(t, []float64{0.7, 0.7}, temperatures)
+	secondary.AssertNumberOfCalls(t, "ExecuteRequest", 2)
+
+	analyzer.SetEnsemble(configs.EnsembleConfig{Samples: 2, Members: []configs.EnsembleMember{{Provider: "secondary"}}})
+	_, err = analyzer.AnalyzeContract(context.Background(), "analysis", "Acme buys from Globex for USD 1,000.")
+	assert.ErrorContains(t, err, "all 2 ensemble runs failed")
+	assert.ErrorIs(t, err, ErrOverloaded)
+}
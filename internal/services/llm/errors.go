@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors that a *ProviderError matches with errors.Is, by kind.
+var (
+	ErrInvalidRequest = errors.New("invalid LLM request")
+	ErrAuthentication = errors.New("LLM provider rejected the credentials")
+	ErrRateLimited    = errors.New("LLM provider rate limit exceeded")
+	ErrOverloaded     = errors.New("LLM provider is overloaded")
+	ErrProviderFailed = errors.New("LLM provider failed")
+)
+
+// ErrUnsupportedProvider is returned for a provider with no registered client.
+var ErrUnsupportedProvider = errors.New("unsupported LLM provider")
+
+// ErrProvidersUnavailable is returned by the Router when every provider
+// routed for a task failed with an outage.
+var ErrProvidersUnavailable = errors.New("all LLM providers failed")
+
+// ErrorKind classifies a provider error independently of the provider.
+type ErrorKind string
+
+const (
+	ErrorKindInvalidRequest ErrorKind = "invalid_request"
+	ErrorKindAuthentication ErrorKind = "authentication"
+	ErrorKindPermission     ErrorKind = "permission"
+	ErrorKindNotFound       ErrorKind = "not_found"
+	ErrorKindTooLarge       ErrorKind = "request_too_large"
+	ErrorKindRateLimited    ErrorKind = "rate_limited"
+	ErrorKindOverloaded     ErrorKind = "overloaded"
+	ErrorKindServer         ErrorKind = "server"
+)
+
+// ProviderError is returned when an LLM provider rejects or fails a request.
+type ProviderError struct {
+	Kind       ErrorKind
+	StatusCode int
+	// Type is the provider's own error type, if it reported one.
+	Type    string
+	Message string
+}
+
+func (e *ProviderError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("LLM API returned status %d: %s: %s", e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("LLM API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// Is matches the sentinel error for the kind of e.
+func (e *ProviderError) Is(target error) bool {
+	switch e.Kind {
+	case ErrorKindInvalidRequest, ErrorKindNotFound, ErrorKindTooLarge:
+		return target == ErrInvalidRequest
+	case ErrorKindAuthentication, ErrorKindPermission:
+		return target == ErrAuthentication
+	case ErrorKindRateLimited:
+		return target == ErrRateLimited
+	case ErrorKindOverloaded:
+		return target == ErrOverloaded
+	default:
+		return target == ErrProviderFailed
+	}
+}
+
+// Retryable reports whether the same request may succeed later.
+func (e *ProviderError) Retryable() bool {
+	switch e.Kind {
+	case ErrorKindRateLimited, ErrorKindOverloaded, ErrorKindServer:
+		return true
+	default:
+		return false
+	}
+}
+
+// kindForStatus classifies an HTTP status code.
+func kindForStatus(status int) ErrorKind {
+	switch {
+	case status == http.StatusUnauthorized:
+		return ErrorKindAuthentication
+	case status == http.StatusForbidden:
+		return ErrorKindPermission
+	case status == http.StatusNotFound:
+		return ErrorKindNotFound
+	case status == http.StatusRequestEntityTooLarge:
+		return ErrorKindTooLarge
+	case status == http.StatusTooManyRequests:
+		return ErrorKindRateLimited
+	case status == 529, status == http.StatusServiceUnavailable:
+		return ErrorKindOverloaded
+	case status >= 500:
+		return ErrorKindServer
+	default:
+		return ErrorKindInvalidRequest
+	}
+}
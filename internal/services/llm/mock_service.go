@@ -38,3 +38,12 @@ func (m *MockLLMService) ExecuteRequest(ctx context.Context, provider string, re
 	}
 	return args.Get(0).(*external.Response), args.Error(1)
 }
+
+// Chat mocks the Chat method
+func (m *MockLLMService) Chat(ctx context.Context, provider string, req *ChatRequest) (*ChatResponse, error) {
+	args := m.Called(ctx, provider, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ChatResponse), args.Error(1)
+}
@@ -5,6 +5,7 @@ import (
 
 	"contract-analysis-service/internal/models"
 	"contract-analysis-service/internal/pkg/external"
+	"contract-analysis-service/internal/services/llm"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -35,3 +36,12 @@ func (m *Service) ExecuteRequest(ctx context.Context, provider string, req *exte
 	}
 	return args.Get(0).(*external.Response), args.Error(1)
 }
+
+// Chat mocks the Chat method.
+func (m *Service) Chat(ctx context.Context, provider string, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	args := m.Called(ctx, provider, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*llm.ChatResponse), args.Error(1)
+}
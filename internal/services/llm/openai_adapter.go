@@ -0,0 +1,347 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"contract-analysis-service/internal/pkg/external"
+	"contract-analysis-service/internal/pkg/jsonschema"
+)
+
+// DefaultOpenAIModel is used by OpenAIAdapter when neither the request nor the
+// adapter names a model.
+const DefaultOpenAIModel = "gpt-4o"
+
+// OpenAIAdapter speaks the OpenAI chat completions format, which OpenRouter
+// and most self-hosted gateways accept as well.
+type OpenAIAdapter struct {
+	// DefaultModel is used when the request does not name a model.
+	DefaultModel string
+	// StructuredOutputs sends the request schema as a json_schema response
+	// format. Only some models behind OpenAI-compatible APIs accept it.
+	StructuredOutputs bool
+}
+
+// NewOpenRouterAdapter returns an adapter for OpenRouter, which uses the
+// OpenAI format with its own model names.
+func NewOpenRouterAdapter(defaultModel string) OpenAIAdapter {
+	return OpenAIAdapter{DefaultModel: defaultModel}
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function Tool   `json:"function"`
+}
+
+type openAIRequest struct {
+	Model          string               `json:"model"`
+	Messages       []openAIMessage      `json:"messages"`
+	Temperature    float64              `json:"temperature,omitempty"`
+	MaxTokens      int                  `json:"max_tokens,omitempty"`
+	ResponseFormat interface{}          `json:"response_format,omitempty"`
+	Tools          []openAITool         `json:"tools,omitempty"`
+	ToolChoice     interface{}          `json:"tool_choice,omitempty"`
+	Stream         bool                 `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage Usage `json:"usage"`
+	Error *struct {
+		Message string      `json:"message"`
+		Type    string      `json:"type"`
+		Code    interface{} `json:"code"`
+	} `json:"error"`
+}
+
+// BuildChatRequest translates a chat request to a /chat/completions call.
+func (a OpenAIAdapter) BuildChatRequest(req *ChatRequest) (*external.Request, error) {
+	return a.buildRequest(a.payload(req))
+}
+
+// BuildStreamRequest translates a chat request to a streamed
+// /chat/completions call. The usage is requested in the last event.
+func (a OpenAIAdapter) BuildStreamRequest(req *ChatRequest) (*external.Request, error) {
+	payload := a.payload(req)
+	payload.Stream = true
+	payload.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	httpReq, err := a.buildRequest(payload)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.IsStreaming = true
+	return httpReq, nil
+}
+
+// NewStreamDecoder returns a decoder for a streamed chat completion.
+func (a OpenAIAdapter) NewStreamDecoder() StreamDecoder {
+	return &openAIStreamDecoder{}
+}
+
+func (a OpenAIAdapter) payload(req *ChatRequest) openAIRequest {
+	model := req.Model
+	if model == "" {
+		model = a.DefaultModel
+	}
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+
+	payload := openAIRequest{
+		Model:       model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+	for _, m := range req.Messages {
+		content := m.Content
+		msg := openAIMessage{Role: m.Role, Content: &content, ToolCallID: m.ToolCallID}
+		for _, call := range m.ToolCalls {
+			tc := openAIToolCall{ID: call.ID, Type: "function"}
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = string(call.Arguments)
+			msg.ToolCalls = append(msg.ToolCalls, tc)
+		}
+		// Assistant messages that only call tools have no content
+		if m.Role == RoleAssistant && m.Content == "" && len(m.ToolCalls) > 0 {
+			msg.Content = nil
+		}
+		payload.Messages = append(payload.Messages, msg)
+	}
+	switch {
+	// Structured outputs require an object at the root
+	case a.StructuredOutputs && req.Schema != nil && req.Schema.Is(jsonschema.TypeObject):
+		name := req.SchemaName
+		if name == "" {
+			name = "response"
+		}
+		payload.ResponseFormat = map[string]interface{}{
+			"type":        "json_schema",
+			"json_schema": map[string]interface{}{"name": name, "schema": req.Schema},
+		}
+	case req.ResponseFormat != ResponseFormatText:
+		payload.ResponseFormat = map[string]string{"type": string(req.ResponseFormat)}
+	}
+	for _, tool := range req.Tools {
+		payload.Tools = append(payload.Tools, openAITool{Type: "function", Function: tool})
+	}
+	if req.ToolChoice != "" {
+		payload.ToolChoice = map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": req.ToolChoice},
+		}
+	}
+	return payload
+}
+
+func (a OpenAIAdapter) buildRequest(payload openAIRequest) (*external.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	return &external.Request{
+		Method:  "POST",
+		URL:     "/chat/completions",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    body,
+	}, nil
+}
+
+// ParseChatResponse reads the first choice of a chat completion.
+func (a OpenAIAdapter) ParseChatResponse(resp *external.Response) (*ChatResponse, error) {
+	var parsed openAIResponse
+	decodeErr := json.Unmarshal(resp.Body, &parsed)
+
+	if parsed.Error != nil || resp.StatusCode >= 400 {
+		return nil, openAIError(resp.StatusCode, &parsed, resp.Body)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to parse LLM response: %w", decodeErr)
+	}
+	if len(parsed.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in LLM response")
+	}
+
+	choice := parsed.Choices[0]
+	chatResp := &ChatResponse{
+		ID:           parsed.ID,
+		Model:        parsed.Model,
+		FinishReason: choice.FinishReason,
+		Usage:        parsed.Usage,
+	}
+	if choice.Message.Content != nil {
+		chatResp.Content = *choice.Message.Content
+	}
+	for _, call := range choice.Message.ToolCalls {
+		args := call.Function.Arguments
+		if args == "" {
+			args = "{}"
+		}
+		chatResp.ToolCalls = append(chatResp.ToolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: json.RawMessage(args),
+		})
+	}
+	if chatResp.Usage.TotalTokens == 0 {
+		chatResp.Usage.TotalTokens = chatResp.Usage.PromptTokens + chatResp.Usage.CompletionTokens
+	}
+	return chatResp, nil
+}
+
+// openAIError builds the error for a failed completion. OpenRouter reports
+// upstream failures in the body of a 200 response, with the upstream status
+// as the error code.
+func openAIError(status int, parsed *openAIResponse, body []byte) *ProviderError {
+	if parsed.Error == nil {
+		return &ProviderError{Kind: kindForStatus(status), StatusCode: status, Message: truncate(string(body), 200)}
+	}
+	if status < 400 {
+		status = http.StatusBadGateway
+		if code, ok := parsed.Error.Code.(float64); ok && code >= 400 {
+			status = int(code)
+		}
+	}
+	return &ProviderError{Kind: kindForStatus(status), StatusCode: status, Type: parsed.Error.Type, Message: parsed.Error.Message}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+type openAIStreamChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+// openAIStreamDecoder assembles a chat completion from its stream of chunks.
+type openAIStreamDecoder struct {
+	resp      ChatResponse
+	content   strings.Builder
+	arguments []*strings.Builder
+	chunks    int
+}
+
+// Decode reads one chunk. OpenRouter reports upstream failures as a chunk
+// with an error in place of choices.
+func (d *openAIStreamDecoder) Decode(data []byte) (*ChatDelta, error) {
+	var failure openAIResponse
+	if json.Unmarshal(data, &failure) == nil && failure.Error != nil {
+		return nil, openAIError(http.StatusOK, &failure, data)
+	}
+	var chunk openAIStreamChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM stream event: %w", err)
+	}
+	d.chunks++
+	if chunk.ID != "" {
+		d.resp.ID = chunk.ID
+	}
+	if chunk.Model != "" {
+		d.resp.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		d.resp.Usage = *chunk.Usage
+	}
+	if len(chunk.Choices) == 0 {
+		return nil, nil
+	}
+	choice := chunk.Choices[0]
+	if choice.FinishReason != "" {
+		d.resp.FinishReason = choice.FinishReason
+	}
+	// The first delta of a tool call names it, the following ones extend its
+	// arguments. Only the first increment of a chunk is returned; chunks
+	// carry one.
+	var delta *ChatDelta
+	for _, call := range choice.Delta.ToolCalls {
+		for len(d.resp.ToolCalls) <= call.Index {
+			d.resp.ToolCalls = append(d.resp.ToolCalls, ToolCall{})
+			d.arguments = append(d.arguments, &strings.Builder{})
+		}
+		if call.ID != "" {
+			d.resp.ToolCalls[call.Index].ID = call.ID
+		}
+		if call.Function.Name != "" {
+			d.resp.ToolCalls[call.Index].Name = call.Function.Name
+		}
+		d.arguments[call.Index].WriteString(call.Function.Arguments)
+		if delta == nil && call.Function.Arguments != "" {
+			delta = &ChatDelta{Arguments: call.Function.Arguments, ToolCall: call.Index}
+		}
+	}
+	if choice.Delta.Content != "" {
+		d.content.WriteString(choice.Delta.Content)
+		if delta == nil {
+			delta = &ChatDelta{Content: choice.Delta.Content}
+		}
+	}
+	return delta, nil
+}
+
+// Response returns the completion assembled from the chunks.
+func (d *openAIStreamDecoder) Response() (*ChatResponse, error) {
+	if d.chunks == 0 {
+		return nil, fmt.Errorf("no events in LLM stream")
+	}
+	resp := d.resp
+	resp.Content = d.content.String()
+	resp.ToolCalls = nil
+	for i, call := range d.resp.ToolCalls {
+		args := d.arguments[i].String()
+		if args == "" {
+			args = "{}"
+		}
+		call.Arguments = json.RawMessage(args)
+		resp.ToolCalls = append(resp.ToolCalls, call)
+	}
+	if resp.Usage.TotalTokens == 0 {
+		resp.Usage.TotalTokens = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
+	}
+	return &resp, nil
+}
@@ -8,6 +8,7 @@ import (
 
 // OpenAIClient implements Client for OpenAI API
 type OpenAIClient struct {
+	OpenAIAdapter
 	client external.Client
 	apiKey string
 }
@@ -15,14 +16,18 @@ type OpenAIClient struct {
 // NewOpenAIClient creates a new OpenAI client
 func NewOpenAIClient(client external.Client, apiKey string) *OpenAIClient {
 	return &OpenAIClient{
-		client: client,
-		apiKey: apiKey,
+		OpenAIAdapter: OpenAIAdapter{StructuredOutputs: true},
+		client:        client,
+		apiKey:        apiKey,
 	}
 }
 
 // ExecuteRequest executes a request to OpenAI API
 func (c *OpenAIClient) ExecuteRequest(ctx context.Context, req *external.Request) (*external.Response, error) {
 	// Set OpenAI-specific headers
+	if req.Headers == nil {
+		req.Headers = make(map[string]string)
+	}
 	req.Headers["Authorization"] = "Bearer " + c.apiKey
 	req.Headers["Content-Type"] = "application/json"
 
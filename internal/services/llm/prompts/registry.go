@@ -0,0 +1,388 @@
+// Package prompts holds the prompt templates of the LLM tasks.
+//
+// Templates are Go text/template files named <name>/<version>.tmpl, e.g.
+// risk_assessment/v2.tmpl. A template can be overridden for an industry or
+// jurisdiction by adding selectors to the file name, as in
+// risk_assessment/v2.industry-healthcare.tmpl or
+// compliance_check/v1.jurisdiction-eu.industry-finance.tmpl. The highest
+// version of each prompt that has a base template, one without selectors, is
+// used unless another one is pinned; a version with only overrides is never
+// used for callers the overrides do not match.
+//
+// The templates in the templates directory are embedded in the binary; a
+// directory given to New overlays them, so prompts can be changed without a
+// release. Templates are executed with the fields below and may use the json
+// and trim functions.
+//
+//	contract_analysis        .ContractText
+//	chunk_analysis           .ContractText .Part .Parts
+//	milestone_sequencing     .Milestones
+//	risk_assessment          .ContractText .IndustryStandards
+//	compliance_check         .ContractText .Jurisdiction
+//	contract_validation      .DocumentText
+//	industry_classification  .ContractText
+package prompts
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+)
+
+// Prompt names.
+const (
+	ContractAnalysis       = "contract_analysis"
+	ChunkAnalysis          = "chunk_analysis"
+	MilestoneSequencing    = "milestone_sequencing"
+	RiskAssessment         = "risk_assessment"
+	ComplianceCheck        = "compliance_check"
+	ContractValidation     = "contract_validation"
+	IndustryClassification = "industry_classification"
+)
+
+// Selector keys that pick overrides of a template.
+const (
+	Industry     = "industry"
+	Jurisdiction = "jurisdiction"
+)
+
+// ErrNotFound is returned for a prompt or version that has no template.
+var ErrNotFound = errors.New("prompt template not found")
+
+//go:embed templates
+var embedded embed.FS
+
+var versionPattern = regexp.MustCompile(`^v(\d+)$`)
+
+// Prompt is a rendered template.
+type Prompt struct {
+	Name    string
+	Version string
+	// Variant lists the selectors of the override that was used, e.g.
+	// "industry-healthcare"; it is empty for the base template.
+	Variant string
+	Text    string
+}
+
+// Ref identifies the template a prompt was rendered from, e.g.
+// "risk_assessment@v2" or "risk_assessment@v2.industry-healthcare".
+func (p *Prompt) Ref() string {
+	ref := p.Name + "@" + p.Version
+	if p.Variant != "" {
+		ref += "." + p.Variant
+	}
+	return ref
+}
+
+// variant is one template file of a prompt version.
+type variant struct {
+	name      string
+	selectors map[string]string
+	tmpl      *template.Template
+}
+
+// Registry holds the templates of every prompt version.
+type Registry struct {
+	// templates maps a prompt name and version to its variants
+	templates map[string]map[string][]*variant
+	active    map[string]string
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultRegistry *Registry
+)
+
+// Default returns the registry of the embedded templates.
+func Default() *Registry {
+	defaultOnce.Do(func() {
+		r, err := New("", nil)
+		if err != nil {
+			panic(fmt.Sprintf("prompts: invalid embedded templates: %v", err))
+		}
+		defaultRegistry = r
+	})
+	return defaultRegistry
+}
+
+// New loads the embedded templates, overlaid with the templates in dir if it
+// is not empty, and pins the versions given by prompt name.
+func New(dir string, versions map[string]string) (*Registry, error) {
+	sources := []fs.FS{mustSub(embedded, "templates")}
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("prompt template directory: %w", err)
+		}
+		sources = append(sources, os.DirFS(dir))
+	}
+	r, err := Load(sources...)
+	if err != nil {
+		return nil, err
+	}
+	for name, version := range versions {
+		if err := r.Pin(name, version); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Load reads the templates of the given file systems. A file in a later file
+// system replaces the same file in an earlier one.
+func Load(sources ...fs.FS) (*Registry, error) {
+	r := &Registry{templates: make(map[string]map[string][]*variant), active: make(map[string]string)}
+	for _, fsys := range sources {
+		err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || path.Ext(p) != ".tmpl" {
+				return err
+			}
+			return r.add(fsys, p)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for name, versions := range r.templates {
+		for version, variants := range versions {
+			if !hasBase(variants) {
+				continue
+			}
+			if r.active[name] == "" || versionNumber(version) > versionNumber(r.active[name]) {
+				r.active[name] = version
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *Registry) add(fsys fs.FS, p string) error {
+	dir, file := path.Split(p)
+	name := strings.Trim(dir, "/")
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("prompt template %s: expected <name>/<version>.tmpl", p)
+	}
+	parts := strings.Split(strings.TrimSuffix(file, ".tmpl"), ".")
+	version := parts[0]
+	if !versionPattern.MatchString(version) {
+		return fmt.Errorf("prompt template %s: version %q is not of the form v<N>", p, version)
+	}
+	v := &variant{selectors: make(map[string]string)}
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "-")
+		if !ok || key == "" || normalize(value) == "" {
+			return fmt.Errorf("prompt template %s: selector %q is not of the form <key>-<value>", p, part)
+		}
+		v.selectors[key] = normalize(value)
+	}
+	v.name = strings.Join(parts[1:], ".")
+
+	text, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return fmt.Errorf("failed to read prompt template %s: %w", p, err)
+	}
+	v.tmpl, err = template.New(p).Funcs(funcs).Option("missingkey=error").Parse(strings.TrimSuffix(string(text), "\n"))
+	if err != nil {
+		return fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+
+	if r.templates[name] == nil {
+		r.templates[name] = make(map[string][]*variant)
+	}
+	variants := r.templates[name][version]
+	for i, existing := range variants {
+		if existing.name == v.name {
+			variants[i] = v
+			return nil
+		}
+	}
+	r.templates[name][version] = append(variants, v)
+	return nil
+}
+
+// Pin makes version the one rendered for the named prompt. The version must
+// have a base template.
+func (r *Registry) Pin(name, version string) error {
+	variants, ok := r.templates[name][version]
+	if !ok {
+		return fmt.Errorf("%w: %s@%s", ErrNotFound, name, version)
+	}
+	if !hasBase(variants) {
+		return fmt.Errorf("%w: %s@%s has no base template", ErrNotFound, name, version)
+	}
+	r.active[name] = version
+	return nil
+}
+
+// hasBase reports whether one of the variants is a base template.
+func hasBase(variants []*variant) bool {
+	for _, v := range variants {
+		if len(v.selectors) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Versions returns the version rendered for each prompt.
+func (r *Registry) Versions() map[string]string {
+	versions := make(map[string]string, len(r.active))
+	for name, version := range r.active {
+		versions[name] = version
+	}
+	return versions
+}
+
+// Render executes the active version of the named prompt with data. Of the
+// templates of that version, the override matching the most selectors of ctx
+// is used, or the base template when none matches. The rendered prompt is
+// added to the Trace of ctx, if any.
+func (r *Registry) Render(ctx context.Context, name string, data interface{}) (*Prompt, error) {
+	version, ok := r.active[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	v := choose(r.templates[name][version], selectorsFrom(ctx))
+	if v == nil {
+		return nil, fmt.Errorf("%w: %s@%s has no template for the selectors %v", ErrNotFound, name, version, selectorsFrom(ctx))
+	}
+
+	var b strings.Builder
+	if err := v.tmpl.Execute(&b, data); err != nil {
+		return nil, fmt.Errorf("failed to render prompt %s@%s: %w", name, version, err)
+	}
+	prompt := &Prompt{Name: name, Version: version, Variant: v.name, Text: b.String()}
+	if trace, ok := ctx.Value(traceKey{}).(*Trace); ok {
+		trace.add(prompt.Ref())
+	}
+	return prompt, nil
+}
+
+// choose returns the variant whose selectors all match and that has the most
+// of them, the first by name on a tie.
+func choose(variants []*variant, selectors map[string]string) *variant {
+	var best *variant
+	for _, v := range variants {
+		matches := true
+		for key, value := range v.selectors {
+			if selectors[key] != value {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		if best == nil || len(v.selectors) > len(best.selectors) ||
+			len(v.selectors) == len(best.selectors) && v.name < best.name {
+			best = v
+		}
+	}
+	return best
+}
+
+var funcs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"trim": strings.TrimSpace,
+}
+
+type selectorsKey struct{}
+
+// WithSelector returns a context whose prompts are rendered from the
+// overrides for value of key, e.g. WithSelector(ctx, Industry, "Healthcare").
+func WithSelector(ctx context.Context, key, value string) context.Context {
+	selectors := make(map[string]string)
+	for k, v := range selectorsFrom(ctx) {
+		selectors[k] = v
+	}
+	if value = normalize(value); value != "" {
+		selectors[key] = value
+	} else {
+		delete(selectors, key)
+	}
+	return context.WithValue(ctx, selectorsKey{}, selectors)
+}
+
+func selectorsFrom(ctx context.Context) map[string]string {
+	selectors, _ := ctx.Value(selectorsKey{}).(map[string]string)
+	return selectors
+}
+
+type traceKey struct{}
+
+// Trace collects the references of the prompts rendered with a context.
+type Trace struct {
+	mu   sync.Mutex
+	refs map[string]bool
+}
+
+// Track returns a context that records the prompts rendered with it in the
+// returned Trace.
+func Track(ctx context.Context) (context.Context, *Trace) {
+	trace := &Trace{refs: make(map[string]bool)}
+	return context.WithValue(ctx, traceKey{}, trace), trace
+}
+
+func (t *Trace) add(ref string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refs[ref] = true
+}
+
+// Refs returns the references of the rendered prompts, sorted.
+func (t *Trace) Refs() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	refs := make([]string, 0, len(t.refs))
+	for ref := range t.refs {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// normalize turns a selector value into its file name form: lower case with
+// runs of other characters than letters and digits replaced by a dash.
+func normalize(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+func versionNumber(version string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	return n
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
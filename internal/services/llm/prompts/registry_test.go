@@ -0,0 +1,133 @@
+package prompts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefault_RendersEmbeddedTemplates(t *testing.T) {
+	registry := Default()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		data    interface{}
+		want    string
+		version string
+	}{
+		{ContractAnalysis, struct{ ContractText string }{"  Acme sells to Globex.\n"}, "\"\"\"\nAcme sells to Globex.\n\"\"\"", "v1"},
+		{ChunkAnalysis, struct {
+			ContractText string
+			Part, Parts  int
+		}{"clause", 2, 3}, "CONTRACT TEXT (PART 2 OF 3)", "v1"},
+		{MilestoneSequencing, struct{ Milestones []map[string]int }{[]map[string]int{{"percentage": 40}}}, "MILESTONES:\n[{\"percentage\":40}]", "v2"},
+		{RiskAssessment, struct{ ContractText, IndustryStandards string }{"text", "Uptime of 99.9%"}, "Uptime of 99.9%", "v2"},
+		{ComplianceCheck, struct{ ContractText, Jurisdiction string }{"text", "EU"}, "compliance with EU jurisdiction", "v1"},
+		{ContractValidation, struct{ DocumentText string }{"document"}, "Document:\n\ndocument", "v1"},
+		{IndustryClassification, struct{ ContractText string }{"contract"}, "Document:\n\ncontract", "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt, err := registry.Render(ctx, tt.name, tt.data)
+			require.NoError(t, err)
+			assert.Equal(t, tt.version, prompt.Version)
+			assert.Contains(t, prompt.Text, tt.want)
+			assert.NotRegexp(t, `\n$`, prompt.Text)
+		})
+	}
+
+	_, err := registry.Render(ctx, "unknown", nil)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = registry.Render(ctx, ContractAnalysis, struct{ Text string }{"missing field"})
+	assert.Error(t, err)
+}
+
+func TestRegistry_SelectsVersionAndOverride(t *testing.T) {
+	base := fstest.MapFS{
+		"risk_assessment/v1.tmpl":                                      {Data: []byte("v1 base")},
+		"risk_assessment/v2.tmpl":                                      {Data: []byte("v2 base")},
+		"risk_assessment/v10.tmpl":                                     {Data: []byte("v10 base")},
+		"risk_assessment/v10.industry-healthcare.tmpl":                 {Data: []byte("v10 healthcare")},
+		"risk_assessment/v10.jurisdiction-eu.tmpl":                     {Data: []byte("v10 eu")},
+		"risk_assessment/v10.industry-healthcare.jurisdiction-eu.tmpl": {Data: []byte("v10 healthcare eu")},
+		"README.md": {Data: []byte("ignored")},
+	}
+	overlay := fstest.MapFS{
+		"risk_assessment/v10.jurisdiction-eu.tmpl": {Data: []byte("v10 eu, revised")},
+	}
+	registry, err := Load(base, overlay)
+	require.NoError(t, err)
+
+	render := func(ctx context.Context) *Prompt {
+		t.Helper()
+		prompt, err := registry.Render(ctx, RiskAssessment, nil)
+		require.NoError(t, err)
+		return prompt
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, "v10 base", render(ctx).Text)
+	assert.Equal(t, "risk_assessment@v10", render(ctx).Ref())
+
+	healthcare := WithSelector(ctx, Industry, "Healthcare")
+	assert.Equal(t, "v10 healthcare", render(healthcare).Text)
+	assert.Equal(t, "risk_assessment@v10.industry-healthcare", render(healthcare).Ref())
+	assert.Equal(t, "v10 healthcare eu", render(WithSelector(healthcare, Jurisdiction, "EU")).Text)
+	assert.Equal(t, "v10 eu, revised", render(WithSelector(WithSelector(ctx, Industry, "Finance"), Jurisdiction, "eu")).Text)
+	assert.Equal(t, "v10 base", render(WithSelector(healthcare, Industry, "")).Text)
+
+	require.NoError(t, registry.Pin(RiskAssessment, "v2"))
+	assert.Equal(t, "v2 base", render(healthcare).Text, "overrides of other versions are not used")
+	assert.ErrorIs(t, registry.Pin(RiskAssessment, "v3"), ErrNotFound)
+}
+
+func TestRegistry_IgnoresVersionsWithoutBaseTemplate(t *testing.T) {
+	registry, err := Load(fstest.MapFS{
+		"risk_assessment/v2.tmpl":                     {Data: []byte("v2 base")},
+		"risk_assessment/v3.industry-healthcare.tmpl": {Data: []byte("v3 healthcare")},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "v2", registry.Versions()[RiskAssessment])
+	prompt, err := registry.Render(WithSelector(context.Background(), Industry, "construction"), RiskAssessment, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v2 base", prompt.Text)
+
+	assert.ErrorIs(t, registry.Pin(RiskAssessment, "v3"), ErrNotFound)
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, RiskAssessment), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, RiskAssessment, "v3.industry-healthcare.tmpl"), []byte("v3 healthcare"), 0o644))
+	_, err = New(dir, map[string]string{RiskAssessment: "v3"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLoad_RejectsMalformedNames(t *testing.T) {
+	for _, name := range []string{"v1.tmpl", "risk/latest.tmpl", "risk/v1.healthcare.tmpl", "risk/v1.tmpl/x.tmpl"} {
+		_, err := Load(fstest.MapFS{name: {Data: []byte("text")}})
+		assert.Error(t, err, name)
+	}
+	_, err := Load(fstest.MapFS{"risk/v1.tmpl": {Data: []byte("{{.Text")}})
+	assert.Error(t, err)
+}
+
+func TestTrack(t *testing.T) {
+	registry, err := Load(fstest.MapFS{
+		"a/v1.tmpl": {Data: []byte("a")},
+		"b/v2.tmpl": {Data: []byte("b")},
+	})
+	require.NoError(t, err)
+
+	ctx, trace := Track(context.Background())
+	for _, name := range []string{"b", "a", "b"} {
+		_, err := registry.Render(ctx, name, nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"a@v1", "b@v2"}, trace.Refs())
+}
@@ -0,0 +1,218 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/pkg/external"
+	"github.com/sony/gobreaker"
+	"go.uber.org/zap"
+)
+
+// Tasks name the kinds of LLM calls the service makes. Callers pass a task
+// in place of a provider and the Router picks the providers for it.
+const (
+	TaskValidation     = "validation"
+	TaskClassification = "classification"
+	TaskAnalysis       = "analysis"
+	TaskSequencing     = "sequencing"
+	TaskRisk           = "risk"
+	TaskCompliance     = "compliance"
+)
+
+// healthSmoothing is the weight of the latest outcome in a provider's
+// health score.
+const healthSmoothing = 0.3
+
+// providerHealth tracks how a provider has been doing recently.
+type providerHealth struct {
+	// score is a moving average of outcomes, 1 for a success and 0 for a
+	// failure that caused a failover
+	score float64
+	// degradedUntil is the end of the cooldown that started when the score
+	// last fell below the minimum
+	degradedUntil time.Time
+}
+
+// Router is a Service that sends each request through an ordered chain of
+// providers. The provider argument of its methods names a task route, and
+// chat requests fail over to the next provider in the chain on 5xx
+// responses, timeouts and open circuits. Providers whose health falls below
+// the configured minimum are moved to the end of the chain for a cooldown.
+type Router struct {
+	service   Service
+	cfg       configs.LLMRoutingConfig
+	logger    *zap.Logger
+	now       func() time.Time
+	mu        sync.Mutex
+	providers map[string]bool
+	health    map[string]*providerHealth
+}
+
+// NewRouter creates a router over service, whose clients should be added
+// through the router so that it can resolve provider names.
+func NewRouter(service Service, cfg configs.LLMRoutingConfig, logger *zap.Logger) *Router {
+	return &Router{
+		service:   service,
+		cfg:       cfg,
+		logger:    logger,
+		now:       time.Now,
+		providers: make(map[string]bool),
+		health:    make(map[string]*providerHealth),
+	}
+}
+
+// AddClient adds a new LLM client to the underlying service
+func (r *Router) AddClient(provider string, client external.Client) {
+	r.mu.Lock()
+	r.providers[provider] = true
+	r.mu.Unlock()
+	r.service.AddClient(provider, client)
+}
+
+// Chat sends the request to the providers routed for task until one of them
+// answers or fails with an error that another provider would not fix.
+func (r *Router) Chat(ctx context.Context, task string, req *ChatRequest) (*ChatResponse, error) {
+	var lastErr error
+	for _, provider := range r.chain(task) {
+		resp, err := r.service.Chat(ctx, provider, req)
+		if err == nil {
+			r.record(provider, true)
+			return resp, nil
+		}
+		if errors.Is(err, ErrUnsupportedProvider) {
+			// Routes may name providers that are not configured here
+			continue
+		}
+		if !shouldFailover(ctx, err) {
+			return nil, err
+		}
+		r.record(provider, false)
+		r.logger.Warn("LLM provider failed, trying the next one",
+			zap.String("task", task), zap.String("provider", provider), zap.Error(err))
+		lastErr = err
+	}
+	if lastErr == nil {
+		return nil, fmt.Errorf("%w: no provider configured for %s", ErrUnsupportedProvider, task)
+	}
+	return nil, fmt.Errorf("%w for %s: %w", ErrProvidersUnavailable, task, lastErr)
+}
+
+// ExecuteRequest sends a raw request to the first provider routed for task.
+// Raw requests are in one provider's format, so they do not fail over.
+func (r *Router) ExecuteRequest(ctx context.Context, task string, req *external.Request) (*external.Response, error) {
+	provider, err := r.first(task)
+	if err != nil {
+		return nil, err
+	}
+	return r.service.ExecuteRequest(ctx, provider, req)
+}
+
+// AnalyzeContract analyses a contract with the first provider routed for task
+func (r *Router) AnalyzeContract(ctx context.Context, task, contractText string) (*models.ContractAnalysis, error) {
+	provider, err := r.first(task)
+	if err != nil {
+		return nil, err
+	}
+	return r.service.AnalyzeContract(ctx, provider, contractText)
+}
+
+// Health returns the health score of provider, from 0 to 1.
+func (r *Router) Health(provider string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if h, ok := r.health[provider]; ok {
+		return h.score
+	}
+	return 1
+}
+
+// chain returns the providers to try for task. A task without a route uses
+// the default chain, and a provider name routes to that provider alone.
+// Healthy providers keep their configured order; unhealthy ones follow,
+// healthiest first.
+func (r *Router) chain(task string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	configured, ok := r.cfg.Routes[task]
+	switch {
+	case ok:
+	case r.providers[task]:
+		configured = []string{task}
+	case len(r.cfg.Default) > 0:
+		configured = r.cfg.Default
+	default:
+		configured = []string{task}
+	}
+
+	now := r.now()
+	var healthy, degraded []string
+	for _, provider := range configured {
+		if h, ok := r.health[provider]; ok && now.Before(h.degradedUntil) {
+			degraded = append(degraded, provider)
+			continue
+		}
+		healthy = append(healthy, provider)
+	}
+	sort.SliceStable(degraded, func(i, j int) bool {
+		return r.health[degraded[i]].score > r.health[degraded[j]].score
+	})
+	return append(healthy, degraded...)
+}
+
+// first returns the provider to try first for task, or ErrUnsupportedProvider
+// if its route names none.
+func (r *Router) first(task string) (string, error) {
+	chain := r.chain(task)
+	if len(chain) == 0 {
+		return "", fmt.Errorf("%w: no provider configured for %s", ErrUnsupportedProvider, task)
+	}
+	return chain[0], nil
+}
+
+// record updates the health of provider with the outcome of a request.
+func (r *Router) record(provider string, success bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.health[provider]
+	if !ok {
+		h = &providerHealth{score: 1}
+		r.health[provider] = h
+	}
+	outcome := 0.0
+	if success {
+		outcome = 1
+	}
+	h.score = (1-healthSmoothing)*h.score + healthSmoothing*outcome
+	if !success && h.score < r.cfg.GetMinHealth() {
+		h.degradedUntil = r.now().Add(r.cfg.GetCooldown())
+	}
+}
+
+// shouldFailover reports whether err is a provider outage that another
+// provider may not share: a retryable provider error (5xx, overloaded, rate
+// limited), a network error or timeout, or an open circuit. Errors caused by
+// the caller's own context are not.
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		return providerErr.Retryable()
+	}
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,137 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/pkg/external"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const okCompletion = `{"id":"cmpl-1","choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`
+
+func newTestRouter(cfg configs.LLMRoutingConfig) (*Router, *external.MockClient, *external.MockClient) {
+	router := NewRouter(NewLLMService(zap.NewNop()), cfg, zap.NewNop())
+	primary, secondary := new(external.MockClient), new(external.MockClient)
+	router.AddClient("primary", primary)
+	router.AddClient("secondary", secondary)
+	return router, primary, secondary
+}
+
+func TestRouter_Chat_FailsOver(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *external.Response
+		err  error
+	}{
+		{name: "server error", resp: &external.Response{StatusCode: 503, Body: []byte(`{"error":{"message":"unavailable"}}`)}},
+		{name: "rate limited", resp: &external.Response{StatusCode: 429, Body: []byte(`{"error":{"message":"slow down"}}`)}},
+		{name: "circuit open", err: gobreaker.ErrOpenState},
+		{name: "timeout", err: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, primary, secondary := newTestRouter(configs.LLMRoutingConfig{
+				Routes: map[string][]string{TaskRisk: {"primary", "secondary"}},
+			})
+			primary.On("ExecuteRequest", mock.Anything, mock.Anything).Return(tt.resp, tt.err).Once()
+			secondary.On("ExecuteRequest", mock.Anything, mock.Anything).
+				Return(&external.Response{StatusCode: 200, Body: []byte(okCompletion)}, nil).Once()
+
+			resp, err := router.Chat(context.Background(), TaskRisk, NewChatRequest("", "Hello"))
+			require.NoError(t, err)
+			assert.Equal(t, "secondary", resp.Provider)
+			assert.Less(t, router.Health("primary"), 1.0)
+			assert.Equal(t, 1.0, router.Health("secondary"))
+		})
+	}
+}
+
+func TestRouter_Chat_DoesNotFailOverOnInvalidRequest(t *testing.T) {
+	router, primary, secondary := newTestRouter(configs.LLMRoutingConfig{
+		Routes: map[string][]string{TaskRisk: {"primary", "secondary"}},
+	})
+	primary.On("ExecuteRequest", mock.Anything, mock.Anything).
+		Return(&external.Response{StatusCode: 400, Body: []byte(`{"error":{"message":"bad model"}}`)}, nil)
+
+	_, err := router.Chat(context.Background(), TaskRisk, NewChatRequest("", "Hello"))
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	secondary.AssertNotCalled(t, "ExecuteRequest", mock.Anything, mock.Anything)
+}
+
+func TestRouter_Chat_AllProvidersFail(t *testing.T) {
+	router, primary, secondary := newTestRouter(configs.LLMRoutingConfig{Default: []string{"primary", "secondary"}})
+	overloaded := &external.Response{StatusCode: 529, Body: []byte(`{"error":{"message":"overloaded"}}`)}
+	primary.On("ExecuteRequest", mock.Anything, mock.Anything).Return(overloaded, nil)
+	secondary.On("ExecuteRequest", mock.Anything, mock.Anything).Return(overloaded, nil)
+
+	// A task without a route of its own uses the default chain
+	_, err := router.Chat(context.Background(), TaskCompliance, NewChatRequest("", "Hello"))
+	assert.ErrorIs(t, err, ErrOverloaded)
+	assert.ErrorIs(t, err, ErrProvidersUnavailable)
+	primary.AssertNumberOfCalls(t, "ExecuteRequest", 1)
+	secondary.AssertNumberOfCalls(t, "ExecuteRequest", 1)
+}
+
+func TestRouter_Chat_SkipsUnconfiguredProviders(t *testing.T) {
+	router, primary, _ := newTestRouter(configs.LLMRoutingConfig{
+		Routes: map[string][]string{TaskAnalysis: {"anthropic", "primary"}},
+	})
+	primary.On("ExecuteRequest", mock.Anything, mock.Anything).
+		Return(&external.Response{StatusCode: 200, Body: []byte(okCompletion)}, nil)
+
+	resp, err := router.Chat(context.Background(), TaskAnalysis, NewChatRequest("", "Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "primary", resp.Provider)
+}
+
+func TestRouter_Chat_UnhealthyProviderIsTriedLast(t *testing.T) {
+	router, primary, secondary := newTestRouter(configs.LLMRoutingConfig{
+		Routes:    map[string][]string{TaskRisk: {"primary", "secondary"}},
+		MinHealth: 0.9,
+		Cooldown:  time.Minute,
+	})
+	now := time.Now()
+	router.now = func() time.Time { return now }
+
+	primary.On("ExecuteRequest", mock.Anything, mock.Anything).
+		Return(&external.Response{StatusCode: 502, Body: []byte(`bad gateway`)}, nil).Once()
+	secondary.On("ExecuteRequest", mock.Anything, mock.Anything).
+		Return(&external.Response{StatusCode: 200, Body: []byte(okCompletion)}, nil)
+
+	_, err := router.Chat(context.Background(), TaskRisk, NewChatRequest("", "Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"secondary", "primary"}, router.chain(TaskRisk))
+
+	// During the cooldown the secondary is asked first
+	resp, err := router.Chat(context.Background(), TaskRisk, NewChatRequest("", "Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "secondary", resp.Provider)
+	primary.AssertNumberOfCalls(t, "ExecuteRequest", 1)
+
+	// After it the primary gets its place back
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, []string{"primary", "secondary"}, router.chain(TaskRisk))
+}
+
+func TestRouter_EmptyRouteIsUnsupported(t *testing.T) {
+	router, primary, _ := newTestRouter(configs.LLMRoutingConfig{
+		Default: []string{"primary"},
+		Routes:  map[string][]string{TaskRisk: {}},
+	})
+
+	_, err := router.Chat(context.Background(), TaskRisk, NewChatRequest("", "Hello"))
+	assert.ErrorIs(t, err, ErrUnsupportedProvider)
+	_, err = router.ExecuteRequest(context.Background(), TaskRisk, &external.Request{})
+	assert.ErrorIs(t, err, ErrUnsupportedProvider)
+	_, err = router.AnalyzeContract(context.Background(), TaskRisk, "contract text")
+	assert.ErrorIs(t, err, ErrUnsupportedProvider)
+	primary.AssertNotCalled(t, "ExecuteRequest", mock.Anything, mock.Anything)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"contract-analysis-service/internal/models"
@@ -17,6 +18,9 @@ type Service interface {
 	AnalyzeContract(ctx context.Context, provider, contractText string) (*models.ContractAnalysis, error)
 	AddClient(provider string, client external.Client)
 	ExecuteRequest(ctx context.Context, provider string, req *external.Request) (*external.Response, error)
+	// Chat sends a chat completion request to the provider, translated to its
+	// wire format by the provider's ChatAdapter.
+	Chat(ctx context.Context, provider string, req *ChatRequest) (*ChatResponse, error)
 }
 
 // llmService handles integration with various LLM APIs
@@ -42,7 +46,7 @@ func (s *llmService) AddClient(provider string, client external.Client) {
 func (s *llmService) ExecuteRequest(ctx context.Context, provider string, req *external.Request) (*external.Response, error) {
 	client, ok := s.clients[provider]
 	if !ok {
-		return nil, fmt.Errorf("unsupported LLM provider: %s", provider)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, provider)
 	}
 
 	logger := s.logger.With(
@@ -67,11 +71,95 @@ func (s *llmService) ExecuteRequest(ctx context.Context, provider string, req *e
 	return resp, nil
 }
 
+// Chat sends a chat completion request through the specified LLM provider.
+// The response is streamed when ctx asks for it (see WithStream) and the
+// provider's adapter can stream.
+func (s *llmService) Chat(ctx context.Context, provider string, req *ChatRequest) (*ChatResponse, error) {
+	client, ok := s.clients[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, provider)
+	}
+	adapter, ok := client.(ChatAdapter)
+	if !ok {
+		adapter = OpenAIAdapter{}
+	}
+	if onDelta := streamFrom(ctx); onDelta != nil {
+		if streamer, ok := adapter.(StreamAdapter); ok {
+			return s.chatStream(ctx, provider, streamer, req, onDelta)
+		}
+	}
+
+	httpReq, err := adapter.BuildChatRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.ExecuteRequest(ctx, provider, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	chatResp, err := adapter.ParseChatResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	chatResp.Provider = provider
+	return chatResp, nil
+}
+
+// chatStream sends a streaming chat request and passes each increment of the
+// response to onDelta as the events arrive.
+func (s *llmService) chatStream(ctx context.Context, provider string, adapter StreamAdapter, req *ChatRequest, onDelta func(ChatDelta)) (*ChatResponse, error) {
+	httpReq, err := adapter.BuildStreamRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	decoder := adapter.NewStreamDecoder()
+	var text strings.Builder
+	arguments := make(map[int]*strings.Builder)
+	httpReq.OnEvent = func(data []byte) error {
+		delta, err := decoder.Decode(data)
+		if err != nil || delta == nil {
+			return err
+		}
+		if delta.Arguments != "" {
+			args, ok := arguments[delta.ToolCall]
+			if !ok {
+				args = &strings.Builder{}
+				arguments[delta.ToolCall] = args
+			}
+			args.WriteString(delta.Arguments)
+			delta.Text = args.String()
+		} else {
+			text.WriteString(delta.Content)
+			delta.Text = text.String()
+		}
+		onDelta(*delta)
+		return nil
+	}
+
+	resp, err := s.ExecuteRequest(ctx, provider, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		// Failed requests are answered with a regular error body
+		if _, err := adapter.ParseChatResponse(resp); err != nil {
+			return nil, err
+		}
+		return nil, &ProviderError{Kind: kindForStatus(resp.StatusCode), StatusCode: resp.StatusCode, Message: truncate(string(resp.Body), 200)}
+	}
+	chatResp, err := decoder.Response()
+	if err != nil {
+		return nil, err
+	}
+	chatResp.Provider = provider
+	return chatResp, nil
+}
+
 // AnalyzeContract performs contract analysis using the specified LLM
 func (s *llmService) AnalyzeContract(ctx context.Context, provider, contractText string) (*models.ContractAnalysis, error) {
 	client, ok := s.clients[provider]
 	if !ok {
-		return nil, fmt.Errorf("unsupported LLM provider: %s", provider)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, provider)
 	}
 
 	// The request body for the LLM will be more complex, this is a placeholder
@@ -0,0 +1,196 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"contract-analysis-service/internal/pkg/external"
+)
+
+// ChatDelta is an increment of a streamed chat response: either text or
+// the arguments of a tool call.
+type ChatDelta struct {
+	// Content is the text added by the increment.
+	Content string
+	// Arguments is the part of the arguments of a tool call added by the
+	// increment, and ToolCall the index of the call in the response.
+	Arguments string
+	ToolCall  int
+	// Text is the text of the response so far or, for increments of
+	// arguments, the arguments of the tool call so far.
+	Text string
+}
+
+// StreamAdapter is a ChatAdapter for a provider that can stream responses
+// as server-sent events.
+type StreamAdapter interface {
+	ChatAdapter
+	// BuildStreamRequest translates a chat request to a streaming call.
+	BuildStreamRequest(req *ChatRequest) (*external.Request, error)
+	// NewStreamDecoder returns a decoder for the events of one response.
+	NewStreamDecoder() StreamDecoder
+}
+
+// StreamDecoder assembles a chat response from the events it is streamed in.
+type StreamDecoder interface {
+	// Decode reads the data of one event and returns the increment it
+	// carries, or nil for events that carry none. Errors reported in the
+	// stream are returned as *ProviderError.
+	Decode(data []byte) (*ChatDelta, error)
+	// Response returns the response assembled from the events.
+	Response() (*ChatResponse, error)
+}
+
+type streamKey struct{}
+
+// WithStream returns a copy of ctx whose chat requests stream their
+// responses, when the provider's adapter supports it, and pass each
+// increment to fn as it arrives. Requests made in parallel call fn
+// concurrently; requests to other providers return their response whole.
+func WithStream(ctx context.Context, fn func(ChatDelta)) context.Context {
+	return context.WithValue(ctx, streamKey{}, fn)
+}
+
+func streamFrom(ctx context.Context) func(ChatDelta) {
+	fn, _ := ctx.Value(streamKey{}).(func(ChatDelta))
+	return fn
+}
+
+// PartialField is a field of a JSON object whose value has been streamed in
+// full.
+type PartialField struct {
+	// Path is the key of the field, followed by the index for the elements
+	// of an array, e.g. "buyer" or "milestones[1]".
+	Path  string
+	Value json.RawMessage
+}
+
+// PartialFields returns the fields of the JSON object in text, a response
+// that may still be streaming, whose values are complete. Arrays are
+// returned element by element, so that the items of a list can be shown as
+// they arrive. Code fences and prose before the object are skipped.
+func PartialFields(text string) []PartialField {
+	start := strings.IndexByte(text, '{')
+	if start < 0 {
+		return nil
+	}
+	s := text[start+1:]
+	var fields []PartialField
+	i := 0
+	for {
+		i = skipSpace(s, i)
+		if i >= len(s) || s[i] != '"' {
+			return fields
+		}
+		keyEnd, ok := scanJSONValue(s, i)
+		if !ok {
+			return fields
+		}
+		var key string
+		if json.Unmarshal([]byte(s[i:keyEnd]), &key) != nil {
+			return fields
+		}
+		i = skipSpace(s, keyEnd)
+		if i >= len(s) || s[i] != ':' {
+			return fields
+		}
+		i = skipSpace(s, i+1)
+		if i >= len(s) {
+			return fields
+		}
+
+		if s[i] == '[' {
+			i++
+			for n := 0; ; n++ {
+				i = skipSpace(s, i)
+				if i >= len(s) {
+					return fields
+				}
+				if s[i] == ']' {
+					i++
+					break
+				}
+				end, ok := scanJSONValue(s, i)
+				if !ok {
+					return fields
+				}
+				fields = append(fields, PartialField{Path: fmt.Sprintf("%s[%d]", key, n), Value: json.RawMessage(s[i:end])})
+				i = skipSpace(s, end)
+				if i < len(s) && s[i] == ',' {
+					i++
+				}
+			}
+		} else {
+			end, ok := scanJSONValue(s, i)
+			if !ok {
+				return fields
+			}
+			fields = append(fields, PartialField{Path: key, Value: json.RawMessage(s[i:end])})
+			i = end
+		}
+
+		i = skipSpace(s, i)
+		if i < len(s) && s[i] == ',' {
+			i++
+		}
+	}
+}
+
+// scanJSONValue returns the end of the JSON value that starts at s[i], and
+// whether the value is complete and valid.
+func scanJSONValue(s string, i int) (int, bool) {
+	end := i
+	switch s[i] {
+	case '"', '{', '[':
+		depth := 0
+		inString, escaped := false, false
+	scan:
+		for end = i; end < len(s); end++ {
+			c := s[end]
+			if inString {
+				switch {
+				case escaped:
+					escaped = false
+				case c == '\\':
+					escaped = true
+				case c == '"':
+					inString = false
+				}
+			} else {
+				switch c {
+				case '"':
+					inString = true
+				case '{', '[':
+					depth++
+				case '}', ']':
+					depth--
+				}
+			}
+			if !inString && depth == 0 {
+				break scan
+			}
+		}
+		if end >= len(s) {
+			return 0, false
+		}
+		end++
+	default:
+		// A number or literal ends at the first delimiter; at the end of the
+		// text it may still be growing
+		end = strings.IndexAny(s[i:], ",}] \t\r\n")
+		if end < 0 {
+			return 0, false
+		}
+		end += i
+	}
+	return end, json.Valid([]byte(s[i:end]))
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\r' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,144 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"contract-analysis-service/internal/pkg/external"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// streamingClient is a client that delivers its events to a streaming
+// request and returns them as the body, like the HTTP client does.
+type streamingClient []string
+
+func streamEvents(events ...string) streamingClient { return events }
+
+func (c streamingClient) ExecuteRequest(ctx context.Context, req *external.Request) (*external.Response, error) {
+	if !req.IsStreaming || req.OnEvent == nil {
+		return nil, errors.New("not a streaming request")
+	}
+	for _, event := range c {
+		if err := req.OnEvent([]byte(event)); err != nil {
+			return nil, err
+		}
+	}
+	return &external.Response{StatusCode: 200, Body: []byte(strings.Join(c, "\n"))}, nil
+}
+
+func TestPartialFields(t *testing.T) {
+	text := "```json\n{\"buyer\": \"Acme \\\"Corp\\\"\", \"total_value\": 1000, \"milestones\": [{\"description\": \"Delivery\"}, {\"desc"
+	fields := PartialFields(text)
+	require.Len(t, fields, 3)
+	assert.Equal(t, "buyer", fields[0].Path)
+	assert.JSONEq(t, `"Acme \"Corp\""`, string(fields[0].Value))
+	assert.Equal(t, "total_value", fields[1].Path)
+	assert.Equal(t, "milestones[0]", fields[2].Path)
+	assert.JSONEq(t, `{"description":"Delivery"}`, string(fields[2].Value))
+
+	// A number is only complete once a delimiter follows it
+	assert.Empty(t, PartialFields(`{"total_value": 100`))
+	assert.Len(t, PartialFields(`{"total_value": 100, "cur`), 1)
+	assert.Empty(t, PartialFields("no object yet"))
+}
+
+func TestLLMService_Chat_StreamOpenAI(t *testing.T) {
+	service := NewLLMService(zap.NewNop())
+	service.AddClient("openai", streamEvents(
+		`{"id":"cmpl-1","model":"gpt-4o","choices":[{"delta":{"role":"assistant","content":"{\"buyer\":"}}]}`,
+		`{"id":"cmpl-1","model":"gpt-4o","choices":[{"delta":{"content":" \"Acme\"}"},"finish_reason":"stop"}]}`,
+		`{"id":"cmpl-1","model":"gpt-4o","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":4,"total_tokens":14}}`,
+	))
+
+	var deltas []ChatDelta
+	ctx := WithStream(context.Background(), func(delta ChatDelta) { deltas = append(deltas, delta) })
+	resp, err := service.Chat(ctx, "openai", NewChatRequest("", "Who is the buyer?"))
+	require.NoError(t, err)
+
+	require.Len(t, deltas, 2)
+	assert.Equal(t, `{"buyer":`, deltas[0].Text)
+	assert.Equal(t, ` "Acme"}`, deltas[1].Content)
+	assert.Equal(t, `{"buyer": "Acme"}`, resp.Content)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, "openai", resp.Provider)
+	assert.Equal(t, Usage{PromptTokens: 10, CompletionTokens: 4, TotalTokens: 14}, resp.Usage)
+}
+
+func TestClaudeClient_Chat_Stream(t *testing.T) {
+	service := NewLLMService(zap.NewNop())
+	service.AddClient("anthropic", NewClaudeClient(streamEvents(
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet","content":[],"usage":{"input_tokens":12,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"ping"}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Acme"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" Corp"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":6}}`,
+		`{"type":"message_stop"}`,
+	), "test-key"))
+
+	var texts []string
+	ctx := WithStream(context.Background(), func(delta ChatDelta) { texts = append(texts, delta.Text) })
+	resp, err := service.Chat(ctx, "anthropic", NewChatRequest("", "Who is the buyer?"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Acme", "Acme Corp"}, texts)
+	assert.Equal(t, "Acme Corp", resp.Content)
+	assert.Equal(t, "stop", resp.FinishReason)
+	assert.Equal(t, Usage{PromptTokens: 12, CompletionTokens: 6, TotalTokens: 18}, resp.Usage)
+
+	// An error reported mid-stream is a provider error
+	service.AddClient("anthropic", NewClaudeClient(streamEvents(
+		`{"type":"message_start","message":{"id":"msg_2","type":"message","role":"assistant","content":[],"usage":{"input_tokens":12}}}`,
+		`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+	), "test-key"))
+	_, err = service.Chat(ctx, "anthropic", NewChatRequest("", "Who is the buyer?"))
+	assert.ErrorIs(t, err, ErrOverloaded)
+}
+
+func TestLLMService_Chat_StreamToolCalls(t *testing.T) {
+	var arguments []string
+	ctx := WithStream(context.Background(), func(delta ChatDelta) {
+		if delta.Arguments != "" {
+			arguments = append(arguments, delta.Text)
+		}
+	})
+	req := NewChatRequest("", "Sequence.")
+	req.Tools = []Tool{{Name: "record_milestone_sequence"}}
+	req.ToolChoice = "record_milestone_sequence"
+
+	service := NewLLMService(zap.NewNop())
+	service.AddClient("openai", streamEvents(
+		`{"id":"cmpl-1","choices":[{"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"record_milestone_sequence","arguments":""}}]}}]}`,
+		`{"id":"cmpl-1","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"milestones\":"}}]}}]}`,
+		`{"id":"cmpl-1","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":" []}"}}]},"finish_reason":"stop"}]}`,
+	))
+	resp, err := service.Chat(ctx, "openai", req)
+	require.NoError(t, err)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, ToolCall{ID: "call_1", Name: "record_milestone_sequence", Arguments: json.RawMessage(`{"milestones": []}`)}, resp.ToolCalls[0])
+	assert.Equal(t, []string{`{"milestones":`, `{"milestones": []}`}, arguments)
+
+	arguments = nil
+	service.AddClient("anthropic", NewClaudeClient(streamEvents(
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":12}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Recording."}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"record_milestone_sequence","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"milestones\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":" []}"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}`,
+	), "test-key"))
+	resp, err = service.Chat(ctx, "anthropic", req)
+	require.NoError(t, err)
+	assert.Equal(t, "Recording.", resp.Content)
+	assert.Equal(t, FinishReasonToolCalls, resp.FinishReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, ToolCall{ID: "toolu_1", Name: "record_milestone_sequence", Arguments: json.RawMessage(`{"milestones": []}`)}, resp.ToolCalls[0])
+	assert.Equal(t, []string{`{"milestones":`, `{"milestones": []}`}, arguments)
+}
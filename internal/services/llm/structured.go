@@ -0,0 +1,229 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"contract-analysis-service/internal/pkg/jsonschema"
+)
+
+// maxRepairAttempts bounds the follow-up requests sent when a response does
+// not match its schema.
+const maxRepairAttempts = 2
+
+// ErrInvalidOutput is returned when the model keeps answering with output
+// that does not match the expected schema.
+var ErrInvalidOutput = errors.New("LLM output does not match the expected schema")
+
+var codeFence = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*(.*?)```")
+
+// ChatJSON sends req and decodes the JSON in the reply into v, a pointer to
+// the DTO expected. The JSON Schema of v is added to the prompt and sent to
+// providers that can enforce it. The reply is stripped of code fences and
+// prose, values of the wrong JSON type are coerced where the intent is clear,
+// and a reply that still does not match is sent back with the validation
+// errors, at most maxRepairAttempts times. It returns the response the value
+// was decoded from.
+func ChatJSON(ctx context.Context, service Service, provider string, req *ChatRequest, name string, v interface{}) (*ChatResponse, error) {
+	schema := jsonschema.For(v)
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	structured := *req
+	structured.Schema = schema
+	structured.SchemaName = name
+	if schema.Is(jsonschema.TypeObject) && structured.ResponseFormat == ResponseFormatText {
+		structured.ResponseFormat = ResponseFormatJSON
+	}
+	structured.Messages = withSchemaInstruction(req.Messages, string(schemaJSON))
+
+	for attempt := 0; ; attempt++ {
+		resp, err := service.Chat(ctx, provider, &structured)
+		if err != nil {
+			return nil, err
+		}
+		err = decodeStructured(schema, resp.Content, v)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt == maxRepairAttempts {
+			return nil, fmt.Errorf("%w after %d repair attempts: %v", ErrInvalidOutput, attempt, err)
+		}
+		structured.Messages = append(structured.Messages,
+			Message{Role: RoleAssistant, Content: resp.Content},
+			Message{Role: RoleUser, Content: repairPrompt(err)},
+		)
+	}
+}
+
+// ChatTool sends req with a tool named name, whose parameters are the JSON
+// Schema of v, a pointer to a struct, and makes the model call it. The
+// arguments of the call are decoded into v like a ChatJSON reply, and
+// arguments that do not match are sent back as the result of the call, at
+// most maxRepairAttempts times. Providers enforce the schema of tool
+// arguments far more reliably than instructions in the prompt; a model
+// without tool support that answers with the JSON instead is accepted too.
+// It returns the response the value was decoded from.
+func ChatTool(ctx context.Context, service Service, provider string, req *ChatRequest, name, description string, v interface{}) (*ChatResponse, error) {
+	schema := jsonschema.For(v)
+	if !schema.Is(jsonschema.TypeObject) {
+		return nil, fmt.Errorf("tool %s: parameters must be an object", name)
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	call := *req
+	call.Tools = append(append([]Tool(nil), req.Tools...), Tool{Name: name, Description: description, Parameters: schemaJSON})
+	call.ToolChoice = name
+	call.Messages = append([]Message(nil), req.Messages...)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := service.Chat(ctx, provider, &call)
+		if err != nil {
+			return nil, err
+		}
+		var toolCall *ToolCall
+		for i := range resp.ToolCalls {
+			if resp.ToolCalls[i].Name == name {
+				toolCall = &resp.ToolCalls[i]
+				break
+			}
+		}
+		if toolCall == nil {
+			err = decodeStructured(schema, resp.Content, v)
+		} else {
+			err = decodeStructured(schema, string(toolCall.Arguments), v)
+		}
+		if err == nil {
+			return resp, nil
+		}
+		if attempt == maxRepairAttempts {
+			return nil, fmt.Errorf("%w after %d repair attempts: %v", ErrInvalidOutput, attempt, err)
+		}
+		if toolCall == nil {
+			call.Messages = append(call.Messages,
+				Message{Role: RoleAssistant, Content: resp.Content},
+				Message{Role: RoleUser, Content: repairPrompt(err)},
+			)
+			continue
+		}
+		// Every call must be answered before the model is asked again
+		call.Messages = append(call.Messages, Message{Role: RoleAssistant, Content: resp.Content, ToolCalls: resp.ToolCalls})
+		for _, c := range resp.ToolCalls {
+			result := "Not called: call " + name + " only."
+			if c.ID == toolCall.ID {
+				result = toolRepairPrompt(name, err)
+			}
+			call.Messages = append(call.Messages, Message{Role: RoleTool, ToolCallID: c.ID, Content: result})
+		}
+	}
+}
+
+// ExtractJSON returns the JSON value in a model reply, dropping markdown code
+// fences and any prose around it, or "" when the reply has none. A value that
+// is cut off is returned as is, for the parser to report.
+func ExtractJSON(content string) string {
+	if m := codeFence.FindStringSubmatch(content); m != nil {
+		content = m[1]
+	}
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return ""
+	}
+
+	depth := 0
+	inString, escaped := false, false
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return content[start : i+1]
+			}
+		}
+	}
+	return content[start:]
+}
+
+// withSchemaInstruction returns messages with an instruction to follow the
+// schema added after the leading system messages.
+func withSchemaInstruction(messages []Message, schema string) []Message {
+	instruction := Message{
+		Role:    RoleSystem,
+		Content: "Respond only with JSON that matches this JSON Schema:\n" + schema,
+	}
+	i := 0
+	for i < len(messages) && messages[i].Role == RoleSystem {
+		i++
+	}
+	out := make([]Message, 0, len(messages)+1)
+	out = append(out, messages[:i]...)
+	out = append(out, instruction)
+	return append(out, messages[i:]...)
+}
+
+func decodeStructured(schema *jsonschema.Schema, content string, v interface{}) error {
+	raw := ExtractJSON(content)
+	if raw == "" {
+		return errors.New("the response contains no JSON")
+	}
+	coerced, err := schema.Coerce([]byte(raw))
+	if err != nil {
+		return err
+	}
+	if err := schema.Validate(coerced); err != nil {
+		return err
+	}
+	return json.Unmarshal(coerced, v)
+}
+
+func repairPrompt(err error) string {
+	var b strings.Builder
+	b.WriteString("Your previous response does not match the required JSON Schema:\n")
+	writeValidationErrors(&b, err)
+	b.WriteString("Respond again with only the corrected JSON.")
+	return b.String()
+}
+
+func toolRepairPrompt(name string, err error) string {
+	var b strings.Builder
+	b.WriteString("The arguments do not match the parameters of the tool:\n")
+	writeValidationErrors(&b, err)
+	fmt.Fprintf(&b, "Call %s again with the corrected arguments.", name)
+	return b.String()
+}
+
+func writeValidationErrors(b *strings.Builder, err error) {
+	var validationErrs jsonschema.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		for _, e := range validationErrs {
+			fmt.Fprintf(b, "- %s\n", e.Error())
+		}
+	} else {
+		fmt.Fprintf(b, "- %s\n", err.Error())
+	}
+}
@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/pkg/external"
+	"contract-analysis-service/internal/pkg/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "bare", content: `{"a":1}`, want: `{"a":1}`},
+		{name: "fenced", content: "Here you go:\n```json\n{\"a\":1}\n```\nLet me know!", want: `{"a":1}`},
+		{name: "trailing prose", content: `[{"a":"}"}] I hope this helps.`, want: `[{"a":"}"}]`},
+		{name: "truncated", content: `{"a":[1,2`, want: `{"a":[1,2`},
+		{name: "none", content: "I cannot help with that.", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExtractJSON(tt.content))
+		})
+	}
+}
+
+func completion(content string) *external.Response {
+	body, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{
+			"message":       map[string]string{"role": "assistant", "content": content},
+			"finish_reason": "stop",
+		}},
+	})
+	return &external.Response{StatusCode: 200, Body: body}
+}
+
+func TestChatJSON_CoercesAndRepairs(t *testing.T) {
+	mockClient := new(external.MockClient)
+	service := NewLLMService(zap.NewNop())
+	service.AddClient("openai", mockClient)
+
+	var requests []map[string]interface{}
+	record := func(args mock.Arguments) {
+		var sent map[string]interface{}
+		_ = json.Unmarshal(args.Get(1).(*external.Request).Body, &sent)
+		requests = append(requests, sent)
+	}
+	mockClient.On("ExecuteRequest", mock.Anything, mock.Anything).Run(record).
+		Return(completion("```json\n{\"buyer\":\"Acme\",\"seller\":\"Globex\",\"total_value\":\"1,000\",\"currency\":\"USD\"}\n```"), nil).Once()
+	mockClient.On("ExecuteRequest", mock.Anything, mock.Anything).Run(record).
+		Return(completion(`{"buyer":"Acme","seller":"Globex","total_value":1000,"currency":"USD","milestones":[],"risk_factors":[]}`), nil).Once()
+
+	var analysis models.ContractAnalysis
+	_, err := ChatJSON(context.Background(), service, "openai", NewChatRequest("Be precise.", "Analyze."), "contract_analysis", &analysis)
+	require.NoError(t, err)
+	assert.Equal(t, "1000", analysis.TotalValue.String())
+
+	require.Len(t, requests, 2)
+	first := requests[0]["messages"].([]interface{})
+	require.Len(t, first, 3)
+	assert.Contains(t, first[1].(map[string]interface{})["content"], "JSON Schema")
+	assert.Equal(t, map[string]interface{}{"type": "json_object"}, requests[0]["response_format"])
+
+	// The repair request carries the reply and the validation errors
+	repair := requests[1]["messages"].([]interface{})
+	require.Len(t, repair, 5)
+	prompt := repair[4].(map[string]interface{})["content"].(string)
+	assert.Contains(t, prompt, `missing required property "milestones"`)
+	assert.Contains(t, prompt, `missing required property "risk_factors"`)
+}
+
+func TestChatJSON_GivesUpAfterRepairAttempts(t *testing.T) {
+	mockClient := new(external.MockClient)
+	service := NewLLMService(zap.NewNop())
+	service.AddClient("openai", mockClient)
+	mockClient.On("ExecuteRequest", mock.Anything, mock.Anything).Return(completion("I cannot answer that."), nil)
+
+	var result models.ValidationResult
+	_, err := ChatJSON(context.Background(), service, "openai", NewChatRequest("", "Validate."), "validation_result", &result)
+	assert.ErrorIs(t, err, ErrInvalidOutput)
+	mockClient.AssertNumberOfCalls(t, "ExecuteRequest", maxRepairAttempts+1)
+}
+
+func TestOpenAIAdapter_StructuredOutputs(t *testing.T) {
+	req := NewChatRequest("", "Validate.")
+	req.Schema = jsonschema.For(&models.ValidationResult{})
+	req.SchemaName = "validation_result"
+
+	httpReq, err := OpenAIAdapter{StructuredOutputs: true}.BuildChatRequest(req)
+	require.NoError(t, err)
+
+	var sent struct {
+		ResponseFormat struct {
+			Type       string `json:"type"`
+			JSONSchema struct {
+				Name   string                 `json:"name"`
+				Schema map[string]interface{} `json:"schema"`
+			} `json:"json_schema"`
+		} `json:"response_format"`
+	}
+	require.NoError(t, json.Unmarshal(httpReq.Body, &sent))
+	assert.Equal(t, "json_schema", sent.ResponseFormat.Type)
+	assert.Equal(t, "validation_result", sent.ResponseFormat.JSONSchema.Name)
+	assert.Equal(t, "object", sent.ResponseFormat.JSONSchema.Schema["type"])
+}
+
+func toolCompletion(name, arguments string) *external.Response {
+	body, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{
+			"message": map[string]interface{}{"role": "assistant", "content": nil, "tool_calls": []interface{}{map[string]interface{}{
+				"id": "call_1", "type": "function", "function": map[string]string{"name": name, "arguments": arguments},
+			}}},
+			"finish_reason": "stop",
+		}},
+	})
+	return &external.Response{StatusCode: 200, Body: body}
+}
+
+func TestChatTool_DecodesAndRepairsArguments(t *testing.T) {
+	mockClient := new(external.MockClient)
+	service := NewLLMService(zap.NewNop())
+	service.AddClient("openai", mockClient)
+
+	var requests []map[string]interface{}
+	record := func(args mock.Arguments) {
+		var sent map[string]interface{}
+		_ = json.Unmarshal(args.Get(1).(*external.Request).Body, &sent)
+		requests = append(requests, sent)
+	}
+	mockClient.On("ExecuteRequest", mock.Anything, mock.Anything).Run(record).
+		Return(toolCompletion("record_risk_assessment", `{"risks":[],"compliance_score":"0.5"}`), nil).Once()
+	mockClient.On("ExecuteRequest", mock.Anything, mock.Anything).Run(record).
+		Return(toolCompletion("record_risk_assessment", `{"missing_clauses":[],"risks":[],"compliance_score":0.5,"suggestions":["Add late fees"]}`), nil).Once()
+
+	var assessment models.AnalysisRiskAssessment
+	_, err := ChatTool(context.Background(), service, "openai", NewChatRequest("", "Assess."), "record_risk_assessment", "Records the risks.", &assessment)
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, assessment.ComplianceScore)
+	assert.Equal(t, []string{"Add late fees"}, assessment.Suggestions)
+
+	require.Len(t, requests, 2)
+	assert.Equal(t, map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "record_risk_assessment"}}, requests[0]["tool_choice"])
+	tools := requests[0]["tools"].([]interface{})
+	require.Len(t, tools, 1)
+	parameters := tools[0].(map[string]interface{})["function"].(map[string]interface{})["parameters"].(map[string]interface{})
+	assert.Equal(t, "object", parameters["type"])
+	assert.Len(t, requests[0]["messages"], 1, "the schema is sent with the tool, not in the prompt")
+
+	// The invalid arguments are answered with the validation errors
+	repair := requests[1]["messages"].([]interface{})
+	require.Len(t, repair, 3)
+	call := repair[1].(map[string]interface{})
+	assert.Len(t, call["tool_calls"], 1)
+	result := repair[2].(map[string]interface{})
+	assert.Equal(t, "tool", result["role"])
+	assert.Equal(t, "call_1", result["tool_call_id"])
+	assert.Contains(t, result["content"], `missing required property "missing_clauses"`)
+}
+
+func TestChatTool_AcceptsJSONContent(t *testing.T) {
+	mockClient := new(external.MockClient)
+	service := NewLLMService(zap.NewNop())
+	service.AddClient("openai", mockClient)
+	// A model without tool support answers in text
+	mockClient.On("ExecuteRequest", mock.Anything, mock.Anything).
+		Return(completion(`{"milestones":[{"id":"m1","description":"Delivery","sequence_order":1,"category":"delivery","dependencies":[],"percentage":100}]}`), nil).Once()
+
+	var sequence struct {
+		Milestones []models.SequencedMilestone `json:"milestones"`
+	}
+	_, err := ChatTool(context.Background(), service, "openai", NewChatRequest("", "Sequence."), "record_milestone_sequence", "", &sequence)
+	require.NoError(t, err)
+	require.Len(t, sequence.Milestones, 1)
+	assert.Equal(t, "m1", sequence.Milestones[0].ID)
+
+	var milestones []models.SequencedMilestone
+	_, err = ChatTool(context.Background(), service, "openai", NewChatRequest("", "Sequence."), "record_milestone_sequence", "", &milestones)
+	assert.ErrorContains(t, err, "parameters must be an object")
+}
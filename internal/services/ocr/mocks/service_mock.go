@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"context"
+
+	"contract-analysis-service/internal/services/ocr"
+	"github.com/stretchr/testify/mock"
+)
+
+// Service is a mock implementation of the ocr.Service interface.
+type Service struct {
+	mock.Mock
+}
+
+// ExtractTextFromImage mocks the ExtractTextFromImage method.
+func (m *Service) ExtractTextFromImage(ctx context.Context, imagePath string) (*ocr.OCRResult, error) {
+	args := m.Called(ctx, imagePath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ocr.OCRResult), args.Error(1)
+}
@@ -0,0 +1,36 @@
+package usage
+
+import (
+	"context"
+
+	"contract-analysis-service/internal/services/llm"
+	"go.uber.org/zap"
+)
+
+// Meter is an llm.Service decorator that records the usage of every chat
+// response. It belongs below the Router, wrapping the service that talks to
+// the providers, so that requests retried on another provider and schema
+// repair attempts are each accounted with the provider that served them.
+type Meter struct {
+	llm.Service
+	usage  Service
+	logger *zap.Logger
+}
+
+// NewMeter wraps service so that its chat usage is recorded with usage.
+func NewMeter(service llm.Service, usage Service, logger *zap.Logger) *Meter {
+	return &Meter{Service: service, usage: usage, logger: logger}
+}
+
+// Chat sends the request and records the usage of the response. A failure to
+// record is logged rather than failing a call that has already been paid for.
+func (m *Meter) Chat(ctx context.Context, provider string, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	resp, err := m.Service.Chat(ctx, provider, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.usage.Record(ctx, provider, resp); err != nil {
+		m.logger.Error("Failed to record LLM usage", zap.String("provider", provider), zap.Error(err))
+	}
+	return resp, nil
+}
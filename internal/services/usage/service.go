@@ -0,0 +1,233 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/pkg/metrics"
+	"contract-analysis-service/internal/pkg/tenant"
+	"contract-analysis-service/internal/repositories"
+	"contract-analysis-service/internal/services/llm"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrContractNotFound is returned when asking for the usage of an unknown contract.
+	ErrContractNotFound = errors.New("contract not found")
+	// ErrBudgetExhausted is returned when a tenant has spent its monthly LLM budget.
+	ErrBudgetExhausted = errors.New("monthly LLM budget exhausted")
+)
+
+// otherTenants is the tenant label of the usage metrics for tenants without a
+// budget of their own.
+const otherTenants = "other"
+
+// tokensPerPriceUnit is the number of tokens prices are quoted for.
+var tokensPerPriceUnit = decimal.NewFromInt(1_000_000)
+
+// Service accounts the tokens and cost of LLM calls to contracts, analysis
+// stages and tenants, and enforces the tenants' monthly budgets.
+type Service interface {
+	// Record stores the usage reported in resp, attributed to the contract,
+	// stage and tenant carried by ctx.
+	Record(ctx context.Context, provider string, resp *llm.ChatResponse) error
+	// GetContractUsage totals the usage of a contract, overall and per stage.
+	GetContractUsage(ctx context.Context, contractID string) (*models.ContractUsage, error)
+	// CheckBudget returns an error wrapping ErrBudgetExhausted when the
+	// tenant has spent its budget for the current month.
+	CheckBudget(ctx context.Context, tenantID string) error
+}
+
+// usageService implements the Service interface.
+type usageService struct {
+	repo         repositories.LLMUsageRepository
+	contractRepo repositories.ContractRepository
+	cfg          configs.UsageConfig
+	prices       []configs.ModelPrice
+	metrics      *metrics.LLMMetrics
+	logger       *zap.Logger
+	now          func() time.Time
+	// unpriced holds the models already reported as missing from the price table
+	unpriced sync.Map
+}
+
+// NewUsageService creates a new usage service. metrics may be nil.
+func NewUsageService(repo repositories.LLMUsageRepository, contractRepo repositories.ContractRepository, cfg configs.UsageConfig, metrics *metrics.LLMMetrics, logger *zap.Logger) Service {
+	// Longest model first, so that the most specific prefix matches
+	prices := append([]configs.ModelPrice(nil), cfg.Prices...)
+	sort.SliceStable(prices, func(i, j int) bool { return len(prices[i].Model) > len(prices[j].Model) })
+	return &usageService{
+		repo:         repo,
+		contractRepo: contractRepo,
+		cfg:          cfg,
+		prices:       prices,
+		metrics:      metrics,
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+// Record stores the usage reported in resp.
+func (s *usageService) Record(ctx context.Context, provider string, resp *llm.ChatResponse) error {
+	sc := scopeFrom(ctx)
+	tenantID := sc.tenantID
+	if tenantID == "" {
+		tenantID = tenant.FromContext(ctx)
+	}
+	model := resp.Model
+	if model == "" {
+		model = "unknown"
+	}
+
+	record := &models.LLMUsage{
+		ID:               uuid.New().String(),
+		ContractID:       sc.contractID,
+		TenantID:         tenantID,
+		Stage:            sc.stage,
+		Provider:         provider,
+		Model:            model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
+		Cost:             s.cost(model, resp.Usage),
+		CreatedAt:        s.now(),
+	}
+	if s.metrics != nil {
+		label := s.metricTenant(tenantID)
+		s.metrics.TokensTotal.WithLabelValues(provider, model, sc.stage, label, "prompt").Add(float64(record.PromptTokens))
+		s.metrics.TokensTotal.WithLabelValues(provider, model, sc.stage, label, "completion").Add(float64(record.CompletionTokens))
+		s.metrics.CostTotal.WithLabelValues(provider, model, sc.stage, label).Add(record.Cost.InexactFloat64())
+	}
+	if err := s.repo.Create(record); err != nil {
+		return fmt.Errorf("failed to record LLM usage: %w", err)
+	}
+	return nil
+}
+
+// GetContractUsage totals the usage of a contract.
+func (s *usageService) GetContractUsage(ctx context.Context, contractID string) (*models.ContractUsage, error) {
+	contract, err := s.contractRepo.GetByID(contractID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrContractNotFound
+		}
+		return nil, fmt.Errorf("failed to load contract: %w", err)
+	}
+	records, err := s.repo.ListByContractID(contractID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load LLM usage: %w", err)
+	}
+
+	result := &models.ContractUsage{
+		ContractID: contractID,
+		TenantID:   contract.TenantID,
+		Currency:   s.cfg.GetCurrency(),
+		Stages:     []*models.StageUsage{},
+	}
+	stages := make(map[string]*models.StageUsage)
+	for _, record := range records {
+		result.Add(record)
+		stage, ok := stages[record.Stage]
+		if !ok {
+			stage = &models.StageUsage{Stage: record.Stage}
+			stages[record.Stage] = stage
+			result.Stages = append(result.Stages, stage)
+		}
+		stage.Add(record)
+	}
+	return result, nil
+}
+
+// CheckBudget checks the tenant's spend this month against its budget.
+func (s *usageService) CheckBudget(ctx context.Context, tenantID string) error {
+	if tenantID == "" {
+		tenantID = tenant.Default
+	}
+	budget := decimal.NewFromFloat(s.cfg.GetMonthlyBudget(tenantID))
+	if !budget.IsPositive() {
+		return nil
+	}
+	now := s.now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	spent, err := s.repo.TenantCost(tenantID, monthStart)
+	if err != nil {
+		return fmt.Errorf("failed to load LLM spend: %w", err)
+	}
+	if spent.GreaterThanOrEqual(budget) {
+		return fmt.Errorf("%w: tenant %s has spent %s of %s %s", ErrBudgetExhausted,
+			tenantID, spent.StringFixed(2), budget.StringFixed(2), s.cfg.GetCurrency())
+	}
+	return nil
+}
+
+// metricTenant returns the tenant label of the usage metrics. Only the
+// default tenant and the tenants configured with a budget of their own get a
+// label each; the others share otherTenants, so that the number of series
+// does not grow with the number of tenants.
+func (s *usageService) metricTenant(tenantID string) string {
+	if tenantID == tenant.Default {
+		return tenantID
+	}
+	for _, budget := range s.cfg.Budgets {
+		if budget.Tenant == tenantID {
+			return tenantID
+		}
+	}
+	return otherTenants
+}
+
+// cost prices usage at the rate of model, or returns zero for a model
+// missing from the price table.
+func (s *usageService) cost(model string, usage llm.Usage) decimal.Decimal {
+	for _, price := range s.prices {
+		if strings.HasPrefix(model, price.Model) {
+			prompt := decimal.NewFromInt(int64(usage.PromptTokens)).Mul(decimal.NewFromFloat(price.Prompt))
+			completion := decimal.NewFromInt(int64(usage.CompletionTokens)).Mul(decimal.NewFromFloat(price.Completion))
+			return prompt.Add(completion).Div(tokensPerPriceUnit).Round(8)
+		}
+	}
+	if _, reported := s.unpriced.LoadOrStore(model, true); !reported {
+		s.logger.Warn("No price configured for LLM model; its usage is recorded at no cost", zap.String("model", model))
+	}
+	return decimal.Zero
+}
+
+// scope is what the LLM calls made with a context are attributed to.
+type scope struct {
+	contractID string
+	tenantID   string
+	stage      string
+}
+
+type scopeKey struct{}
+
+// WithContract attributes the LLM calls made with the returned context to a
+// contract and the tenant it belongs to.
+func WithContract(ctx context.Context, contractID, tenantID string) context.Context {
+	sc := scopeFrom(ctx)
+	sc.contractID = contractID
+	sc.tenantID = tenantID
+	return context.WithValue(ctx, scopeKey{}, sc)
+}
+
+// WithStage attributes the LLM calls made with the returned context to a
+// stage of the contract's analysis.
+func WithStage(ctx context.Context, stage string) context.Context {
+	sc := scopeFrom(ctx)
+	sc.stage = stage
+	return context.WithValue(ctx, scopeKey{}, sc)
+}
+
+func scopeFrom(ctx context.Context) scope {
+	sc, _ := ctx.Value(scopeKey{}).(scope)
+	return sc
+}
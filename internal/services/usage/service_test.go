@@ -0,0 +1,127 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"contract-analysis-service/configs"
+	"contract-analysis-service/internal/models"
+	"contract-analysis-service/internal/pkg/tenant"
+	"contract-analysis-service/internal/repositories"
+	repo_mocks "contract-analysis-service/internal/repositories/mocks"
+	sqliterepo "contract-analysis-service/internal/repositories/sqlite"
+	"contract-analysis-service/internal/services/llm"
+	llm_mocks "contract-analysis-service/internal/services/llm/mocks"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testUsageConfig = configs.UsageConfig{
+	Prices: []configs.ModelPrice{
+		{Model: "gpt-4o", Prompt: 2.5, Completion: 10},
+		{Model: "gpt-4o-mini", Prompt: 0.15, Completion: 0.6},
+	},
+	MonthlyBudget: 0.01,
+	Budgets:       []configs.TenantBudget{{Tenant: "acme", Monthly: 1}},
+}
+
+func newUsageService(t *testing.T) (*usageService, repositories.LLMUsageRepository) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "usage.db")), &gorm.Config{})
+	require.NoError(t, err)
+	repo := sqliterepo.NewLLMUsageRepository(db)
+	contractRepo := new(repo_mocks.ContractRepository)
+	contractRepo.On("GetByID", "contract-1").Return(&models.Contract{ID: "contract-1", TenantID: "acme"}, nil)
+	contractRepo.On("GetByID", "missing").Return(nil, repositories.ErrNotFound)
+	service := NewUsageService(repo, contractRepo, testUsageConfig, nil, zap.NewNop()).(*usageService)
+	return service, repo
+}
+
+func chatResponse(model string, prompt, completion int) *llm.ChatResponse {
+	return &llm.ChatResponse{
+		Model: model,
+		Usage: llm.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion},
+	}
+}
+
+func TestMeter_RecordsContractUsage(t *testing.T) {
+	service, _ := newUsageService(t)
+	next := new(llm_mocks.Service)
+	meter := NewMeter(next, service, zap.NewNop())
+	req := llm.NewChatRequest("", "Analyze")
+
+	ctx := WithContract(context.Background(), "contract-1", "acme")
+	next.On("Chat", mock.Anything, "openai", req).Return(chatResponse("gpt-4o-mini-2024-07-18", 1000, 500), nil).Once()
+	_, err := meter.Chat(WithStage(ctx, "validation"), "openai", req)
+	require.NoError(t, err)
+
+	next.On("Chat", mock.Anything, "openai", req).Return(chatResponse("gpt-4o-2024-08-06", 2000, 1000), nil).Once()
+	next.On("Chat", mock.Anything, "local", req).Return(chatResponse("llama-3", 300, 100), nil).Once()
+	next.On("Chat", mock.Anything, "broken", req).Return(nil, errors.New("unavailable")).Once()
+	// Later calls sort after the validation
+	service.now = func() time.Time { return time.Now().Add(time.Second) }
+	analysisCtx := WithStage(ctx, "analysis")
+	for _, provider := range []string{"openai", "local", "broken"} {
+		meter.Chat(analysisCtx, provider, req)
+	}
+
+	result, err := service.GetContractUsage(context.Background(), "contract-1")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", result.TenantID)
+	assert.Equal(t, "USD", result.Currency)
+	assert.Equal(t, 3, result.Calls, "failed calls are not recorded")
+	assert.Equal(t, 3300, result.PromptTokens)
+	assert.Equal(t, 1600, result.CompletionTokens)
+	assert.Equal(t, 4900, result.TotalTokens)
+	// 1000 × 0.15 + 500 × 0.6 and 2000 × 2.5 + 1000 × 10 per million; llama-3 has no price
+	assert.True(t, result.Cost.Equal(decimal.RequireFromString("0.01545")), result.Cost.String())
+
+	require.Len(t, result.Stages, 2)
+	assert.Equal(t, "validation", result.Stages[0].Stage)
+	assert.True(t, result.Stages[0].Cost.Equal(decimal.RequireFromString("0.00045")), result.Stages[0].Cost.String())
+	assert.Equal(t, "analysis", result.Stages[1].Stage)
+	assert.Equal(t, 2, result.Stages[1].Calls)
+
+	_, err = service.GetContractUsage(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrContractNotFound)
+}
+
+func TestUsageService_MetricTenant(t *testing.T) {
+	service, _ := newUsageService(t)
+	assert.Equal(t, "acme", service.metricTenant("acme"))
+	assert.Equal(t, tenant.Default, service.metricTenant(tenant.Default))
+	assert.Equal(t, otherTenants, service.metricTenant("unconfigured"))
+}
+
+func TestUsageService_CheckBudget(t *testing.T) {
+	service, repo := newUsageService(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	// Spend of an earlier month does not count
+	require.NoError(t, repo.Create(&models.LLMUsage{ID: "old", TenantID: tenant.Default, Cost: decimal.NewFromInt(5), CreatedAt: now.AddDate(0, -1, 0)}))
+	assert.NoError(t, service.CheckBudget(ctx, ""))
+
+	// Calls outside a contract are accounted to the tenant of the request
+	require.NoError(t, service.Record(tenant.WithID(ctx, tenant.Default), "openai", chatResponse("gpt-4o", 4000, 0)))
+	err := service.CheckBudget(ctx, tenant.Default)
+	assert.ErrorIs(t, err, ErrBudgetExhausted)
+	assert.ErrorIs(t, service.CheckBudget(ctx, ""), ErrBudgetExhausted)
+
+	// acme has a budget of its own
+	require.NoError(t, service.Record(WithContract(ctx, "contract-1", "acme"), "openai", chatResponse("gpt-4o", 4000, 0)))
+	assert.NoError(t, service.CheckBudget(ctx, "acme"))
+
+	// A new month starts with a clean slate
+	service.now = func() time.Time { return now.AddDate(0, 1, 0) }
+	assert.NoError(t, service.CheckBudget(ctx, tenant.Default))
+}
@@ -7,8 +7,9 @@ import (
 	"testing"
 
 	"contract-analysis-service/internal/models"
-	"contract-analysis-service/internal/pkg/external"
+	"contract-analysis-service/internal/services/llm"
 	llm_mocks "contract-analysis-service/internal/services/llm/mocks"
+	"contract-analysis-service/internal/services/llm/prompts"
 	"contract-analysis-service/internal/services/validation"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -19,7 +20,7 @@ func TestValidationService_ValidateContract(t *testing.T) {
 	logger := zap.NewNop()
 	llmMock := new(llm_mocks.Service)
 
-	service := validation.NewValidationService(llmMock, logger)
+	service := validation.NewValidationService(llmMock, prompts.Default(), logger)
 
 	// Mock the LLM response
 	validationResult := &models.ValidationResult{
@@ -29,36 +30,9 @@ func TestValidationService_ValidateContract(t *testing.T) {
 	}
 	validationResultJSON, _ := json.Marshal(validationResult)
 
-	// Construct the full expected response from the LLM
-	llmResponseContent := struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}{
-		Choices: []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		}{
-			{
-				Message: struct {
-					Content string `json:"content"`
-				}{
-					Content: string(validationResultJSON),
-				},
-			},
-		},
-	}
-	responseBody, _ := json.Marshal(llmResponseContent)
-
-	llmResponse := &external.Response{
-		StatusCode: 200,
-		Body:       responseBody,
-	}
-
-	llmMock.On("ExecuteRequest", mock.Anything, "openrouter", mock.Anything).Return(llmResponse, nil)
+	llmMock.On("Chat", mock.Anything, llm.TaskValidation, mock.MatchedBy(func(req *llm.ChatRequest) bool {
+		return req.ResponseFormat == llm.ResponseFormatJSON
+	})).Return(&llm.ChatResponse{Content: string(validationResultJSON)}, nil)
 
 	// Call the method
 	result, err := service.ValidateContract(context.Background(), "some document text")
@@ -68,6 +42,7 @@ func TestValidationService_ValidateContract(t *testing.T) {
 	assert.NotNil(t, result)
 	assert.True(t, result.IsValidContract)
 	assert.Equal(t, "Sale of Goods", result.ContractType)
+	assert.Equal(t, "contract_validation@v1", result.PromptVersion)
 	llmMock.AssertExpectations(t)
 }
 
@@ -75,11 +50,11 @@ func TestValidationService_ValidateContract_Error(t *testing.T) {
 	logger := zap.NewNop()
 	llmMock := new(llm_mocks.Service)
 
-	service := validation.NewValidationService(llmMock, logger)
+	service := validation.NewValidationService(llmMock, prompts.Default(), logger)
 
 	// Mock the LLM error
 	expectedError := errors.New("LLM API error")
-	llmMock.On("ExecuteRequest", mock.Anything, "openrouter", mock.Anything).Return(nil, expectedError)
+	llmMock.On("Chat", mock.Anything, llm.TaskValidation, mock.Anything).Return(nil, expectedError)
 
 	// Call the method
 	result, err := service.ValidateContract(context.Background(), "some document text")